@@ -0,0 +1,142 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The Append functions encode a single item, or the header of one, onto dst and return the
+// extended slice, like strconv.AppendInt. They use the smallest argument encoding and do no
+// nesting or conformance checks, so the caller is responsible for producing well-formed
+// sequences. CborWriter is built on them.
+
+// AppendHeader appends the initial byte and minimally encoded argument of an item with
+// the given major type, such as a string length or an array count.
+func AppendHeader(dst []byte, mt MajorType, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return append(dst, encodeInitialByte(mt, byte(arg)))
+	case arg <= math.MaxUint8:
+		return append(dst, encodeInitialByte(mt, byte(AdditionalInfo8Bit)), byte(arg))
+	case arg <= math.MaxUint16:
+		dst = append(dst, encodeInitialByte(mt, byte(AdditionalInfo16Bit)))
+		return binary.BigEndian.AppendUint16(dst, uint16(arg))
+	case arg <= math.MaxUint32:
+		dst = append(dst, encodeInitialByte(mt, byte(AdditionalInfo32Bit)))
+		return binary.BigEndian.AppendUint32(dst, uint32(arg))
+	default:
+		dst = append(dst, encodeInitialByte(mt, byte(AdditionalInfo64Bit)))
+		return binary.BigEndian.AppendUint64(dst, arg)
+	}
+}
+
+// AppendUint64 appends an unsigned integer.
+func AppendUint64(dst []byte, value uint64) []byte {
+	return AppendHeader(dst, MajorTypeUnsignedInteger, value)
+}
+
+// AppendInt64 appends a signed integer.
+func AppendInt64(dst []byte, value int64) []byte {
+	if value >= 0 {
+		return AppendHeader(dst, MajorTypeUnsignedInteger, uint64(value))
+	}
+	// CBOR encodes negative integers as -1 - n
+	return AppendHeader(dst, MajorTypeNegativeInteger, uint64(-1-value))
+}
+
+// AppendByteString appends a definite-length byte string.
+func AppendByteString(dst []byte, value []byte) []byte {
+	dst = AppendHeader(dst, MajorTypeByteString, uint64(len(value)))
+	return append(dst, value...)
+}
+
+// AppendTextString appends a definite-length text string. It does not validate UTF-8.
+func AppendTextString(dst []byte, value string) []byte {
+	dst = AppendHeader(dst, MajorTypeTextString, uint64(len(value)))
+	return append(dst, value...)
+}
+
+// AppendArrayHeader appends the header of a definite-length array of n elements.
+func AppendArrayHeader(dst []byte, n int) []byte {
+	return AppendHeader(dst, MajorTypeArray, uint64(n))
+}
+
+// AppendMapHeader appends the header of a definite-length map of n key/value pairs.
+func AppendMapHeader(dst []byte, n int) []byte {
+	return AppendHeader(dst, MajorTypeMap, uint64(n))
+}
+
+// AppendIndefiniteHeader appends the start of an indefinite-length byte string, text
+// string, array or map. Close it with AppendBreak.
+func AppendIndefiniteHeader(dst []byte, mt MajorType) []byte {
+	return append(dst, encodeInitialByte(mt, byte(AdditionalInfoIndefiniteLength)))
+}
+
+// AppendBreak appends the break byte that ends an indefinite-length item.
+func AppendBreak(dst []byte) []byte {
+	return append(dst, breakByte)
+}
+
+// AppendTag appends a semantic tag. The tagged item must follow.
+func AppendTag(dst []byte, tag CborTag) []byte {
+	return AppendHeader(dst, MajorTypeTag, uint64(tag))
+}
+
+// AppendBool appends true or false.
+func AppendBool(dst []byte, value bool) []byte {
+	if value {
+		return AppendSimpleValue(dst, SimpleValueTrue)
+	}
+	return AppendSimpleValue(dst, SimpleValueFalse)
+}
+
+// AppendNull appends null.
+func AppendNull(dst []byte) []byte {
+	return AppendSimpleValue(dst, SimpleValueNull)
+}
+
+// AppendUndefined appends undefined.
+func AppendUndefined(dst []byte) []byte {
+	return AppendSimpleValue(dst, SimpleValueUndefined)
+}
+
+// AppendSimpleValue appends a simple value, using the two-byte form for values of 32 and up.
+// The reserved values 24-31 have no well-formed encoding; use WriteSimpleValue to have
+// them rejected.
+func AppendSimpleValue(dst []byte, value SimpleValue) []byte {
+	if value < 32 {
+		return append(dst, encodeInitialByte(MajorTypeSimpleOrFloat, byte(value)))
+	}
+	return append(dst, encodeInitialByte(MajorTypeSimpleOrFloat, byte(AdditionalInfo8Bit)), byte(value))
+}
+
+// AppendFloat16 appends a half-precision float.
+func AppendFloat16(dst []byte, value float32) []byte {
+	dst = append(dst, encodeInitialByte(MajorTypeSimpleOrFloat, byte(AdditionalInfo16Bit)))
+	return binary.BigEndian.AppendUint16(dst, float32ToFloat16Bits(value))
+}
+
+// AppendFloat32 appends a single-precision float.
+func AppendFloat32(dst []byte, value float32) []byte {
+	dst = append(dst, encodeInitialByte(MajorTypeSimpleOrFloat, byte(AdditionalInfo32Bit)))
+	return binary.BigEndian.AppendUint32(dst, math.Float32bits(value))
+}
+
+// AppendFloat64 appends a double-precision float.
+func AppendFloat64(dst []byte, value float64) []byte {
+	dst = append(dst, encodeInitialByte(MajorTypeSimpleOrFloat, byte(AdditionalInfo64Bit)))
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(value))
+}
+
+// AppendFloat appends a float using the smallest precision that represents it exactly,
+// like CborWriter.WriteFloat.
+func AppendFloat(dst []byte, value float64) []byte {
+	f32 := float32(value)
+	if float64(f32) != value {
+		return AppendFloat64(dst, value)
+	}
+	if float16BitsToFloat32(float32ToFloat16Bits(f32)) == f32 && !math.IsNaN(value) {
+		return AppendFloat16(dst, f32)
+	}
+	return AppendFloat32(dst, f32)
+}
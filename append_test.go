@@ -0,0 +1,59 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func TestAppendFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		append   func([]byte) []byte
+		expected string
+	}{
+		{"uint small", func(b []byte) []byte { return AppendUint64(b, 23) }, "17"},
+		{"uint 8", func(b []byte) []byte { return AppendUint64(b, 24) }, "1818"},
+		{"uint 16", func(b []byte) []byte { return AppendUint64(b, 1000) }, "1903e8"},
+		{"uint 32", func(b []byte) []byte { return AppendUint64(b, 1000000) }, "1a000f4240"},
+		{"uint 64", func(b []byte) []byte { return AppendUint64(b, math.MaxUint64) }, "1bffffffffffffffff"},
+		{"int negative", func(b []byte) []byte { return AppendInt64(b, -1000) }, "3903e7"},
+		{"int min", func(b []byte) []byte { return AppendInt64(b, math.MinInt64) }, "3b7fffffffffffffff"},
+		{"byte string", func(b []byte) []byte { return AppendByteString(b, []byte{1, 2, 3, 4}) }, "4401020304"},
+		{"text string", func(b []byte) []byte { return AppendTextString(b, "IETF") }, "6449455446"},
+		{"array header", func(b []byte) []byte { return AppendArrayHeader(b, 25) }, "9819"},
+		{"map header", func(b []byte) []byte { return AppendMapHeader(b, 2) }, "a2"},
+		{"indefinite", func(b []byte) []byte { return AppendBreak(AppendIndefiniteHeader(b, MajorTypeArray)) }, "9fff"},
+		{"tag", func(b []byte) []byte { return AppendTag(b, TagUnixTime) }, "c1"},
+		{"bool", func(b []byte) []byte { return AppendBool(AppendBool(b, false), true) }, "f4f5"},
+		{"null undefined", func(b []byte) []byte { return AppendUndefined(AppendNull(b)) }, "f6f7"},
+		{"simple", func(b []byte) []byte { return AppendSimpleValue(b, 255) }, "f8ff"},
+		{"float16", func(b []byte) []byte { return AppendFloat16(b, 1.5) }, "f93e00"},
+		{"float32", func(b []byte) []byte { return AppendFloat32(b, 100000) }, "fa47c35000"},
+		{"float64", func(b []byte) []byte { return AppendFloat64(b, 1.1) }, "fb3ff199999999999a"},
+		{"float smallest", func(b []byte) []byte { return AppendFloat(b, 65504) }, "f97bff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := []byte{0xaa}
+			got := tt.append(prefix)
+			if hex.EncodeToString(got) != "aa"+tt.expected {
+				t.Errorf("expected aa%s, got %x", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppendNoAllocation(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		b := AppendMapHeader(buf[:0], 1)
+		b = AppendTextString(b, "id")
+		b = AppendUint64(b, 42)
+		_ = b
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,88 @@
+package cbor
+
+import "math/big"
+
+// WriteBigFloatValue writes x as a bigfloat (tag 5): an array of a base-2 exponent and an
+// integer mantissa, using the shortest mantissa that represents x exactly. Every finite
+// big.Float is representable, so no rounding takes place. The sign of a negative zero is
+// lost because the mantissa is an integer, and infinities return ErrUnsupportedType. A
+// nil x is written as null.
+func (w *CborWriter) WriteBigFloatValue(x *big.Float) error {
+	if x == nil {
+		return w.WriteNull()
+	}
+	if x.IsInf() {
+		return NewCborError(ErrUnsupportedType, len(w.buffer), "bigfloat cannot encode infinity")
+	}
+
+	mantissa := new(big.Int)
+	var exponent int64
+	if x.Sign() != 0 {
+		// x = frac × 2^exp with 0.5 <= |frac| < 1, and frac has MinPrec significant bits.
+		frac := new(big.Float)
+		exp := x.MantExp(frac)
+		prec := int(x.MinPrec())
+		frac.SetMantExp(frac, prec).Int(mantissa)
+		exponent = int64(exp) - int64(prec)
+	}
+
+	if err := w.WriteTag(TagBigFloat); err != nil {
+		return err
+	}
+	if err := w.WriteStartArray(2); err != nil {
+		return err
+	}
+	if err := w.WriteInt64(exponent); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(mantissa); err != nil {
+		return err
+	}
+	return w.WriteEndArray()
+}
+
+// ReadBigFloatValue reads a bigfloat (tag 5) into a big.Float. The mantissa may be an
+// integer or a bignum. The result is exact: its precision is the bit length of the
+// mantissa, and at least 64 as with big.Float.SetInt. Values whose exponent lies outside
+// the range of big.Float return ErrOverflow.
+func (r *CborReader) ReadBigFloatValue() (*big.Float, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return nil, err
+	}
+	if tag != TagBigFloat {
+		return nil, NewCborError(ErrInvalidCbor, r.offset, "expected bigfloat tag")
+	}
+
+	start := r.offset
+	length, err := r.ReadStartArray()
+	if err != nil {
+		return nil, err
+	}
+	if length != 2 && length != -1 {
+		return nil, NewCborError(ErrInvalidCbor, start, "bigfloat must be an array of two integers")
+	}
+
+	expOffset := r.offset
+	exponent, err := r.ReadInt64()
+	if err != nil {
+		return nil, err
+	}
+	mantissa, err := r.ReadBigInt()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ReadEndArray(); err != nil {
+		return nil, err
+	}
+
+	// big.Float keeps the exponent of the value normalized to 0.5 <= |frac| < 1.
+	if bits := int64(mantissa.BitLen()); bits > 0 {
+		if e := exponent + bits; e < big.MinExp || e > big.MaxExp {
+			return nil, NewCborError(ErrOverflow, expOffset, "bigfloat exponent out of range")
+		}
+	}
+
+	x := new(big.Float).SetInt(mantissa)
+	return x.SetMantExp(x, int(exponent)), nil
+}
@@ -0,0 +1,138 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestBigFloatValueRFCVector(t *testing.T) {
+	// RFC 8949 Section 3.4.4: 1.5 as 3 × 2^-1.
+	want, _ := hex.DecodeString("c5822003")
+
+	w := NewCborWriter()
+	if err := w.WriteBigFloatValue(big.NewFloat(1.5)); err != nil {
+		t.Fatalf("WriteBigFloatValue failed: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("expected %x, got %x", want, w.Bytes())
+	}
+
+	x, err := NewCborReader(want).ReadBigFloatValue()
+	if err != nil {
+		t.Fatalf("ReadBigFloatValue failed: %v", err)
+	}
+	if f, _ := x.Float64(); f != 1.5 {
+		t.Errorf("expected 1.5, got %v", x)
+	}
+}
+
+func TestBigFloatValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		value string
+		prec  uint
+	}{
+		{"0", 64},
+		{"-3", 64},
+		{"0.1", 200},
+		{"1e1000", 500},
+		{"-123456789.000000000123456789", 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			x, _, err := big.ParseFloat(tt.value, 10, tt.prec, big.ToNearestEven)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := NewCborWriter()
+			if err := w.WriteBigFloatValue(x); err != nil {
+				t.Fatalf("WriteBigFloatValue failed: %v", err)
+			}
+			r := NewCborReader(w.Bytes())
+			got, err := r.ReadBigFloatValue()
+			if err != nil {
+				t.Fatalf("ReadBigFloatValue failed: %v", err)
+			}
+			if got.Cmp(x) != 0 {
+				t.Errorf("expected %s, got %s", x.Text('g', -1), got.Text('g', -1))
+			}
+			if got.Prec() < x.MinPrec() {
+				t.Errorf("precision %d cannot hold the %d bits of the value", got.Prec(), x.MinPrec())
+			}
+			if err := r.Finish(); err != nil {
+				t.Errorf("Finish failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestBigFloatValueEdgeCases(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteBigFloatValue(new(big.Float).SetInf(false)); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType for infinity, got %v", err)
+	}
+	if w.Len() != 0 {
+		t.Errorf("expected nothing written for infinity, got %x", w.Bytes())
+	}
+
+	if err := w.WriteBigFloatValue(nil); err != nil {
+		t.Fatalf("WriteBigFloatValue(nil) failed: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), []byte{0xf6}) {
+		t.Errorf("expected null, got %x", w.Bytes())
+	}
+
+	// Negative zero has no integer mantissa of its own and is written as zero.
+	w.Reset()
+	if err := w.WriteBigFloatValue(big.NewFloat(math.Copysign(0, -1))); err != nil {
+		t.Fatalf("WriteBigFloatValue(-0) failed: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), []byte{0xc5, 0x82, 0x00, 0x00}) {
+		t.Errorf("expected bigfloat zero, got %x", w.Bytes())
+	}
+}
+
+func TestReadBigFloatValueErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"wrong_tag", "c4822003", ErrInvalidCbor},
+		{"three_items", "c583200300", ErrInvalidCbor},
+		{"exponent_overflow", "c5821b00000000ffffffff01", ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			_, err := NewCborReader(data).ReadBigFloatValue()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// An indefinite-length array must end after the mantissa.
+	data, _ := hex.DecodeString("c59f200300ff")
+	var mismatch *TypeMismatchError
+	if _, err := NewCborReader(data).ReadBigFloatValue(); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError for extra item, got %v", err)
+	}
+
+	// A bignum mantissa in an indefinite-length array is accepted.
+	data, _ = hex.DecodeString("c59f20c249010000000000000000ff")
+	x, err := NewCborReader(data).ReadBigFloatValue()
+	if err != nil {
+		t.Fatalf("ReadBigFloatValue failed: %v", err)
+	}
+	want := new(big.Float).SetMantExp(big.NewFloat(1), 63)
+	if x.Cmp(want) != 0 {
+		t.Errorf("expected 2^63, got %s", x.Text('g', -1))
+	}
+}
@@ -0,0 +1,46 @@
+package cbor
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// Value adapts a Go value to encoding.BinaryMarshaler and encoding.BinaryUnmarshaler so it
+// can be stored by plumbing that only speaks those interfaces. The binary form is the CBOR
+// encoding of V using the reflection mapping described in Marshal.
+type Value struct {
+	V any
+}
+
+var (
+	_ encoding.BinaryMarshaler   = Value{}
+	_ encoding.BinaryUnmarshaler = (*Value)(nil)
+)
+
+// MarshalBinary encodes V with WriteValue.
+func (v Value) MarshalBinary() ([]byte, error) {
+	w := NewCborWriter()
+	if err := w.WriteValue(v.V); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the single CBOR item in data with ReadValue. If V holds a non-nil
+// pointer the item is decoded into its target; otherwise V is replaced by the ReadAny form.
+// It returns ErrNotAtEnd if data contains bytes after the item.
+func (v *Value) UnmarshalBinary(data []byte) error {
+	target := any(&v.V)
+	if rv := reflect.ValueOf(v.V); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		target = v.V
+	}
+
+	r := NewCborReader(data)
+	if err := r.ReadValue(target); err != nil {
+		return err
+	}
+	if r.BytesRemaining() > 0 {
+		return NewCborError(ErrNotAtEnd, r.offset, "")
+	}
+	return nil
+}
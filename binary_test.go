@@ -0,0 +1,50 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestValueMarshalBinary(t *testing.T) {
+	data, err := Value{V: map[string]int{"a": 1}}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	expected, _ := hex.DecodeString("a1616101")
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %x, got %x", expected, data)
+	}
+}
+
+func TestValueUnmarshalBinary(t *testing.T) {
+	data, _ := hex.DecodeString("a1616101")
+
+	var generic Value
+	if err := generic.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	m, ok := generic.V.(map[any]any)
+	if !ok || m["a"] != uint64(1) {
+		t.Errorf("unexpected value: %#v", generic.V)
+	}
+
+	var typed struct {
+		A int `cbor:"a"`
+	}
+	target := Value{V: &typed}
+	if err := target.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if typed.A != 1 {
+		t.Errorf("expected 1, got %d", typed.A)
+	}
+}
+
+func TestValueUnmarshalBinaryTrailing(t *testing.T) {
+	var v Value
+	if err := v.UnmarshalBinary([]byte{0x01, 0x02}); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+}
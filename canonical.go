@@ -0,0 +1,229 @@
+package cbor
+
+import (
+	"hash"
+	"math"
+)
+
+// Canonicalize decodes the single item in data and re-encodes it in RFC 8949 canonical
+// form: definite lengths, minimal integer and length arguments, the shortest lossless float
+// (with NaN as f97e00), bignums that fit in 64 bits as plain integers, bignums without
+// leading zero bytes and map keys in bytewise order. Tags are preserved. Reader options
+// control how permissively data is decoded, and how deeply it may nest; the default is lax.
+func Canonicalize(data []byte, opts ...ReaderOption) ([]byte, error) {
+	r := NewCborReader(data, opts...)
+	w := NewCborWriter(WithConformanceMode(ConformanceCanonical), WithInitialCapacity(len(data)), WithMaxNestingDepth(r.maxNestingDepth))
+	if err := w.transcodeCanonical(r, 0); err != nil {
+		return nil, err
+	}
+	if r.BytesRemaining() > 0 {
+		return nil, NewCborError(ErrNotAtEnd, r.offset, "")
+	}
+	return w.Bytes(), nil
+}
+
+// CanonicalHash writes the Canonicalize form of the single item in data to h without
+// building the whole canonical encoding. Arrays and tags are streamed into h; only each
+// map, whose keys must be sorted, and scalars are encoded in a reused scratch buffer
+// first. Reader options control decoding as for Canonicalize.
+func CanonicalHash(data []byte, h hash.Hash, opts ...ReaderOption) error {
+	r := NewCborReader(data, opts...)
+	w := NewCborWriter(WithConformanceMode(ConformanceCanonical), WithMaxNestingDepth(r.maxNestingDepth))
+	c := &canonicalHasher{r: r, h: h, w: w}
+	if err := c.item(); err != nil {
+		return err
+	}
+	if r.BytesRemaining() > 0 {
+		return NewCborError(ErrNotAtEnd, r.offset, "")
+	}
+	return nil
+}
+
+// canonicalHasher streams the canonical encoding of a reader's items into a hash.
+type canonicalHasher struct {
+	r       *CborReader
+	h       hash.Hash
+	w       *CborWriter // scratch writer for maps and scalars
+	scratch []byte      // scratch buffer for array and tag headers
+	tags    int         // enclosing tags, which count toward the reader's nesting limit
+}
+
+// item hashes the canonical encoding of the next item.
+func (c *canonicalHasher) item() error {
+	r := c.r
+	state, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case StateStartArray:
+		length, err := r.ReadStartArray()
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			if length, err = r.CountRemainingItems(); err != nil {
+				return err
+			}
+		}
+		c.scratch = AppendArrayHeader(c.scratch[:0], length)
+		c.h.Write(c.scratch)
+		for i := 0; i < length; i++ {
+			if err := c.item(); err != nil {
+				return err
+			}
+		}
+		return r.ReadEndArray()
+
+	case StateTag:
+		tag, err := r.PeekTag()
+		if err != nil {
+			return err
+		}
+		if tag == TagUnsignedBignum || tag == TagNegativeBignum {
+			break
+		}
+		if len(r.nestingStack)+c.tags >= r.maxNestingDepth {
+			return NewCborError(ErrNestingDepthExceeded, r.offset, "CanonicalHash")
+		}
+		if _, err := r.ReadTag(); err != nil {
+			return err
+		}
+		c.scratch = AppendTag(c.scratch[:0], tag)
+		c.h.Write(c.scratch)
+		c.tags++
+		err = c.item()
+		c.tags--
+		return err
+	}
+
+	c.w.Reset()
+	if err := c.w.transcodeCanonical(r, c.tags); err != nil {
+		return err
+	}
+	c.h.Write(c.w.Bytes())
+	return nil
+}
+
+// transcodeCanonical copies the next item from r, letting the canonical writer minimize
+// its encoding and sort map keys. tags counts the enclosing tags, which count toward the
+// reader's nesting limit.
+func (w *CborWriter) transcodeCanonical(r *CborReader, tags int) error {
+	state, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case StateUnsignedInteger, StateNegativeInteger:
+		value, err := r.ReadAny()
+		if err != nil {
+			return err
+		}
+		return w.WriteAny(value)
+
+	case StateByteString, StateStartIndefiniteLengthByteString:
+		value, err := r.ReadByteString()
+		if err != nil {
+			return err
+		}
+		return w.WriteByteString(value)
+
+	case StateTextString, StateStartIndefiniteLengthTextString:
+		value, err := r.ReadTextString()
+		if err != nil {
+			return err
+		}
+		return w.WriteTextString(value)
+
+	case StateStartArray:
+		length, err := r.ReadStartArray()
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			if length, err = r.CountRemainingItems(); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteStartArray(length); err != nil {
+			return err
+		}
+		for i := 0; i < length; i++ {
+			if err := w.transcodeCanonical(r, tags); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadEndArray(); err != nil {
+			return err
+		}
+		return w.WriteEndArray()
+
+	case StateStartMap:
+		length, err := r.ReadStartMap()
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			if length, err = r.CountRemainingItems(); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteStartMap(length); err != nil {
+			return err
+		}
+		for i := 0; i < 2*length; i++ {
+			if err := w.transcodeCanonical(r, tags); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadEndMap(); err != nil {
+			return err
+		}
+		return w.WriteEndMap()
+
+	case StateTag:
+		tag, err := r.PeekTag()
+		if err != nil {
+			return err
+		}
+		if tag == TagUnsignedBignum || tag == TagNegativeBignum {
+			value, err := r.ReadBigInt()
+			if err != nil {
+				return err
+			}
+			return w.WriteBigInt(value)
+		}
+		if len(r.nestingStack)+tags >= r.maxNestingDepth {
+			return NewCborError(ErrNestingDepthExceeded, r.offset, "Canonicalize")
+		}
+		if _, err := r.ReadTag(); err != nil {
+			return err
+		}
+		if err := w.WriteTag(tag); err != nil {
+			return err
+		}
+		return w.transcodeCanonical(r, tags+1)
+
+	case StateBoolean, StateNull, StateUndefinedValue, StateSimpleValue:
+		value, err := r.ReadSimpleValueStrict()
+		if err != nil {
+			return err
+		}
+		return w.WriteSimpleValue(value)
+
+	case StateHalfPrecisionFloat, StateSinglePrecisionFloat, StateDoublePrecisionFloat:
+		value, err := r.ReadFloat()
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(value) {
+			return w.WriteFloat16(float32(math.NaN()))
+		}
+		return w.WriteFloat(value)
+
+	default:
+		return NewCborError(ErrInvalidState, r.offset, "Canonicalize")
+	}
+}
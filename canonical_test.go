@@ -0,0 +1,157 @@
+package cbor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{"already_canonical", "a2616101616202", "a2616101616202"},
+		{"integer_width", "1b0000000000000001", "01"},
+		{"negative_width", "3a00000063", "3863"},
+		{"length_width", "5801ff", "41ff"},
+		{"float_width", "fb3ff8000000000000", "f93e00"},
+		{"float_double", "fb3ff199999999999a", "fb3ff199999999999a"},
+		{"nan", "fb7ff8000000000001", "f97e00"},
+		{"map_order", "a36162026161010a03", "a30a03616101616202"},
+		{"nested", "81a2616202616101", "81a2616101616202"},
+		{"indefinite", "9f5f4101420203ff7f6161ffbf0102ffff", "83430102036161a10102"},
+		{"tag_kept", "c1fb3ff8000000000000", "c1f93e00"},
+		{"bignum_small", "c24a00000000000000000001", "01"},
+		{"bignum_leading_zero", "c24a00010000000000000000", "c249010000000000000000"},
+		{"negative_bignum", "c349010000000000000000", "c349010000000000000000"},
+		{"negative_bignum_fits", "c348ffffffffffffffff", "3bffffffffffffffff"},
+		{"min_negative", "3bffffffffffffffff", "3bffffffffffffffff"},
+		{"simple", "83f5f6f820", "83f5f6f820"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.in)
+			got, err := Canonicalize(data)
+			if err != nil {
+				t.Fatalf("Canonicalize failed: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.out {
+				t.Errorf("expected %s, got %x", tt.out, got)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	if _, err := Canonicalize([]byte{0x01, 0x02}); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+
+	// {1: 2, 0x1801: 3} repeats key 1 once minimized
+	data, _ := hex.DecodeString("a20102180103")
+	if _, err := Canonicalize(data); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+
+	// two-byte simple values below 32 are not well-formed, even to a lax reader
+	for input, want := range map[string]error{
+		"f818": ErrInvalidSimpleValue,
+		"f81f": ErrInvalidSimpleValue,
+		"f814": ErrNonCanonical,
+	} {
+		data, _ := hex.DecodeString(input)
+		if _, err := Canonicalize(data); !errors.Is(err, want) {
+			t.Errorf("%s: expected %v, got %v", input, want, err)
+		}
+	}
+}
+
+func TestCanonicalHashTagsAroundMap(t *testing.T) {
+	// tags outside a map still count once the map goes through the scratch writer
+	data := append(bytes.Repeat([]byte{0xc6}, 40), 0xa1, 0x00)
+	data = append(append(data, bytes.Repeat([]byte{0xc6}, 30)...), 0x00)
+	if err := CanonicalHash(data, sha256.New()); !errors.Is(err, ErrNestingDepthExceeded) {
+		t.Errorf("expected ErrNestingDepthExceeded, got %v", err)
+	}
+
+	data = append(bytes.Repeat([]byte{0xc6}, 10), 0xa1, 0x00, 0x00)
+	got, err := Canonicalize(data)
+	if err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %x, got %x", data, got)
+	}
+}
+
+func TestCanonicalizeNestingDepth(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0x81}, 70), 0x00)
+	opt := WithReaderMaxNestingDepth(100)
+
+	got, err := Canonicalize(data, opt)
+	if err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %x, got %x", data, got)
+	}
+	if err := CanonicalHash(data, sha256.New(), opt); err != nil {
+		t.Errorf("CanonicalHash failed: %v", err)
+	}
+
+	// a map is encoded by the scratch writer, which must allow the same depth
+	mapped := append(append([]byte{0xa1, 0x00}, bytes.Repeat([]byte{0x81}, 70)...), 0x00)
+	if err := CanonicalHash(mapped, sha256.New(), opt); err != nil {
+		t.Errorf("CanonicalHash with a deep map failed: %v", err)
+	}
+
+	if _, err := Canonicalize(data); !errors.Is(err, ErrNestingDepthExceeded) {
+		t.Errorf("expected ErrNestingDepthExceeded at the default depth, got %v", err)
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	inputs := []string{
+		"1b0000000000000001",
+		"fb3ff8000000000000",
+		"a36162026161010a03",
+		"81a2616202616101",
+		"9f5f4101420203ff7f6161ffbf0102ffff",
+		"c1fb3ff8000000000000",
+		"c24a00000000000000000001",
+		"d8189f9f1801ffc1a2616201616100ff",
+		"83f5f6f820",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			data, _ := hex.DecodeString(input)
+			canonical, err := Canonicalize(data)
+			if err != nil {
+				t.Fatalf("Canonicalize failed: %v", err)
+			}
+			want := sha256.Sum256(canonical)
+
+			h := sha256.New()
+			if err := CanonicalHash(data, h); err != nil {
+				t.Fatalf("CanonicalHash failed: %v", err)
+			}
+			if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+				t.Errorf("hash mismatch: expected %x, got %x", want, got)
+			}
+		})
+	}
+
+	if err := CanonicalHash([]byte{0x01, 0x02}, sha256.New()); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+	data, _ := hex.DecodeString("81a20102180103")
+	if err := CanonicalHash(data, sha256.New()); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+}
@@ -104,6 +104,8 @@ const (
 	TagExpectedBase16 CborTag = 23
 	// TagEncodedCborData is encoded CBOR data item.
 	TagEncodedCborData CborTag = 24
+	// TagRationalNumber is a rational number encoded as [numerator, denominator].
+	TagRationalNumber CborTag = 30
 	// TagURI is a URI (RFC 3986).
 	TagURI CborTag = 32
 	// TagBase64URL is a base64url encoded text.
@@ -114,6 +116,18 @@ const (
 	TagRegularExpression CborTag = 35
 	// TagMIMEMessage is a MIME message (RFC 2045).
 	TagMIMEMessage CborTag = 36
+	// TagEpochDate is a date as days since 1970-01-01 (RFC 8943).
+	TagEpochDate CborTag = 100
+	// TagSet is a set of unique elements encoded as an array.
+	TagSet CborTag = 258
+	// TagNetworkAddress is an IPv4 or IPv6 address as a 4 or 16 byte string.
+	TagNetworkAddress CborTag = 260
+	// TagNetworkAddressPrefix is an IP prefix as a map of address bytes to prefix length.
+	TagNetworkAddressPrefix CborTag = 261
+	// TagExtendedTime is an extended time map (RFC 9581).
+	TagExtendedTime CborTag = 1001
+	// TagFullDateString is a full-date string such as "2006-01-02" (RFC 8943).
+	TagFullDateString CborTag = 1004
 	// TagSelfDescribedCbor is a self-described CBOR.
 	TagSelfDescribedCbor CborTag = 55799
 )
@@ -237,6 +251,16 @@ const (
 // Break byte used to terminate indefinite-length items.
 const breakByte byte = 0xFF
 
+const (
+	// fullDateLayout is the RFC 3339 full-date format used by tag 1004.
+	fullDateLayout = "2006-01-02"
+	// secondsPerDay is the length of a day in epoch-date (tag 100) arithmetic.
+	secondsPerDay = 24 * 60 * 60
+)
+
+// selfDescribedPrefix is the encoding of tag 55799, the magic number of self-described CBOR.
+var selfDescribedPrefix = []byte{0xd9, 0xd9, 0xf7}
+
 // encodeInitialByte creates the initial byte from major type and additional info.
 func encodeInitialByte(mt MajorType, ai byte) byte {
 	return byte(mt)<<5 | (ai & 0x1F)
@@ -246,3 +270,33 @@ func encodeInitialByte(mt MajorType, ai byte) byte {
 func decodeInitialByte(b byte) (MajorType, byte) {
 	return MajorType(b >> 5), b & 0x1F
 }
+
+// decodeHeader decodes the initial byte and argument of the item at the start of data.
+// It returns the major type, additional info, argument value and header length.
+// For indefinite-length items and simple values/floats the argument is raw.
+func decodeHeader(data []byte) (MajorType, byte, uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, 0, 0, ErrUnexpectedEndOfData
+	}
+
+	mt, ai := decodeInitialByte(data[0])
+
+	switch {
+	case ai < 24:
+		return mt, ai, uint64(ai), 1, nil
+	case ai <= 27:
+		size := 1 << (ai - 24)
+		if len(data) < 1+size {
+			return 0, 0, 0, 0, ErrUnexpectedEndOfData
+		}
+		var arg uint64
+		for _, b := range data[1 : 1+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		return mt, ai, arg, 1 + size, nil
+	case ai == byte(AdditionalInfoIndefiniteLength):
+		return mt, ai, 0, 1, nil
+	default:
+		return 0, 0, 0, 0, ErrInvalidCbor
+	}
+}
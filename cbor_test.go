@@ -2,9 +2,16 @@ package cbor
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"math"
 	"math/big"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -1000,6 +1007,19 @@ func TestSimpleValue(t *testing.T) {
 			}
 		})
 	}
+
+	for _, value := range []SimpleValue{24, 31} {
+		w := NewCborWriter()
+		if err := w.WriteSimpleValue(value); !errors.Is(err, ErrInvalidSimpleValue) {
+			t.Errorf("WriteSimpleValue(%d): expected ErrInvalidSimpleValue, got %v", value, err)
+		}
+		if len(w.Bytes()) != 0 {
+			t.Errorf("WriteSimpleValue(%d): expected nothing written, got %x", value, w.Bytes())
+		}
+		if _, err := Marshal(value); !errors.Is(err, ErrInvalidSimpleValue) {
+			t.Errorf("Marshal(%d): expected ErrInvalidSimpleValue, got %v", value, err)
+		}
+	}
 }
 
 func TestTryReadNull(t *testing.T) {
@@ -1048,22 +1068,22 @@ func TestCanonicalModeRejectsIndefiniteLength(t *testing.T) {
 	w := NewCborWriter(WithConformanceMode(ConformanceCanonical))
 
 	err := w.WriteStartIndefiniteLengthArray()
-	if err != ErrIndefiniteLengthNotAllowed {
+	if !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
 		t.Errorf("expected ErrIndefiniteLengthNotAllowed, got %v", err)
 	}
 
 	err = w.WriteStartIndefiniteLengthMap()
-	if err != ErrIndefiniteLengthNotAllowed {
+	if !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
 		t.Errorf("expected ErrIndefiniteLengthNotAllowed, got %v", err)
 	}
 
 	err = w.WriteStartIndefiniteLengthByteString()
-	if err != ErrIndefiniteLengthNotAllowed {
+	if !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
 		t.Errorf("expected ErrIndefiniteLengthNotAllowed, got %v", err)
 	}
 
 	err = w.WriteStartIndefiniteLengthTextString()
-	if err != ErrIndefiniteLengthNotAllowed {
+	if !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
 		t.Errorf("expected ErrIndefiniteLengthNotAllowed, got %v", err)
 	}
 }
@@ -1083,7 +1103,7 @@ func TestNestingDepthLimit(t *testing.T) {
 
 	// This should fail
 	err := w.WriteStartArray(1)
-	if err != ErrNestingDepthExceeded {
+	if !errors.Is(err, ErrNestingDepthExceeded) {
 		t.Errorf("expected ErrNestingDepthExceeded, got %v", err)
 	}
 }
@@ -1168,3 +1188,3142 @@ func TestResetReader(t *testing.T) {
 		t.Errorf("got %d, want 42", val)
 	}
 }
+
+func TestReadExpecting(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteTextString("hello"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+
+	r := NewCborReader(w.Bytes())
+
+	err := r.ReadExpecting(StateUnsignedInteger)
+	var tmErr *TypeMismatchError
+	if !errors.As(err, &tmErr) {
+		t.Fatalf("expected TypeMismatchError, got %v", err)
+	}
+	if tmErr.Expected != StateUnsignedInteger || tmErr.Actual != StateTextString {
+		t.Errorf("got expected=%v actual=%v", tmErr.Expected, tmErr.Actual)
+	}
+
+	if err := r.ReadExpecting(StateTextString); err != nil {
+		t.Fatalf("ReadExpecting failed: %v", err)
+	}
+
+	// The item must not have been consumed
+	got, err := r.ReadTextString()
+	if err != nil {
+		t.Fatalf("ReadTextString failed: %v", err)
+	}
+	if got != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+}
+
+func writeUnsortedMap(t *testing.T, w *CborWriter) {
+	t.Helper()
+	if err := w.WriteStartMap(3); err != nil {
+		t.Fatalf("WriteStartMap failed: %v", err)
+	}
+	if err := w.WriteTextString("bb"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	if err := w.WriteInt64(1); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteInt64(100); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteInt64(2); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteTextString("a"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	if err := w.WriteStartArray(1); err != nil {
+		t.Fatalf("WriteStartArray failed: %v", err)
+	}
+	if err := w.WriteInt64(3); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteEndArray(); err != nil {
+		t.Fatalf("WriteEndArray failed: %v", err)
+	}
+	if err := w.WriteEndMap(); err != nil {
+		t.Fatalf("WriteEndMap failed: %v", err)
+	}
+}
+
+func TestDeterministicMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []WriterOption
+		expected string
+	}{
+		// Keys keep insertion order: "bb", 100, "a"
+		{"lax", nil, "a36262620118640261618103"},
+		// Bytewise: 100 (1864) < "a" (6161) < "bb" (626262)
+		{"deterministic", []WriterOption{WithWriterDeterministicMaps()}, "a31864026161810362626201"},
+		{"canonical", []WriterOption{WithConformanceMode(ConformanceCanonical)}, "a31864026161810362626201"},
+		// Length-first: 100 (2 bytes) and "a" (2 bytes) before "bb" (3 bytes)
+		{"ctap2", []WriterOption{WithConformanceMode(ConformanceCtap2Canonical)}, "a31864026161810362626201"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter(tt.opts...)
+			writeUnsortedMap(t, w)
+			got := hex.EncodeToString(w.Bytes())
+			if got != tt.expected {
+				t.Errorf("got %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeterministicMapsNested(t *testing.T) {
+	w := NewCborWriter(WithWriterDeterministicMaps())
+	if err := w.WriteStartIndefiniteLengthMap(); err != nil {
+		t.Fatalf("WriteStartIndefiniteLengthMap failed: %v", err)
+	}
+	if err := w.WriteTextString("z"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	writeUnsortedMap(t, w)
+	if err := w.WriteTextString("y"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	if err := w.WriteNull(); err != nil {
+		t.Fatalf("WriteNull failed: %v", err)
+	}
+	if err := w.WriteEndMap(); err != nil {
+		t.Fatalf("WriteEndMap failed: %v", err)
+	}
+
+	expected := "bf6179f6617a" + "a31864026161810362626201" + "ff"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
+
+func TestCanonicalModeRejectsDuplicateKeys(t *testing.T) {
+	for _, mode := range []CborConformanceMode{ConformanceCanonical, ConformanceCtap2Canonical} {
+		w := NewCborWriter(WithConformanceMode(mode))
+		if err := w.WriteStartMap(2); err != nil {
+			t.Fatalf("WriteStartMap failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := w.WriteTextString("k"); err != nil {
+				t.Fatalf("WriteTextString failed: %v", err)
+			}
+			if err := w.WriteInt64(int64(i)); err != nil {
+				t.Fatalf("WriteInt64 failed: %v", err)
+			}
+		}
+		if err := w.WriteEndMap(); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("mode %d: expected ErrDuplicateKey, got %v", mode, err)
+		}
+	}
+
+	// Deterministic maps only sort and leave duplicate detection to canonical modes
+	w := NewCborWriter(WithWriterDeterministicMaps())
+	if err := w.WriteStartMap(2); err != nil {
+		t.Fatalf("WriteStartMap failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.WriteTextString("k"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.WriteInt64(int64(i)); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+	}
+	if err := w.WriteEndMap(); err != nil {
+		t.Errorf("WriteEndMap failed: %v", err)
+	}
+}
+
+func TestReadByteStringInto(t *testing.T) {
+	t.Run("definite", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteByteString([]byte{1, 2, 3}); err != nil {
+			t.Fatalf("WriteByteString failed: %v", err)
+		}
+
+		r := NewCborReader(w.Bytes())
+		buf := make([]byte, 8)
+		n, err := r.ReadByteStringInto(buf)
+		if err != nil {
+			t.Fatalf("ReadByteStringInto failed: %v", err)
+		}
+		if !bytes.Equal(buf[:n], []byte{1, 2, 3}) {
+			t.Errorf("got %v, want [1 2 3]", buf[:n])
+		}
+	})
+
+	t.Run("indefinite", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartIndefiniteLengthByteString(); err != nil {
+			t.Fatalf("WriteStartIndefiniteLengthByteString failed: %v", err)
+		}
+		if err := w.WriteByteStringChunk([]byte{1, 2}); err != nil {
+			t.Fatalf("WriteByteStringChunk failed: %v", err)
+		}
+		if err := w.WriteByteStringChunk([]byte{3}); err != nil {
+			t.Fatalf("WriteByteStringChunk failed: %v", err)
+		}
+		if err := w.WriteEndIndefiniteLengthByteString(); err != nil {
+			t.Fatalf("WriteEndIndefiniteLengthByteString failed: %v", err)
+		}
+
+		r := NewCborReader(w.Bytes())
+		buf := make([]byte, 3)
+		n, err := r.ReadByteStringInto(buf)
+		if err != nil {
+			t.Fatalf("ReadByteStringInto failed: %v", err)
+		}
+		if !bytes.Equal(buf[:n], []byte{1, 2, 3}) {
+			t.Errorf("got %v, want [1 2 3]", buf[:n])
+		}
+	})
+
+	t.Run("buffer_too_small", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteByteString([]byte("hello")); err != nil {
+			t.Fatalf("WriteByteString failed: %v", err)
+		}
+
+		r := NewCborReader(w.Bytes())
+		n, err := r.ReadByteStringInto(make([]byte, 2))
+		if !errors.Is(err, ErrBufferTooSmall) {
+			t.Fatalf("expected ErrBufferTooSmall, got %v", err)
+		}
+		if n != 5 {
+			t.Errorf("got needed length %d, want 5", n)
+		}
+
+		// Nothing consumed, retry with a large enough buffer
+		buf := make([]byte, n)
+		if _, err := r.ReadByteStringInto(buf); err != nil {
+			t.Fatalf("ReadByteStringInto failed: %v", err)
+		}
+		if string(buf) != "hello" {
+			t.Errorf("got %q, want %q", buf, "hello")
+		}
+	})
+}
+
+func TestPeekTag(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteStartArray(1); err != nil {
+		t.Fatalf("WriteStartArray failed: %v", err)
+	}
+	if err := w.WriteUri("https://example.com"); err != nil {
+		t.Fatalf("WriteUri failed: %v", err)
+	}
+	if err := w.WriteEndArray(); err != nil {
+		t.Fatalf("WriteEndArray failed: %v", err)
+	}
+
+	r := NewCborReader(w.Bytes())
+	if _, err := r.PeekTag(); err == nil {
+		t.Fatal("expected error peeking a tag at an array")
+	}
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+
+	offset := r.CurrentOffset()
+	tag, err := r.PeekTag()
+	if err != nil {
+		t.Fatalf("PeekTag failed: %v", err)
+	}
+	if tag != TagURI {
+		t.Errorf("got tag %d, want %d", tag, TagURI)
+	}
+	if r.CurrentOffset() != offset {
+		t.Errorf("PeekTag moved offset from %d to %d", offset, r.CurrentOffset())
+	}
+
+	tag, err = r.ReadTag()
+	if err != nil {
+		t.Fatalf("ReadTag failed: %v", err)
+	}
+	if tag != TagURI {
+		t.Errorf("got tag %d, want %d", tag, TagURI)
+	}
+	if _, err := r.ReadTextString(); err != nil {
+		t.Fatalf("ReadTextString failed: %v", err)
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Fatalf("ReadEndArray failed: %v", err)
+	}
+}
+
+func TestReadArrayPrefix(t *testing.T) {
+	writeArray := func(t *testing.T, indefinite bool) []byte {
+		t.Helper()
+		w := NewCborWriter()
+		var err error
+		if indefinite {
+			err = w.WriteStartIndefiniteLengthArray()
+		} else {
+			err = w.WriteStartArray(5)
+		}
+		if err != nil {
+			t.Fatalf("start array failed: %v", err)
+		}
+		for i := 0; i < 4; i++ {
+			if err := w.WriteInt64(int64(i * 10)); err != nil {
+				t.Fatalf("WriteInt64 failed: %v", err)
+			}
+		}
+		if err := w.WriteStartMap(1); err != nil {
+			t.Fatalf("WriteStartMap failed: %v", err)
+		}
+		if err := w.WriteTextString("k"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.WriteTextString("v"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.WriteEndMap(); err != nil {
+			t.Fatalf("WriteEndMap failed: %v", err)
+		}
+		if err := w.WriteEndArray(); err != nil {
+			t.Fatalf("WriteEndArray failed: %v", err)
+		}
+		return w.BytesCopy()
+	}
+
+	for _, indefinite := range []bool{false, true} {
+		r := NewCborReader(writeArray(t, indefinite))
+		var got []int64
+		err := r.ReadArrayPrefix(2, func(i int, r *CborReader) error {
+			v, err := r.ReadInt64()
+			got = append(got, v)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("indefinite=%v: ReadArrayPrefix failed: %v", indefinite, err)
+		}
+		if len(got) != 2 || got[0] != 0 || got[1] != 10 {
+			t.Errorf("indefinite=%v: got %v, want [0 10]", indefinite, got)
+		}
+		state, err := r.PeekState()
+		if err != nil {
+			t.Fatalf("PeekState failed: %v", err)
+		}
+		if state != StateFinished {
+			t.Errorf("indefinite=%v: got state %v, want %v", indefinite, state, StateFinished)
+		}
+	}
+}
+
+func TestReaderBookmarkRestore(t *testing.T) {
+	// [1, [2, 3], 4]
+	r := NewCborReader([]byte{0x83, 0x01, 0x82, 0x02, 0x03, 0x04})
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	if _, err := r.ReadInt64(); err != nil {
+		t.Fatalf("ReadInt64 failed: %v", err)
+	}
+
+	mark := r.Bookmark()
+
+	// Speculatively read into the nested array, then rewind
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	if _, err := r.ReadInt64(); err != nil {
+		t.Fatalf("ReadInt64 failed: %v", err)
+	}
+	if r.NestingDepth() != 2 {
+		t.Fatalf("got depth %d, want 2", r.NestingDepth())
+	}
+
+	r.Restore(mark)
+	if r.NestingDepth() != 1 {
+		t.Errorf("got depth %d after restore, want 1", r.NestingDepth())
+	}
+
+	if err := r.SkipValue(); err != nil {
+		t.Fatalf("SkipValue failed: %v", err)
+	}
+	val, err := r.ReadInt64()
+	if err != nil {
+		t.Fatalf("ReadInt64 failed: %v", err)
+	}
+	if val != 4 {
+		t.Errorf("got %d, want 4", val)
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Fatalf("ReadEndArray failed: %v", err)
+	}
+
+	// The bookmark is unaffected by further reads and can be reused
+	r.Restore(mark)
+	if err := r.SkipValue(); err != nil {
+		t.Fatalf("SkipValue after second restore failed: %v", err)
+	}
+}
+
+func TestWriterTruncateTo(t *testing.T) {
+	t.Run("discard_partial_element", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartArray(2); err != nil {
+			t.Fatalf("WriteStartArray failed: %v", err)
+		}
+		if err := w.WriteInt64(1); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+
+		mark := w.Bookmark()
+		if err := w.WriteStartMap(2); err != nil {
+			t.Fatalf("WriteStartMap failed: %v", err)
+		}
+		if err := w.WriteTextString("a"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.WriteStartArray(3); err != nil {
+			t.Fatalf("WriteStartArray failed: %v", err)
+		}
+
+		if err := w.TruncateTo(mark); err != nil {
+			t.Fatalf("TruncateTo failed: %v", err)
+		}
+		if w.NestingDepth() != 1 {
+			t.Errorf("got depth %d, want 1", w.NestingDepth())
+		}
+		if w.Len() != mark {
+			t.Errorf("got length %d, want %d", w.Len(), mark)
+		}
+
+		if err := w.WriteInt64(2); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+		if err := w.WriteEndArray(); err != nil {
+			t.Fatalf("WriteEndArray failed: %v", err)
+		}
+		if got := hex.EncodeToString(w.Bytes()); got != "820102" {
+			t.Errorf("got %s, want 820102", got)
+		}
+	})
+
+	t.Run("discard_completed_elements", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartIndefiniteLengthArray(); err != nil {
+			t.Fatalf("WriteStartIndefiniteLengthArray failed: %v", err)
+		}
+		if err := w.WriteInt64(1); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+		mark := w.Bookmark()
+		if err := w.WriteStartArray(1); err != nil {
+			t.Fatalf("WriteStartArray failed: %v", err)
+		}
+		if err := w.WriteInt64(2); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+		if err := w.WriteEndArray(); err != nil {
+			t.Fatalf("WriteEndArray failed: %v", err)
+		}
+		if err := w.WriteInt64(3); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+
+		if err := w.TruncateTo(mark); err != nil {
+			t.Fatalf("TruncateTo failed: %v", err)
+		}
+		if err := w.WriteEndArray(); err != nil {
+			t.Fatalf("WriteEndArray failed: %v", err)
+		}
+		if got := hex.EncodeToString(w.Bytes()); got != "9f01ff" {
+			t.Errorf("got %s, want 9f01ff", got)
+		}
+	})
+
+	t.Run("restore_map_key_state", func(t *testing.T) {
+		w := NewCborWriter(WithConformanceMode(ConformanceCanonical))
+		if err := w.WriteStartMap(1); err != nil {
+			t.Fatalf("WriteStartMap failed: %v", err)
+		}
+		if err := w.WriteTextString("k"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		mark := w.Bookmark()
+		if err := w.WriteInt64(1); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+
+		if err := w.TruncateTo(mark); err != nil {
+			t.Fatalf("TruncateTo failed: %v", err)
+		}
+		// A key is pending, so the map cannot be closed yet
+		if err := w.WriteEndMap(); !errors.Is(err, ErrIncompleteContainer) {
+			t.Errorf("expected ErrIncompleteContainer, got %v", err)
+		}
+		if err := w.WriteInt64(2); err != nil {
+			t.Fatalf("WriteInt64 failed: %v", err)
+		}
+		if err := w.WriteEndMap(); err != nil {
+			t.Fatalf("WriteEndMap failed: %v", err)
+		}
+		if got := hex.EncodeToString(w.Bytes()); got != "a1616b02" {
+			t.Errorf("got %s, want a1616b02", got)
+		}
+	})
+
+	t.Run("invalid_marks", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteTextString("hello"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.TruncateTo(w.Len() + 1); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+		// Inside the string payload
+		if err := w.TruncateTo(3); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+		if w.Len() != 6 {
+			t.Errorf("failed truncation modified the buffer")
+		}
+		if err := w.TruncateTo(0); err != nil {
+			t.Fatalf("TruncateTo failed: %v", err)
+		}
+		if w.Len() != 0 {
+			t.Errorf("got length %d, want 0", w.Len())
+		}
+	})
+}
+
+func TestWriterTruncateToSortedMap(t *testing.T) {
+	for _, indefinite := range []bool{false, true} {
+		w := NewCborWriter(WithWriterDeterministicMaps())
+		if indefinite {
+			_ = w.WriteStartIndefiniteLengthMap()
+		} else {
+			_ = w.WriteStartMap(2)
+		}
+		_ = w.WriteTextString("b")
+		_ = w.WriteInt64(1)
+		mark := w.Bookmark()
+		_ = w.WriteTextString("a")
+		_ = w.WriteInt64(2)
+		if err := w.WriteEndMap(); err != nil {
+			t.Fatalf("WriteEndMap failed: %v", err)
+		}
+		sorted := hex.EncodeToString(w.Bytes())
+		if err := w.TruncateTo(mark); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("indefinite=%v: expected ErrInvalidState, got %v", indefinite, err)
+		}
+		if got := hex.EncodeToString(w.Bytes()); got != sorted {
+			t.Errorf("indefinite=%v: failed truncation modified the buffer: %s", indefinite, got)
+		}
+
+		// Marks before the sorted pairs are still valid.
+		if err := w.TruncateTo(0); err != nil {
+			t.Errorf("indefinite=%v: TruncateTo(0) failed: %v", indefinite, err)
+		}
+	}
+
+	// A map that was already in order is left untouched and keeps its marks.
+	w := NewCborWriter(WithWriterDeterministicMaps())
+	_ = w.WriteStartMap(2)
+	_ = w.WriteTextString("a")
+	_ = w.WriteInt64(1)
+	mark := w.Bookmark()
+	_ = w.WriteTextString("b")
+	_ = w.WriteInt64(2)
+	_ = w.WriteEndMap()
+	if err := w.TruncateTo(mark); err != nil {
+		t.Fatalf("TruncateTo failed: %v", err)
+	}
+	_ = w.WriteTextString("c")
+	_ = w.WriteInt64(3)
+	if err := w.WriteEndMap(); err != nil {
+		t.Fatalf("WriteEndMap failed: %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()); got != "a2616101616303" {
+		t.Errorf("got %s, want a2616101616303", got)
+	}
+}
+
+func TestCountRemainingItems(t *testing.T) {
+	t.Run("definite_array", func(t *testing.T) {
+		r := NewCborReader([]byte{0x83, 0x01, 0x02, 0x03})
+		if _, err := r.CountRemainingItems(); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState at root, got %v", err)
+		}
+		if _, err := r.ReadStartArray(); err != nil {
+			t.Fatalf("ReadStartArray failed: %v", err)
+		}
+		if _, err := r.ReadInt64(); err != nil {
+			t.Fatalf("ReadInt64 failed: %v", err)
+		}
+		n, err := r.CountRemainingItems()
+		if err != nil {
+			t.Fatalf("CountRemainingItems failed: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("got %d, want 2", n)
+		}
+	})
+
+	t.Run("indefinite_array", func(t *testing.T) {
+		// [_ 1, [2, 3], "a"]
+		r := NewCborReader([]byte{0x9f, 0x01, 0x82, 0x02, 0x03, 0x61, 0x61, 0xff})
+		if _, err := r.ReadStartArray(); err != nil {
+			t.Fatalf("ReadStartArray failed: %v", err)
+		}
+		offset := r.CurrentOffset()
+		n, err := r.CountRemainingItems()
+		if err != nil {
+			t.Fatalf("CountRemainingItems failed: %v", err)
+		}
+		if n != 3 {
+			t.Errorf("got %d, want 3", n)
+		}
+		if r.CurrentOffset() != offset || r.NestingDepth() != 1 {
+			t.Errorf("reader was not rewound")
+		}
+		for i := 0; i < 3; i++ {
+			if err := r.SkipValue(); err != nil {
+				t.Fatalf("SkipValue after count failed: %v", err)
+			}
+		}
+		if err := r.ReadEndArray(); err != nil {
+			t.Fatalf("ReadEndArray failed: %v", err)
+		}
+	})
+
+	t.Run("indefinite_map", func(t *testing.T) {
+		// {_ "a": 1, "b": [2]}
+		r := NewCborReader([]byte{0xbf, 0x61, 0x61, 0x01, 0x61, 0x62, 0x81, 0x02, 0xff})
+		if _, err := r.ReadStartMap(); err != nil {
+			t.Fatalf("ReadStartMap failed: %v", err)
+		}
+		n, err := r.CountRemainingItems()
+		if err != nil {
+			t.Fatalf("CountRemainingItems failed: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("got %d, want 2", n)
+		}
+		if _, err := r.ReadTextString(); err != nil {
+			t.Fatalf("ReadTextString failed: %v", err)
+		}
+		if _, err := r.CountRemainingItems(); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState between key and value, got %v", err)
+		}
+	})
+}
+
+func TestMaxStringLength(t *testing.T) {
+	t.Run("byte_string", func(t *testing.T) {
+		data := []byte{0x45, 1, 2, 3, 4, 5}
+		r := NewCborReader(data, WithReaderMaxByteStringLength(4))
+		if _, err := r.ReadByteString(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		r = NewCborReader(data, WithReaderMaxByteStringLength(5))
+		if _, err := r.ReadByteString(); err != nil {
+			t.Errorf("ReadByteString failed: %v", err)
+		}
+	})
+
+	t.Run("text_string", func(t *testing.T) {
+		data := []byte{0x63, 'a', 'b', 'c'}
+		r := NewCborReader(data, WithReaderMaxTextStringLength(2))
+		if _, err := r.ReadTextString(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		// The byte string limit does not apply to text strings
+		r = NewCborReader(data, WithReaderMaxByteStringLength(2))
+		if _, err := r.ReadTextString(); err != nil {
+			t.Errorf("ReadTextString failed: %v", err)
+		}
+	})
+
+	t.Run("indefinite_total", func(t *testing.T) {
+		// (_ h'0102', h'0304')
+		data := []byte{0x5f, 0x42, 1, 2, 0x42, 3, 4, 0xff}
+		r := NewCborReader(data, WithReaderMaxByteStringLength(3))
+		if _, err := r.ReadByteString(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("huge_declared_length", func(t *testing.T) {
+		data := []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		r := NewCborReader(data, WithReaderMaxByteStringLength(1<<20))
+		if _, err := r.ReadByteString(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		// Without a limit the length is still checked against the remaining data
+		r = NewCborReader(data)
+		if _, err := r.ReadByteString(); !errors.Is(err, ErrUnexpectedEndOfData) {
+			t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+		}
+	})
+}
+
+func TestWriterCheckpointRollback(t *testing.T) {
+	w := NewCborWriter(WithWriterDeterministicMaps())
+	if err := w.WriteStartMap(2); err != nil {
+		t.Fatalf("WriteStartMap failed: %v", err)
+	}
+	if err := w.WriteTextString("b"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+
+	c := w.Checkpoint()
+	if err := w.WriteStartArray(2); err != nil {
+		t.Fatalf("WriteStartArray failed: %v", err)
+	}
+	if err := w.WriteInt64(1); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.Rollback(c); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if err := w.WriteInt64(1); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteTextString("a"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	if err := w.WriteInt64(2); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteEndMap(); err != nil {
+		t.Fatalf("WriteEndMap failed: %v", err)
+	}
+
+	// Keys are still sorted after the rollback
+	if got := hex.EncodeToString(w.Bytes()); got != "a2616102616201" {
+		t.Errorf("got %s, want a2616102616201", got)
+	}
+
+	// Closing the map reordered the bytes the checkpoint points into
+	if err := w.Rollback(c); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState after the map was sorted, got %v", err)
+	}
+}
+
+func TestWriterRollbackReuse(t *testing.T) {
+	w := NewCborWriter()
+	_ = w.WriteStartArray(2)
+	_ = w.WriteInt64(1)
+	c := w.Checkpoint()
+	for i := 0; i < 2; i++ {
+		_ = w.WriteTextString("abc")
+		if err := w.Rollback(c); err != nil {
+			t.Fatalf("Rollback %d failed: %v", i, err)
+		}
+	}
+	if err := w.WriteInt64(2); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	if err := w.WriteEndArray(); err != nil {
+		t.Fatalf("WriteEndArray failed: %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()); got != "820102" {
+		t.Errorf("got %s, want 820102", got)
+	}
+}
+
+func TestWriterRollbackAcrossSortedMap(t *testing.T) {
+	for _, indefinite := range []bool{false, true} {
+		w := NewCborWriter(WithWriterDeterministicMaps())
+		if indefinite {
+			_ = w.WriteStartIndefiniteLengthMap()
+		} else {
+			_ = w.WriteStartMap(2)
+		}
+		_ = w.WriteTextString("b")
+		_ = w.WriteInt64(1)
+		c := w.Checkpoint()
+		_ = w.WriteTextString("a")
+		_ = w.WriteInt64(2)
+		if err := w.WriteEndMap(); err != nil {
+			t.Fatalf("WriteEndMap failed: %v", err)
+		}
+		if err := w.Rollback(c); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("indefinite=%v: expected ErrInvalidState, got %v", indefinite, err)
+		}
+	}
+
+	// A checkpoint before the map is unaffected by sorting it.
+	w := NewCborWriter(WithWriterDeterministicMaps())
+	c := w.Checkpoint()
+	_ = w.WriteStartMap(2)
+	_ = w.WriteTextString("b")
+	_ = w.WriteInt64(1)
+	_ = w.WriteTextString("a")
+	_ = w.WriteInt64(2)
+	_ = w.WriteEndMap()
+	if err := w.Rollback(c); err != nil || w.Len() != 0 {
+		t.Errorf("expected rollback to the empty writer, got %v with %d bytes", err, w.Len())
+	}
+}
+
+func TestWriterRollbackAfterTruncateTo(t *testing.T) {
+	w := NewCborWriter(WithAllowMultipleRootValues(true))
+	_ = w.WriteInt64(1)
+	m := w.Bookmark()
+	_ = w.WriteInt64(2)
+	c := w.Checkpoint()
+	_ = w.WriteInt64(3)
+	if err := w.TruncateTo(m); err != nil {
+		t.Fatalf("TruncateTo failed: %v", err)
+	}
+	_ = w.WriteTextString("xyz")
+	if err := w.Rollback(c); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState, got %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()); got != "016378797a" {
+		t.Errorf("failed rollback modified the buffer: %s", got)
+	}
+
+	// Rolling back below a later checkpoint invalidates it as well.
+	w = NewCborWriter(WithAllowMultipleRootValues(true))
+	early := w.Checkpoint()
+	_ = w.WriteInt64(1)
+	late := w.Checkpoint()
+	if err := w.Rollback(early); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	_ = w.WriteTextString("xyz")
+	if err := w.Rollback(late); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState for a checkpoint past the rollback, got %v", err)
+	}
+}
+
+func TestWriterRollbackAcrossReset(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteInt64(1); err != nil {
+		t.Fatalf("WriteInt64 failed: %v", err)
+	}
+	c := w.Checkpoint()
+	w.Reset()
+	if err := w.WriteStartArray(1); err != nil {
+		t.Fatalf("WriteStartArray failed: %v", err)
+	}
+	if err := w.Rollback(c); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState, got %v", err)
+	}
+}
+
+func TestMaxContainerLength(t *testing.T) {
+	t.Run("definite_array", func(t *testing.T) {
+		data := []byte{0x83, 0x01, 0x02, 0x03}
+		r := NewCborReader(data, WithReaderMaxArrayLength(2))
+		if _, err := r.ReadStartArray(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		r = NewCborReader(data, WithReaderMaxArrayLength(3))
+		if err := r.SkipValue(); err != nil {
+			t.Errorf("SkipValue failed: %v", err)
+		}
+	})
+
+	t.Run("definite_map", func(t *testing.T) {
+		data := []byte{0xa2, 0x01, 0x02, 0x03, 0x04}
+		r := NewCborReader(data, WithReaderMaxMapLength(1))
+		if _, err := r.ReadStartMap(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		// The array limit does not apply to maps
+		r = NewCborReader(data, WithReaderMaxArrayLength(1))
+		if err := r.SkipValue(); err != nil {
+			t.Errorf("SkipValue failed: %v", err)
+		}
+	})
+
+	t.Run("indefinite_array", func(t *testing.T) {
+		data := []byte{0x9f, 0x01, 0x02, 0x03, 0xff}
+		r := NewCborReader(data, WithReaderMaxArrayLength(2))
+		if err := r.SkipValue(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		r = NewCborReader(data, WithReaderMaxArrayLength(3))
+		if err := r.SkipValue(); err != nil {
+			t.Errorf("SkipValue failed: %v", err)
+		}
+	})
+
+	t.Run("indefinite_map", func(t *testing.T) {
+		data := []byte{0xbf, 0x01, 0x02, 0x03, 0x04, 0xff}
+		r := NewCborReader(data, WithReaderMaxMapLength(1))
+		if err := r.SkipValue(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		r = NewCborReader(data, WithReaderMaxMapLength(2))
+		if err := r.SkipValue(); err != nil {
+			t.Errorf("SkipValue failed: %v", err)
+		}
+	})
+
+	t.Run("huge_declared_length", func(t *testing.T) {
+		data := []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		r := NewCborReader(data, WithReaderMaxArrayLength(1000))
+		if _, err := r.ReadStartArray(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("expected ErrValueTooLarge, got %v", err)
+		}
+		// Without a limit the declared length cannot exceed the remaining data
+		r = NewCborReader(data)
+		if _, err := r.ReadStartArray(); !errors.Is(err, ErrUnexpectedEndOfData) {
+			t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+		}
+	})
+}
+
+func TestRat(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	values := []*big.Rat{
+		big.NewRat(1, 3),
+		big.NewRat(-7, 2),
+		big.NewRat(0, 1),
+		new(big.Rat).SetFrac(huge, big.NewInt(7)),
+		new(big.Rat).SetFrac(big.NewInt(-1), huge),
+	}
+
+	for _, v := range values {
+		t.Run(v.String(), func(t *testing.T) {
+			w := NewCborWriter()
+			if err := w.WriteRat(v); err != nil {
+				t.Fatalf("WriteRat failed: %v", err)
+			}
+
+			r := NewCborReader(w.Bytes())
+			got, err := r.ReadRat()
+			if err != nil {
+				t.Fatalf("ReadRat failed: %v", err)
+			}
+			if got.Cmp(v) != 0 {
+				t.Errorf("expected %s, got %s", v, got)
+			}
+		})
+	}
+
+	t.Run("encoding", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteRat(big.NewRat(1, 3)); err != nil {
+			t.Fatalf("WriteRat failed: %v", err)
+		}
+		if got := hex.EncodeToString(w.Bytes()); got != "d81e820103" {
+			t.Errorf("expected d81e820103, got %s", got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, h := range []string{
+			"d81e820100", // zero denominator
+			"d81e820120", // negative denominator
+			"d81e8101",   // wrong array length
+			"d819820103", // wrong tag
+		} {
+			data, _ := hex.DecodeString(h)
+			r := NewCborReader(data)
+			if _, err := r.ReadRat(); !errors.Is(err, ErrInvalidCbor) {
+				t.Errorf("%s: expected ErrInvalidCbor, got %v", h, err)
+			}
+		}
+	})
+}
+
+func TestReadBigIntChecked(t *testing.T) {
+	tests := []struct {
+		hex     string
+		value   string
+		minimal bool
+	}{
+		{"c249010000000000000000", "18446744073709551616", true},
+		{"c24a00010000000000000000", "18446744073709551616", false},
+		{"c349010000000000000000", "-18446744073709551617", true},
+		{"c34a00010000000000000000", "-18446744073709551617", false},
+		{"c240", "0", true},
+		{"c24100", "0", false},
+		{"1864", "100", true},
+		{"3863", "-100", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			r := NewCborReader(data)
+			value, minimal, err := r.ReadBigIntChecked()
+			if err != nil {
+				t.Fatalf("ReadBigIntChecked failed: %v", err)
+			}
+			if value.String() != tt.value {
+				t.Errorf("expected %s, got %s", tt.value, value)
+			}
+			if minimal != tt.minimal {
+				t.Errorf("expected minimal=%v, got %v", tt.minimal, minimal)
+			}
+			if r.BytesRemaining() != 0 {
+				t.Errorf("expected all data consumed, %d bytes remaining", r.BytesRemaining())
+			}
+		})
+	}
+
+	t.Run("other_tag", func(t *testing.T) {
+		data, _ := hex.DecodeString("c11a514b67b0")
+		r := NewCborReader(data)
+		var mismatch *TypeMismatchError
+		if _, _, err := r.ReadBigIntChecked(); !errors.As(err, &mismatch) {
+			t.Errorf("expected TypeMismatchError, got %v", err)
+		}
+	})
+}
+
+func TestReadTextStringToBuilder(t *testing.T) {
+	// ["ab", (_ "cd", "e"), "f"]
+	data, _ := hex.DecodeString("836261627f6263646165ff6166")
+	r := NewCborReader(data)
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+
+	var b strings.Builder
+	for _, want := range []int{2, 3, 1} {
+		n, err := r.ReadTextStringToBuilder(&b)
+		if err != nil {
+			t.Fatalf("ReadTextStringToBuilder failed: %v", err)
+		}
+		if n != want {
+			t.Errorf("expected %d bytes, got %d", want, n)
+		}
+	}
+	if b.String() != "abcdef" {
+		t.Errorf("expected abcdef, got %q", b.String())
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Fatalf("ReadEndArray failed: %v", err)
+	}
+
+	t.Run("invalid_utf8", func(t *testing.T) {
+		for _, h := range []string{"62c328", "7f6161 62c328 ff"} {
+			data, _ := hex.DecodeString(strings.ReplaceAll(h, " ", ""))
+			var b strings.Builder
+			r := NewCborReader(data, WithReaderConformanceMode(ConformanceLax))
+			if _, err := r.ReadTextStringToBuilder(&b); !errors.Is(err, ErrInvalidUtf8) {
+				t.Errorf("%s: expected ErrInvalidUtf8, got %v", h, err)
+			}
+			if b.Len() != 0 {
+				t.Errorf("%s: expected empty builder, got %q", h, b.String())
+			}
+		}
+	})
+
+	t.Run("type_mismatch", func(t *testing.T) {
+		var b strings.Builder
+		r := NewCborReader([]byte{0x41, 0x00})
+		var mismatch *TypeMismatchError
+		if _, err := r.ReadTextStringToBuilder(&b); !errors.As(err, &mismatch) {
+			t.Errorf("expected TypeMismatchError, got %v", err)
+		}
+	})
+}
+
+func TestDateTags(t *testing.T) {
+	tests := []struct {
+		date  time.Time
+		full  string
+		epoch string
+	}{
+		// Examples from RFC 8943
+		{time.Date(1940, 10, 9, 0, 0, 0, 0, time.UTC), "d903ec6a313934302d31302d3039", "d8643929b3"},
+		{time.Date(1980, 12, 8, 0, 0, 0, 0, time.UTC), "d903ec6a313938302d31322d3038", "d864190f9a"},
+		{time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), "d903ec6a313937302d30312d3031", "d86400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.full, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := w.WriteFullDate(tt.date); err != nil {
+				t.Fatalf("WriteFullDate failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.full {
+				t.Errorf("expected %s, got %s", tt.full, got)
+			}
+			got, err := NewCborReader(w.Bytes()).ReadFullDate()
+			if err != nil {
+				t.Fatalf("ReadFullDate failed: %v", err)
+			}
+			if !got.Equal(tt.date) || got.Location() != time.UTC {
+				t.Errorf("expected %v, got %v", tt.date, got)
+			}
+
+			w = NewCborWriter()
+			if err := w.WriteEpochDate(tt.date); err != nil {
+				t.Fatalf("WriteEpochDate failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.epoch {
+				t.Errorf("expected %s, got %s", tt.epoch, got)
+			}
+			got, err = NewCborReader(w.Bytes()).ReadEpochDate()
+			if err != nil {
+				t.Fatalf("ReadEpochDate failed: %v", err)
+			}
+			if !got.Equal(tt.date) || got.Location() != time.UTC {
+				t.Errorf("expected %v, got %v", tt.date, got)
+			}
+		})
+	}
+
+	t.Run("time_of_day", func(t *testing.T) {
+		noon := time.Date(2024, 2, 29, 12, 30, 0, 0, time.FixedZone("X", 3600))
+
+		// Lax mode keeps the calendar date and drops the clock
+		w := NewCborWriter()
+		if err := w.WriteFullDate(noon); err != nil {
+			t.Fatalf("WriteFullDate failed: %v", err)
+		}
+		got, err := NewCborReader(w.Bytes()).ReadFullDate()
+		if err != nil {
+			t.Fatalf("ReadFullDate failed: %v", err)
+		}
+		if want := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+
+		w = NewCborWriter(WithConformanceMode(ConformanceStrict))
+		if err := w.WriteFullDate(noon); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("expected ErrInvalidDate, got %v", err)
+		}
+		if err := w.WriteEpochDate(noon); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("expected ErrInvalidDate, got %v", err)
+		}
+		if w.Len() != 0 {
+			t.Errorf("expected nothing written, got %x", w.Bytes())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		data, _ := hex.DecodeString("d903ec6a323032342d30322d3330") // 2024-02-30
+		if _, err := NewCborReader(data).ReadFullDate(); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("expected ErrInvalidDate, got %v", err)
+		}
+		data, _ = hex.DecodeString("d86400")
+		if _, err := NewCborReader(data).ReadFullDate(); !errors.Is(err, ErrInvalidCbor) {
+			t.Errorf("expected ErrInvalidCbor, got %v", err)
+		}
+		data, _ = hex.DecodeString("d8641b7fffffffffffffff")
+		if _, err := NewCborReader(data).ReadEpochDate(); !errors.Is(err, ErrOverflow) {
+			t.Errorf("expected ErrOverflow, got %v", err)
+		}
+	})
+}
+
+func TestPeekIsIndefinite(t *testing.T) {
+	tests := []struct {
+		hex        string
+		indefinite bool
+	}{
+		{"80", false},
+		{"9fff", true},
+		{"a0", false},
+		{"bfff", true},
+		{"40", false},
+		{"5fff", true},
+		{"60", false},
+		{"7fff", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			r := NewCborReader(data)
+			got, err := r.PeekIsIndefinite()
+			if err != nil {
+				t.Fatalf("PeekIsIndefinite failed: %v", err)
+			}
+			if got != tt.indefinite {
+				t.Errorf("expected %v, got %v", tt.indefinite, got)
+			}
+			if r.CurrentOffset() != 0 {
+				t.Errorf("expected offset 0, got %d", r.CurrentOffset())
+			}
+			if err := r.SkipValue(); err != nil {
+				t.Errorf("SkipValue failed: %v", err)
+			}
+		})
+	}
+
+	r := NewCborReader([]byte{0x01})
+	var mismatch *TypeMismatchError
+	if _, err := r.PeekIsIndefinite(); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+}
+
+func TestEmptyIndefiniteStrings(t *testing.T) {
+	t.Run("byte_string", func(t *testing.T) {
+		r := NewCborReader([]byte{0x5f, 0xff})
+		got, err := r.ReadByteString()
+		if err != nil {
+			t.Fatalf("ReadByteString failed: %v", err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("expected empty non-nil slice, got %#v", got)
+		}
+		if state, err := r.PeekState(); err != nil || state != StateFinished {
+			t.Errorf("expected StateFinished, got %v (%v)", state, err)
+		}
+	})
+
+	t.Run("empty_chunks", func(t *testing.T) {
+		// (_ h'', h'')
+		r := NewCborReader([]byte{0x5f, 0x40, 0x40, 0xff})
+		got, err := r.ReadByteString()
+		if err != nil {
+			t.Fatalf("ReadByteString failed: %v", err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("expected empty non-nil slice, got %#v", got)
+		}
+	})
+
+	t.Run("text_string", func(t *testing.T) {
+		r := NewCborReader([]byte{0x7f, 0xff})
+		got, err := r.ReadTextString()
+		if err != nil {
+			t.Fatalf("ReadTextString failed: %v", err)
+		}
+		if got != "" {
+			t.Errorf("expected empty string, got %q", got)
+		}
+		if state, err := r.PeekState(); err != nil || state != StateFinished {
+			t.Errorf("expected StateFinished, got %v (%v)", state, err)
+		}
+	})
+
+	t.Run("in_containers", func(t *testing.T) {
+		// [_ (_ ), {(_ ): (_ )}, 1]
+		data := []byte{0x9f, 0x5f, 0xff, 0xa1, 0x7f, 0xff, 0x5f, 0xff, 0x01, 0xff}
+		r := NewCborReader(data)
+		if _, err := r.ReadStartArray(); err != nil {
+			t.Fatalf("ReadStartArray failed: %v", err)
+		}
+		if n, err := r.CountRemainingItems(); err != nil || n != 3 {
+			t.Errorf("expected 3 remaining items, got %d (%v)", n, err)
+		}
+		if b, err := r.ReadByteString(); err != nil || len(b) != 0 {
+			t.Fatalf("ReadByteString failed: %v %x", err, b)
+		}
+		if _, err := r.ReadStartMap(); err != nil {
+			t.Fatalf("ReadStartMap failed: %v", err)
+		}
+		var sb strings.Builder
+		if n, err := r.ReadTextStringToBuilder(&sb); err != nil || n != 0 {
+			t.Fatalf("ReadTextStringToBuilder failed: %v (%d)", err, n)
+		}
+		if n, err := r.ReadByteStringInto(nil); err != nil || n != 0 {
+			t.Fatalf("ReadByteStringInto failed: %v (%d)", err, n)
+		}
+		if err := r.ReadEndMap(); err != nil {
+			t.Fatalf("ReadEndMap failed: %v", err)
+		}
+		if v, err := r.ReadInt(); err != nil || v != 1 {
+			t.Fatalf("ReadInt failed: %v (%d)", err, v)
+		}
+		if err := r.ReadEndArray(); err != nil {
+			t.Fatalf("ReadEndArray failed: %v", err)
+		}
+
+		if err := NewCborReader(data).SkipValue(); err != nil {
+			t.Errorf("SkipValue failed: %v", err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, data := range [][]byte{{0x5f}, {0x7f}, {0x5f, 0x40}} {
+			r := NewCborReader(data)
+			if err := r.SkipValue(); !errors.Is(err, ErrUnexpectedEndOfData) {
+				t.Errorf("%x: expected ErrUnexpectedEndOfData, got %v", data, err)
+			}
+		}
+	})
+
+	t.Run("canonical", func(t *testing.T) {
+		r := NewCborReader([]byte{0x5f, 0xff}, WithReaderConformanceMode(ConformanceCanonical))
+		if _, err := r.ReadByteString(); !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
+			t.Errorf("expected ErrIndefiniteLengthNotAllowed, got %v", err)
+		}
+	})
+}
+
+func TestReadUnixTimeFloat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		secs  int64
+		nsecs int
+	}{
+		{"fraction rounds to nearest nanosecond", "c1fb3ff004189374bc6a", 1, 1000000},
+		{"year 2100", "c1fb41ee90cae0080000", 4102444800, 250000000},
+		{"pre-1970", "c1fbbff4000000000000", -2, 750000000},
+		{"just before epoch", "c1fbbf50624dd2f1a9fc", -1, 999000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			got, err := NewCborReader(data).ReadUnixTime()
+			if err != nil {
+				t.Fatalf("ReadUnixTime failed: %v", err)
+			}
+			if got.Unix() != tt.secs || got.Nanosecond() != tt.nsecs {
+				t.Errorf("expected %d.%09d, got %d.%09d", tt.secs, tt.nsecs, got.Unix(), got.Nanosecond())
+			}
+		})
+	}
+}
+
+func TestReadUnixTimeFloatOutOfRange(t *testing.T) {
+	for _, input := range []string{"c1f97e00", "c1f97c00", "c1fb7fefffffffffffff"} {
+		data, _ := hex.DecodeString(input)
+		if _, err := NewCborReader(data).ReadUnixTime(); !errors.Is(err, ErrOverflow) {
+			t.Errorf("%s: expected ErrOverflow, got %v", input, err)
+		}
+	}
+}
+
+func TestSelfDescribedTagSkipped(t *testing.T) {
+	// 55799(2(h'010000000000000000'))
+	data, _ := hex.DecodeString("d9d9f7c249010000000000000000")
+	value, err := NewCborReader(data).ReadBigInt()
+	if err != nil {
+		t.Fatalf("ReadBigInt failed: %v", err)
+	}
+	expected, _ := new(big.Int).SetString("18446744073709551616", 10)
+	if value.Cmp(expected) != 0 {
+		t.Errorf("expected %s, got %s", expected, value)
+	}
+
+	// 55799(55799(1(1363896240)))
+	data, _ = hex.DecodeString("d9d9f7d9d9f7c11a514b67b0")
+	tm, err := NewCborReader(data).ReadUnixTime()
+	if err != nil {
+		t.Fatalf("ReadUnixTime failed: %v", err)
+	}
+	if tm.Unix() != 1363896240 {
+		t.Errorf("expected 1363896240, got %d", tm.Unix())
+	}
+
+	// ReadAny keeps the tag
+	data, _ = hex.DecodeString("d9d9f701")
+	v, err := NewCborReader(data).ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if tv, ok := v.(TaggedValue); !ok || tv.Tag != TagSelfDescribedCbor {
+		t.Errorf("expected tagged value, got %#v", v)
+	}
+}
+
+func TestIsSelfDescribed(t *testing.T) {
+	data, _ := hex.DecodeString("d9d9f78101")
+	r := NewCborReader(data)
+	described, err := r.IsSelfDescribed()
+	if err != nil {
+		t.Fatalf("IsSelfDescribed failed: %v", err)
+	}
+	if !described {
+		t.Error("expected self-described data")
+	}
+
+	r = NewCborReader([]byte{0xc1, 0x01})
+	if described, err := r.IsSelfDescribed(); err != nil || described {
+		t.Errorf("expected false, got %v, %v", described, err)
+	}
+}
+
+func TestReaderStripSelfDescribe(t *testing.T) {
+	data, _ := hex.DecodeString("d9d9f78101")
+	r := NewCborReader(data, WithReaderStripSelfDescribe(true))
+	for i := 0; i < 2; i++ {
+		length, err := r.ReadStartArray()
+		if err != nil {
+			t.Fatalf("ReadStartArray failed: %v", err)
+		}
+		if length != 1 {
+			t.Errorf("expected length 1, got %d", length)
+		}
+		r.Reset()
+	}
+
+	// Without the prefix the option has no effect
+	r = NewCborReader([]byte{0x01}, WithReaderStripSelfDescribe(true))
+	if v, err := r.ReadInt64(); err != nil || v != 1 {
+		t.Errorf("expected 1, got %d, %v", v, err)
+	}
+}
+
+func TestWriteByteStringFromReader(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteStartArray(1); err != nil {
+		t.Fatalf("WriteStartArray failed: %v", err)
+	}
+	if err := w.WriteByteStringFromReader(strings.NewReader("hello world"), 5); err != nil {
+		t.Fatalf("WriteByteStringFromReader failed: %v", err)
+	}
+	if err := w.WriteEndArray(); err != nil {
+		t.Fatalf("WriteEndArray failed: %v", err)
+	}
+	expected, _ := hex.DecodeString("814568656c6c6f")
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("expected %x, got %x", expected, w.Bytes())
+	}
+}
+
+func TestWriteByteStringFromReaderShort(t *testing.T) {
+	w := NewCborWriter()
+	err := w.WriteByteStringFromReader(strings.NewReader("abc"), 4)
+	if !errors.Is(err, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+	}
+	if w.Len() != 0 {
+		t.Errorf("expected empty writer, got %x", w.Bytes())
+	}
+
+	if err := w.WriteByteStringFromReader(strings.NewReader(""), -1); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor, got %v", err)
+	}
+}
+
+// shortWriter accepts at most limit bytes per call without reporting an error.
+type shortWriter struct {
+	limit int
+}
+
+func (s shortWriter) Write(p []byte) (int, error) {
+	return min(len(p), s.limit), nil
+}
+
+func TestWriterWriteTo(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteTextString("hello"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := w.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != 6 || !bytes.Equal(buf.Bytes(), w.Bytes()) {
+		t.Errorf("expected 6 bytes %x, got %d bytes %x", w.Bytes(), n, buf.Bytes())
+	}
+
+	n, err = w.WriteTo(shortWriter{limit: 2})
+	if err != io.ErrShortWrite || n != 2 {
+		t.Errorf("expected 2 bytes and io.ErrShortWrite, got %d, %v", n, err)
+	}
+}
+
+func TestReaderReadFrom(t *testing.T) {
+	// Large enough to need the buffer to grow
+	w := NewCborWriter()
+	if err := w.WriteTextString(strings.Repeat("x", 2000)); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+
+	r := NewCborReader(nil)
+	n, err := r.ReadFrom(io.MultiReader(bytes.NewReader(w.Bytes()[:10]), bytes.NewReader(w.Bytes()[10:])))
+	if err != nil {
+		t.Fatalf("ReadFrom failed: %v", err)
+	}
+	if n != int64(w.Len()) {
+		t.Errorf("expected %d bytes, got %d", w.Len(), n)
+	}
+	s, err := r.ReadTextString()
+	if err != nil {
+		t.Fatalf("ReadTextString failed: %v", err)
+	}
+	if len(s) != 2000 {
+		t.Errorf("expected 2000 characters, got %d", len(s))
+	}
+}
+
+func TestReaderReadFromError(t *testing.T) {
+	r := NewCborReader([]byte{0x01})
+	errRead := errors.New("read failed")
+	failing := io.MultiReader(strings.NewReader("ab"), iotest.ErrReader(errRead))
+	if _, err := r.ReadFrom(failing); err != errRead {
+		t.Errorf("expected read error, got %v", err)
+	}
+	if v, err := r.ReadInt64(); err != nil || v != 1 {
+		t.Errorf("expected previous data to be kept, got %d, %v", v, err)
+	}
+}
+
+func TestReadIntegerValue(t *testing.T) {
+	minNegative, _ := new(big.Int).SetString("-18446744073709551616", 10)
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"00", int64(0)},
+		{"1b7fffffffffffffff", int64(math.MaxInt64)},
+		{"1b8000000000000000", new(big.Int).SetUint64(1 << 63)},
+		{"1bffffffffffffffff", new(big.Int).SetUint64(math.MaxUint64)},
+		{"20", int64(-1)},
+		{"3b7fffffffffffffff", int64(math.MinInt64)},
+		{"3b8000000000000000", new(big.Int).Sub(big.NewInt(math.MinInt64), big.NewInt(1))},
+		{"3bffffffffffffffff", minNegative},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			got, err := NewCborReader(data).ReadIntegerValue()
+			if err != nil {
+				t.Fatalf("ReadIntegerValue failed: %v", err)
+			}
+			switch want := tt.expected.(type) {
+			case int64:
+				if got != want {
+					t.Errorf("expected int64 %d, got %T %v", want, got, got)
+				}
+			case *big.Int:
+				if b, ok := got.(*big.Int); !ok || b.Cmp(want) != 0 {
+					t.Errorf("expected *big.Int %s, got %T %v", want, got, got)
+				}
+			}
+
+			asBig, err := NewCborReader(data).ReadBigInt()
+			if err != nil {
+				t.Fatalf("ReadBigInt failed: %v", err)
+			}
+			if asBig.String() != fmt.Sprint(tt.expected) {
+				t.Errorf("ReadBigInt: expected %v, got %s", tt.expected, asBig)
+			}
+		})
+	}
+}
+
+func TestReadInt64OverflowKeepsPosition(t *testing.T) {
+	data, _ := hex.DecodeString("3bffffffffffffffff")
+	r := NewCborReader(data)
+	if _, err := r.ReadInt64(); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	value, err := r.ReadBigInt()
+	if err != nil {
+		t.Fatalf("ReadBigInt failed: %v", err)
+	}
+	if value.String() != "-18446744073709551616" {
+		t.Errorf("expected -18446744073709551616, got %s", value)
+	}
+}
+
+func TestReaderErrorOffsets(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		read   func(r *CborReader) error
+		err    error
+		offset int
+	}{
+		{"truncated argument", "8219", func(r *CborReader) error {
+			if _, err := r.ReadStartArray(); err != nil {
+				return err
+			}
+			_, err := r.ReadUint64()
+			return err
+		}, ErrUnexpectedEndOfData, 1},
+		{"missing break", "9f01", func(r *CborReader) error {
+			if _, err := r.ReadStartArray(); err != nil {
+				return err
+			}
+			if _, err := r.ReadInt64(); err != nil {
+				return err
+			}
+			_, err := r.PeekState()
+			return err
+		}, ErrUnexpectedEndOfData, 2},
+		{"overflow", "1affffffff", func(r *CborReader) error {
+			_, err := r.ReadInt32()
+			return err
+		}, ErrOverflow, 5},
+		{"unexpected break", "ff", func(r *CborReader) error {
+			_, err := r.PeekState()
+			return err
+		}, ErrUnexpectedBreak, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			err := tt.read(NewCborReader(data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			var cborErr *CborError
+			if !errors.As(err, &cborErr) {
+				t.Fatalf("expected *CborError, got %T", err)
+			}
+			if cborErr.Offset != tt.offset {
+				t.Errorf("expected offset %d, got %d", tt.offset, cborErr.Offset)
+			}
+		})
+	}
+}
+
+func TestTypeMismatchErrorOffset(t *testing.T) {
+	// [1, 2, "a"]
+	data, _ := hex.DecodeString("8301026161")
+	r := NewCborReader(data)
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+
+	var err error
+	for err == nil {
+		_, err = r.ReadInt64()
+	}
+	var mismatch *TypeMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected TypeMismatchError, got %v", err)
+	}
+	if mismatch.Offset != 3 || mismatch.Actual != StateTextString {
+		t.Errorf("expected text string at offset 3, got %s at %d", mismatch.Actual, mismatch.Offset)
+	}
+	if !strings.Contains(err.Error(), "offset 3") {
+		t.Errorf("expected offset in message, got %q", err.Error())
+	}
+}
+
+func TestReservedAdditionalInfo(t *testing.T) {
+	inputs := []string{
+		"1c", "1d", "1e", "3c", "5c", "7d", "9d", "be", "dc", "fc", "fd", "fe",
+		"1f", "3f", "df",
+		"811c", "a1011d", "9f5cff",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			data, _ := hex.DecodeString(input)
+			if err := NewCborReader(data).SkipValue(); !errors.Is(err, ErrInvalidCbor) {
+				t.Errorf("SkipValue: expected ErrInvalidCbor, got %v", err)
+			}
+			if _, err := NewCborReader(data).ReadAny(); !errors.Is(err, ErrInvalidCbor) {
+				t.Errorf("ReadAny: expected ErrInvalidCbor, got %v", err)
+			}
+		})
+	}
+
+	// PeekState rejects the item itself rather than classifying it
+	data, _ := hex.DecodeString("811c")
+	r := NewCborReader(data)
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	var cborErr *CborError
+	if _, err := r.PeekState(); !errors.As(err, &cborErr) || cborErr.Offset != 1 {
+		t.Errorf("expected CborError at offset 1, got %v", err)
+	}
+}
+
+func TestNestedIndefiniteChunkRejected(t *testing.T) {
+	inputs := []string{
+		"5f5f4101ffff", // (_ (_ h'01'))
+		"5f41015fffff", // (_ h'01', (_ ))
+		"7f7f6161ffff", // (_ (_ "a"))
+	}
+
+	for _, input := range inputs {
+		data, _ := hex.DecodeString(input)
+		var cborErr *CborError
+		err := NewCborReader(data).SkipValue()
+		if !errors.Is(err, ErrInvalidCbor) || !errors.As(err, &cborErr) {
+			t.Errorf("%s: expected ErrInvalidCbor, got %v", input, err)
+		}
+	}
+
+	var b strings.Builder
+	data, _ := hex.DecodeString("7f7f6161ffff")
+	if _, err := NewCborReader(data).ReadTextStringToBuilder(&b); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("ReadTextStringToBuilder: expected ErrInvalidCbor, got %v", err)
+	}
+}
+
+func TestInvalidIndefiniteChunk(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+	}{
+		{"7f616161614101ff", 5}, // (_ "a", "a", h'01')
+		{"5f41016161ff", 3},     // (_ h'01', "a")
+		{"5f01ff", 1},           // (_ 1)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			err := NewCborReader(data).SkipValue()
+			if !errors.Is(err, ErrInvalidIndefiniteChunk) {
+				t.Fatalf("expected ErrInvalidIndefiniteChunk, got %v", err)
+			}
+			var cborErr *CborError
+			if !errors.As(err, &cborErr) || cborErr.Offset != tt.offset {
+				t.Errorf("expected offset %d, got %v", tt.offset, err)
+			}
+		})
+	}
+}
+
+func TestReaderRequireTextKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"a2616101616202", true},  // {"a": 1, "b": 2}
+		{"bf7f6161ff01ff", true},  // {_ (_ "a"): 1}
+		{"a16161820102", true},    // {"a": [1, 2]}
+		{"a26161010202", false},   // {"a": 1, 2: 2}
+		{"a1a0a0", false},         // {{}: {}}
+		{"a1616181a10101", false}, // {"a": [{1: 1}]}
+		{"a1c1616101", false},     // {1("a"): 1}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			_, err := NewCborReader(data, WithReaderRequireTextKeys(true)).ReadAny()
+			if tt.ok && err != nil {
+				t.Errorf("ReadAny failed: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrNonTextKey) {
+				t.Errorf("expected ErrNonTextKey, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWriterDanglingTag(t *testing.T) {
+	t.Run("end map", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartMap(1); err != nil {
+			t.Fatalf("WriteStartMap failed: %v", err)
+		}
+		if err := w.WriteTextString("a"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.WriteTag(TagUnixTime); err != nil {
+			t.Fatalf("WriteTag failed: %v", err)
+		}
+		if err := w.WriteEndMap(); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+	})
+
+	t.Run("end indefinite array", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartIndefiniteLengthArray(); err != nil {
+			t.Fatalf("WriteStartIndefiniteLengthArray failed: %v", err)
+		}
+		if err := w.WriteTag(TagUnixTime); err != nil {
+			t.Fatalf("WriteTag failed: %v", err)
+		}
+		if err := w.WriteEndArray(); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+	})
+
+	t.Run("tagged container", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartArray(1); err != nil {
+			t.Fatalf("WriteStartArray failed: %v", err)
+		}
+		if err := w.WriteTag(TagSelfDescribedCbor); err != nil {
+			t.Fatalf("WriteTag failed: %v", err)
+		}
+		if err := w.WriteStartMap(0); err != nil {
+			t.Fatalf("WriteStartMap failed: %v", err)
+		}
+		if err := w.WriteEndMap(); err != nil {
+			t.Fatalf("WriteEndMap failed: %v", err)
+		}
+		if err := w.WriteEndArray(); err != nil {
+			t.Errorf("WriteEndArray failed: %v", err)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteStartIndefiniteLengthArray(); err != nil {
+			t.Fatalf("WriteStartIndefiniteLengthArray failed: %v", err)
+		}
+		c := w.Checkpoint()
+		mark := w.Bookmark()
+		if err := w.WriteTag(TagUnixTime); err != nil {
+			t.Fatalf("WriteTag failed: %v", err)
+		}
+		if err := w.TruncateTo(mark); err != nil {
+			t.Fatalf("TruncateTo failed: %v", err)
+		}
+		if err := w.WriteTag(TagUnixTime); err != nil {
+			t.Fatalf("WriteTag failed: %v", err)
+		}
+		if err := w.Rollback(c); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if err := w.WriteEndArray(); err != nil {
+			t.Errorf("WriteEndArray failed: %v", err)
+		}
+	})
+}
+
+func TestWriterFinish(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []WriterOption
+		write func(w *CborWriter)
+		err   error
+	}{
+		{"complete", nil, func(w *CborWriter) {
+			w.WriteStartArray(1)
+			w.WriteInt(1)
+			w.WriteEndArray()
+		}, nil},
+		{"empty", nil, func(w *CborWriter) {}, ErrInvalidState},
+		{"open array", nil, func(w *CborWriter) {
+			w.WriteStartArray(2)
+			w.WriteInt(1)
+		}, ErrIncompleteContainer},
+		{"dangling key", nil, func(w *CborWriter) {
+			w.WriteStartIndefiniteLengthMap()
+			w.WriteTextString("a")
+		}, ErrIncompleteContainer},
+		{"dangling tag", nil, func(w *CborWriter) {
+			w.WriteTag(TagUnixTime)
+		}, ErrInvalidState},
+		{"two roots", nil, func(w *CborWriter) {
+			w.WriteInt(1)
+			w.WriteInt(2)
+		}, ErrNotAtEnd},
+		{"two roots allowed", []WriterOption{WithAllowMultipleRootValues(true)}, func(w *CborWriter) {
+			w.WriteInt(1)
+			w.WriteInt(2)
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter(tt.opts...)
+			tt.write(w)
+			err := w.Finish()
+			if tt.err == nil && err != nil {
+				t.Errorf("Finish failed: %v", err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestReaderFinish(t *testing.T) {
+	data := []byte{0x00, 0x00}
+
+	r := NewCborReader(data)
+	if _, err := r.ReadUint64(); err != nil {
+		t.Fatalf("ReadUint64 failed: %v", err)
+	}
+	var cborErr *CborError
+	if err := r.Finish(); !errors.Is(err, ErrNotAtEnd) || !errors.As(err, &cborErr) || cborErr.Offset != 1 {
+		t.Errorf("expected ErrNotAtEnd at offset 1, got %v", err)
+	}
+
+	r = NewCborReader(data, WithReaderAllowMultipleRootValues(true))
+	if _, err := r.ReadUint64(); err != nil {
+		t.Fatalf("ReadUint64 failed: %v", err)
+	}
+	if err := r.Finish(); err != nil {
+		t.Errorf("Finish failed: %v", err)
+	}
+
+	r = NewCborReader(data[:1])
+	if _, err := r.ReadUint64(); err != nil {
+		t.Fatalf("ReadUint64 failed: %v", err)
+	}
+	if err := r.Finish(); err != nil {
+		t.Errorf("Finish failed: %v", err)
+	}
+
+	r = NewCborReader([]byte{0x82, 0x01, 0x02})
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	if err := r.Finish(); !errors.Is(err, ErrIncompleteContainer) {
+		t.Errorf("expected ErrIncompleteContainer, got %v", err)
+	}
+}
+
+func TestSkipToEndOfContainer(t *testing.T) {
+	tests := []struct {
+		name     string
+		hex      string
+		readKeys int // keys to read before skipping; the last key's value is left unread
+	}{
+		{"definite_after_entry", "82a2616101616282020305", 2},
+		{"definite_pending_value", "82a2616101616282020305", 1},
+		{"indefinite_after_entry", "82bf61610161629f0203ffff05", 2},
+		{"indefinite_pending_value", "82bf61610161629f0203ffff05", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			r := NewCborReader(data)
+			r.ReadStartArray()
+			r.ReadStartMap()
+			r.ReadTextString()
+			if tt.readKeys == 2 {
+				r.ReadInt32()
+				r.ReadTextString()
+			}
+
+			if err := r.SkipToEndOfContainer(); err != nil {
+				t.Fatalf("SkipToEndOfContainer failed: %v", err)
+			}
+			v, err := r.ReadInt32()
+			if err != nil {
+				t.Fatalf("ReadInt32 failed: %v", err)
+			}
+			if v != 5 {
+				t.Errorf("expected 5, got %d", v)
+			}
+			if err := r.SkipToEndOfContainer(); err != nil {
+				t.Fatalf("SkipToEndOfContainer on exhausted array failed: %v", err)
+			}
+			if r.BytesRemaining() != 0 {
+				t.Errorf("expected no remaining bytes, got %d", r.BytesRemaining())
+			}
+		})
+	}
+
+	data, _ := hex.DecodeString("820102")
+	if err := NewCborReader(data).SkipToEndOfContainer(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState at root, got %v", err)
+	}
+}
+
+func TestReadEncodedValueAppend(t *testing.T) {
+	data, _ := hex.DecodeString("84a161610182f9bc00c249010000000000000000" + "6378797a" + "d8186401020304")
+	r := NewCborReader(data)
+	original := NewCborReader(data)
+	r.ReadStartArray()
+	original.ReadStartArray()
+
+	buf := []byte{0xaa}
+	for i := 0; i < 4; i++ {
+		start := len(buf)
+		var err error
+		buf, err = r.ReadEncodedValueAppend(buf)
+		if err != nil {
+			t.Fatalf("ReadEncodedValueAppend failed: %v", err)
+		}
+
+		want, err := original.ReadAny()
+		if err != nil {
+			t.Fatalf("ReadAny of original failed: %v", err)
+		}
+		got, err := NewCborReader(buf[start:]).ReadAny()
+		if err != nil {
+			t.Fatalf("ReadAny of copy failed: %v", err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("item %d: copy decodes to %v, original to %v", i, got, want)
+		}
+	}
+	if buf[0] != 0xaa || !bytes.Equal(buf[1:], data[1:]) {
+		t.Errorf("unexpected appended bytes %x", buf)
+	}
+}
+
+func TestReadEncodedValueAppendCanonical(t *testing.T) {
+	for _, input := range []string{"9f01ff", "bf616101ff", "5f4101ff", "7f6161ff"} {
+		data, _ := hex.DecodeString(input)
+		dst := []byte{0x01}
+		r := NewCborReader(data, WithReaderConformanceMode(ConformanceCanonical))
+		got, err := r.ReadEncodedValueAppend(dst)
+		if !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
+			t.Errorf("%s: expected ErrIndefiniteLengthNotAllowed, got %v", input, err)
+		}
+		if !bytes.Equal(got, dst) {
+			t.Errorf("%s: dst modified on error: %x", input, got)
+		}
+	}
+}
+
+func TestWriteRawValidated(t *testing.T) {
+	key, _ := hex.DecodeString("6161")
+	value, _ := hex.DecodeString("820102")
+
+	w := NewCborWriter()
+	w.WriteStartMap(2)
+	if err := w.WriteRawValidated(key); err != nil {
+		t.Fatalf("WriteRawValidated key failed: %v", err)
+	}
+	if err := w.WriteRawValidated(value); err != nil {
+		t.Fatalf("WriteRawValidated value failed: %v", err)
+	}
+	w.WriteTextString("b")
+	if err := w.WriteRawValidated(value); err != nil {
+		t.Fatalf("WriteRawValidated value failed: %v", err)
+	}
+	if err := w.WriteEndMap(); err != nil {
+		t.Fatalf("WriteEndMap failed: %v", err)
+	}
+
+	expected := "a261618201026162820102"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestWriteRawValidatedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		mode CborConformanceMode
+		hex  string
+		err  error
+	}{
+		{"truncated", ConformanceLax, "8201", ErrUnexpectedEndOfData},
+		{"two_items", ConformanceLax, "0102", ErrInvalidCbor},
+		{"empty", ConformanceLax, "", ErrUnexpectedEndOfData},
+		{"duplicate_key", ConformanceStrict, "a2616101616102", ErrDuplicateKey},
+		{"non_minimal", ConformanceStrict, "1801", ErrNonCanonical},
+		{"indefinite", ConformanceCanonical, "9f01ff", ErrIndefiniteLengthNotAllowed},
+		{"unsorted", ConformanceCanonical, "a2616201616102", ErrUnsortedKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			w := NewCborWriter(WithConformanceMode(tt.mode))
+			w.WriteStartArray(1)
+			if err := w.WriteRawValidated(data); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != "81" {
+				t.Errorf("buffer modified on error: %s", got)
+			}
+		})
+	}
+
+	data, _ := hex.DecodeString("818101")
+	w := NewCborWriter(WithMaxNestingDepth(2))
+	w.WriteStartArray(1)
+	if err := w.WriteRawValidated(data); !errors.Is(err, ErrNestingDepthExceeded) {
+		t.Errorf("expected ErrNestingDepthExceeded, got %v", err)
+	}
+}
+
+func TestWriteRawItem(t *testing.T) {
+	w := NewCborWriter()
+	w.WriteStartArray(3)
+	for _, item := range []string{"01", "1818", "3903e7"} {
+		data, _ := hex.DecodeString(item)
+		if err := w.WriteRawItem(data); err != nil {
+			t.Fatalf("WriteRawItem failed: %v", err)
+		}
+	}
+	if err := w.WriteEndArray(); err != nil {
+		t.Fatalf("WriteEndArray failed: %v", err)
+	}
+
+	expected := "830118183903e7"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	r := NewCborReader(w.Bytes())
+	r.ReadStartArray()
+	for _, want := range []int64{1, 24, -1000} {
+		got, err := r.ReadInt64()
+		if err != nil {
+			t.Fatalf("ReadInt64 failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("expected %d, got %d", want, got)
+		}
+	}
+
+	// WriteRaw leaves the container count untouched.
+	w = NewCborWriter()
+	w.WriteStartArray(1)
+	w.WriteRaw([]byte{0x01})
+	if err := w.WriteEndArray(); !errors.Is(err, ErrIncompleteContainer) {
+		t.Errorf("expected ErrIncompleteContainer after WriteRaw, got %v", err)
+	}
+}
+
+func TestCurrentContainer(t *testing.T) {
+	data, _ := hex.DecodeString("82a2616101616202" + "9f01ff")
+	r := NewCborReader(data)
+
+	check := func(name string, mt MajorType, isMap, expectingKey bool, remaining int64, ok bool) {
+		t.Helper()
+		gotMt, gotMap, gotKey, gotRemaining, gotOk := r.CurrentContainer()
+		if gotMt != mt || gotMap != isMap || gotKey != expectingKey || gotRemaining != remaining || gotOk != ok {
+			t.Errorf("%s: got (%v, %v, %v, %d, %v), expected (%v, %v, %v, %d, %v)", name,
+				gotMt, gotMap, gotKey, gotRemaining, gotOk, mt, isMap, expectingKey, remaining, ok)
+		}
+	}
+
+	check("root", 0, false, false, 0, false)
+	r.ReadStartArray()
+	check("array", MajorTypeArray, false, false, 2, true)
+	r.ReadStartMap()
+	check("map", MajorTypeMap, true, true, 2, true)
+	r.ReadTextString()
+	check("map value", MajorTypeMap, true, false, 2, true)
+	r.ReadInt32()
+	check("second key", MajorTypeMap, true, true, 1, true)
+	r.SkipToEndOfContainer()
+	check("array after map", MajorTypeArray, false, false, 1, true)
+	r.ReadStartArray()
+	check("indefinite", MajorTypeArray, false, false, -1, true)
+}
+
+func TestReaderClone(t *testing.T) {
+	data, _ := hex.DecodeString("8301820203" + "04")
+	r := NewCborReader(data, WithReaderConformanceMode(ConformanceStrict))
+	r.ReadStartArray()
+	r.ReadInt32()
+	r.ReadStartArray()
+
+	c := r.Clone()
+	if c.conformanceMode != ConformanceStrict {
+		t.Errorf("clone lost conformance mode")
+	}
+
+	// Finish the inner array on the clone only.
+	c.ReadInt32()
+	c.ReadInt32()
+	if err := c.ReadEndArray(); err != nil {
+		t.Fatalf("clone ReadEndArray failed: %v", err)
+	}
+	if v, err := c.ReadInt32(); err != nil || v != 4 {
+		t.Fatalf("clone ReadInt32: expected 4, got %d, %v", v, err)
+	}
+	if err := c.ReadEndArray(); err != nil {
+		t.Fatalf("clone ReadEndArray failed: %v", err)
+	}
+
+	if r.NestingDepth() != 2 || r.CurrentOffset() != 3 {
+		t.Errorf("original moved: depth %d, offset %d", r.NestingDepth(), r.CurrentOffset())
+	}
+	if v, err := r.ReadInt32(); err != nil || v != 2 {
+		t.Errorf("original ReadInt32: expected 2, got %d, %v", v, err)
+	}
+	if err := r.ReadEndArray(); !errors.As(err, new(*TypeMismatchError)) {
+		t.Errorf("expected original to be mid-array, got %v", err)
+	}
+}
+
+func TestWriterFloatMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  FloatMode
+		value any
+		hex   string
+	}{
+		{"shortest_float64", FloatShortest, 1.5, "f93e00"},
+		{"shortest_float32", FloatShortest, float32(1.5), "f93e00"},
+		{"double_float64", FloatAlwaysDouble, 1.5, "fb3ff8000000000000"},
+		{"double_float32", FloatAlwaysDouble, float32(1.5), "fb3ff8000000000000"},
+		{"preserve_float64", FloatPreserveInput, 1.5, "fb3ff8000000000000"},
+		{"preserve_float32", FloatPreserveInput, float32(1.5), "fa3fc00000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter(WithWriterFloatMode(tt.mode))
+			if err := w.WriteValue(tt.value); err != nil {
+				t.Fatalf("WriteValue failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.hex {
+				t.Errorf("expected %s, got %s", tt.hex, got)
+			}
+		})
+	}
+
+	w := NewCborWriter(WithWriterFloatMode(FloatAlwaysDouble))
+	w.WriteFloat16(1.5)
+	if got := hex.EncodeToString(w.Bytes()); got != "f93e00" {
+		t.Errorf("expected WriteFloat16 to keep its width, got %s", got)
+	}
+
+	w = NewCborWriter(WithWriterFloatMode(FloatAlwaysDouble), WithConformanceMode(ConformanceCanonical))
+	w.WriteFloat(1.5)
+	if got := hex.EncodeToString(w.Bytes()); got != "f93e00" {
+		t.Errorf("expected canonical mode to use shortest float, got %s", got)
+	}
+}
+
+func TestCtap2RejectsIntegralFloats(t *testing.T) {
+	tests := []struct {
+		hex    string
+		reject bool
+	}{
+		{"f94000", true},              // 2.0
+		{"f90000", true},              // 0.0
+		{"fa47c35000", true},          // 100000.0
+		{"fbc3f0000000000000", true},  // -2^64
+		{"fb43f0000000000000", false}, // 2^64 has no integer encoding
+		{"f93e00", false},             // 1.5
+		{"f98000", false},             // -0.0
+		{"f97c00", false},             // Infinity
+		{"f97e00", false},             // NaN
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			if _, err := NewCborReader(data).ReadFloat(); err != nil {
+				t.Fatalf("lax ReadFloat failed: %v", err)
+			}
+
+			r := NewCborReader(data, WithReaderConformanceMode(ConformanceCtap2Canonical))
+			_, err := r.ReadFloat()
+			if !tt.reject {
+				if err != nil {
+					t.Errorf("expected success, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNonCanonical) {
+				t.Fatalf("expected ErrNonCanonical, got %v", err)
+			}
+			if r.CurrentOffset() != 0 {
+				t.Errorf("expected reader to stay at offset 0, got %d", r.CurrentOffset())
+			}
+			if err := NewCborReader(data, WithReaderConformanceMode(ConformanceCtap2Canonical)).SkipValue(); !errors.Is(err, ErrNonCanonical) {
+				t.Errorf("expected SkipValue to reject, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadSimpleValueStrict(t *testing.T) {
+	tests := []struct {
+		hex   string
+		value SimpleValue
+		err   error
+	}{
+		{"f818", 24, ErrInvalidSimpleValue},
+		{"f819", 25, ErrInvalidSimpleValue},
+		{"f81a", 26, ErrInvalidSimpleValue},
+		{"f81b", 27, ErrInvalidSimpleValue},
+		{"f81c", 28, ErrInvalidSimpleValue},
+		{"f81d", 29, ErrInvalidSimpleValue},
+		{"f81e", 30, ErrInvalidSimpleValue},
+		{"f81f", 31, ErrInvalidSimpleValue},
+		{"f800", 0, ErrNonCanonical},
+		{"f814", 20, ErrNonCanonical},
+		{"f0", 16, nil},
+		{"f4", 20, nil},
+		{"f820", 32, nil},
+		{"f8ff", 255, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+
+			value, err := NewCborReader(data).ReadSimpleValue()
+			if err != nil || value != tt.value {
+				t.Fatalf("lax ReadSimpleValue: expected %d, got %d, %v", tt.value, value, err)
+			}
+
+			r := NewCborReader(data)
+			value, err = r.ReadSimpleValueStrict()
+			if tt.err == nil {
+				if err != nil || value != tt.value {
+					t.Errorf("ReadSimpleValueStrict: expected %d, got %d, %v", tt.value, value, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ReadSimpleValueStrict: expected %v, got %v", tt.err, err)
+			}
+			if r.CurrentOffset() != 0 {
+				t.Errorf("expected reader to stay at offset 0, got %d", r.CurrentOffset())
+			}
+
+			strict := NewCborReader(data, WithReaderConformanceMode(ConformanceStrict))
+			if err := strict.SkipValue(); !errors.Is(err, tt.err) {
+				t.Errorf("strict SkipValue: expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCanonicalTwoByteSimpleValues(t *testing.T) {
+	for _, mode := range []CborConformanceMode{ConformanceCanonical, ConformanceCtap2Canonical} {
+		for _, input := range []string{"f800", "f814", "f818", "f81f"} {
+			data, _ := hex.DecodeString(input)
+			r := NewCborReader(data, WithReaderConformanceMode(mode))
+			if _, err := r.ReadSimpleValue(); !errors.Is(err, ErrNonCanonical) {
+				t.Errorf("mode %d, %s: expected ErrNonCanonical, got %v", mode, input, err)
+			}
+		}
+
+		// ReadSimpleValueStrict reports reserved values as such on canonical readers too
+		for input, want := range map[string]error{
+			"f800": ErrNonCanonical,
+			"f814": ErrNonCanonical,
+			"f818": ErrInvalidSimpleValue,
+			"f81f": ErrInvalidSimpleValue,
+		} {
+			data, _ := hex.DecodeString(input)
+			r := NewCborReader(data, WithReaderConformanceMode(mode))
+			if _, err := r.ReadSimpleValueStrict(); !errors.Is(err, want) {
+				t.Errorf("mode %d, %s: ReadSimpleValueStrict expected %v, got %v", mode, input, want, err)
+			}
+		}
+
+		data, _ := hex.DecodeString("f820")
+		value, err := NewCborReader(data, WithReaderConformanceMode(mode)).ReadSimpleValue()
+		if err != nil || value != 32 {
+			t.Errorf("mode %d, f820: expected 32, got %d, %v", mode, value, err)
+		}
+	}
+}
+
+func TestFloatWidth(t *testing.T) {
+	tests := []struct {
+		hex   string
+		width int
+	}{
+		{"f93e00", 16},
+		{"fa47c35000", 32},
+		{"fb3ff199999999999a", 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			r := NewCborReader(data)
+			width, err := r.FloatWidth()
+			if err != nil {
+				t.Fatalf("FloatWidth failed: %v", err)
+			}
+			if width != tt.width {
+				t.Errorf("expected %d, got %d", tt.width, width)
+			}
+			if r.CurrentOffset() != 0 {
+				t.Errorf("FloatWidth consumed input")
+			}
+			if _, err := r.ReadFloat(); err != nil {
+				t.Errorf("ReadFloat after FloatWidth failed: %v", err)
+			}
+		})
+	}
+
+	var mismatch *TypeMismatchError
+	if _, err := NewCborReader([]byte{0x01}).FloatWidth(); !errors.As(err, &mismatch) || mismatch.Actual != StateUnsignedInteger {
+		t.Errorf("expected TypeMismatchError for integer, got %v", err)
+	}
+}
+
+func TestReadTags(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		tags []CborTag
+	}{
+		{"none", "6161", nil},
+		{"single", "d8206161", []CborTag{TagURI}},
+		{"chain", "d9d9f7d8206161", []CborTag{TagSelfDescribedCbor, TagURI}},
+		{"three", "c0d818d8206161", []CborTag{TagDateTimeString, TagEncodedCborData, TagURI}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			r := NewCborReader(data)
+			tags, err := r.ReadTags()
+			if err != nil {
+				t.Fatalf("ReadTags failed: %v", err)
+			}
+			if fmt.Sprint(tags) != fmt.Sprint(tt.tags) {
+				t.Errorf("expected %v, got %v", tt.tags, tags)
+			}
+			s, err := r.ReadTextString()
+			if err != nil || s != "a" {
+				t.Errorf("expected content \"a\", got %q, %v", s, err)
+			}
+		})
+	}
+
+	data, _ := hex.DecodeString("d820")
+	if _, err := NewCborReader(data).ReadTags(); !errors.Is(err, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData for dangling tag, got %v", err)
+	}
+}
+
+func TestReadTagsWithoutContent(t *testing.T) {
+	data, _ := hex.DecodeString("9fd820ff")
+	r := NewCborReader(data)
+	r.ReadStartArray()
+	if _, err := r.ReadTags(); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor for tag before break, got %v", err)
+	}
+}
+
+func TestWriteTags(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteTags(TagSelfDescribedCbor, TagURI); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+	if err := w.Finish(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState before content, got %v", err)
+	}
+	if err := w.WriteTextString("a"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	if err := w.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	expected := "d9d9f7d8206161"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	r := NewCborReader(w.Bytes())
+	tags, err := r.ReadTags()
+	if err != nil {
+		t.Fatalf("ReadTags failed: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != TagSelfDescribedCbor || tags[1] != TagURI {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	w = NewCborWriter()
+	w.WriteStartArray(1)
+	w.WriteTags(TagURI, TagURI)
+	if err := w.WriteEndArray(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState closing array with pending tags, got %v", err)
+	}
+}
+
+func TestNegativeZero(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	tests := []struct {
+		name  string
+		write func(*CborWriter) error
+		hex   string
+	}{
+		{"WriteFloat", func(w *CborWriter) error { return w.WriteFloat(negZero) }, "f98000"},
+		{"WriteFloat16", func(w *CborWriter) error { return w.WriteFloat16(float32(negZero)) }, "f98000"},
+		{"WriteFloat32", func(w *CborWriter) error { return w.WriteFloat32(float32(negZero)) }, "fa80000000"},
+		{"WriteFloat64", func(w *CborWriter) error { return w.WriteFloat64(negZero) }, "fb8000000000000000"},
+		{"WriteValue", func(w *CborWriter) error { return w.WriteValue(float32(negZero)) }, "f98000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := tt.write(w); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.hex {
+				t.Errorf("expected %s, got %s", tt.hex, got)
+			}
+
+			value, err := NewCborReader(w.Bytes()).ReadFloat()
+			if err != nil {
+				t.Fatalf("ReadFloat failed: %v", err)
+			}
+			if value != 0 || !math.Signbit(value) {
+				t.Errorf("expected -0, got %v", value)
+			}
+		})
+	}
+
+	canonical, err := Canonicalize([]byte{0xfb, 0x80, 0, 0, 0, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if got := hex.EncodeToString(canonical); got != "f98000" {
+		t.Errorf("expected Canonicalize to keep the sign, got %s", got)
+	}
+}
+
+func TestReadTextStringAppend(t *testing.T) {
+	data, _ := hex.DecodeString("83" + "6161" + "7f6262626163ff" + "60")
+	r := NewCborReader(data)
+	r.ReadStartArray()
+
+	buf := make([]byte, 0, 16)
+	var got []string
+	for i := 0; i < 3; i++ {
+		var err error
+		buf, err = r.ReadTextStringAppend(buf[:0])
+		if err != nil {
+			t.Fatalf("ReadTextStringAppend failed: %v", err)
+		}
+		got = append(got, string(buf))
+	}
+	if fmt.Sprint(got) != "[a bbc ]" {
+		t.Errorf("unexpected strings %q", got)
+	}
+
+	buf, err := NewCborReader([]byte{0x61, 0x7a}).ReadTextStringAppend([]byte("xy"))
+	if err != nil || string(buf) != "xyz" {
+		t.Errorf("expected xyz, got %q, %v", buf, err)
+	}
+}
+
+func TestReadTextStringAppendErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		mode CborConformanceMode
+		hex  string
+		err  error
+	}{
+		{"invalid_utf8", ConformanceStrict, "61ff", ErrInvalidUtf8},
+		{"invalid_utf8_chunk", ConformanceStrict, "7f6161" + "61ffff", ErrInvalidUtf8},
+		{"indefinite_canonical", ConformanceCanonical, "7f6161ff", ErrIndefiniteLengthNotAllowed},
+		{"truncated", ConformanceLax, "6261", ErrUnexpectedEndOfData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			dst := []byte("keep")
+			got, err := NewCborReader(data, WithReaderConformanceMode(tt.mode)).ReadTextStringAppend(dst)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if string(got) != "keep" {
+				t.Errorf("expected dst unchanged, got %q", got)
+			}
+		})
+	}
+
+	data, _ := hex.DecodeString("61ff")
+	if got, err := NewCborReader(data).ReadTextStringAppend(nil); err != nil || !bytes.Equal(got, []byte{0xff}) {
+		t.Errorf("lax reader should not validate UTF-8, got %x, %v", got, err)
+	}
+	var mismatch *TypeMismatchError
+	if _, err := NewCborReader([]byte{0x41, 0x61}).ReadTextStringAppend(nil); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError for byte string, got %v", err)
+	}
+}
+
+func TestSkipValueDeepNesting(t *testing.T) {
+	const depth = 10000
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"arrays", append(bytes.Repeat([]byte{0x81}, depth), 0x00)},
+		{"indefinite_arrays", append(bytes.Repeat([]byte{0x9f}, depth), bytes.Repeat([]byte{0xff}, depth)...)},
+		{"maps", append(bytes.Repeat([]byte{0xa1, 0x00}, depth), 0x00)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewCborReader(tt.data).SkipValue(); !errors.Is(err, ErrNestingDepthExceeded) {
+				t.Errorf("expected ErrNestingDepthExceeded, got %v", err)
+			}
+			if _, err := NewCborReader(tt.data).ReadAny(); !errors.Is(err, ErrNestingDepthExceeded) {
+				t.Errorf("ReadAny: expected ErrNestingDepthExceeded, got %v", err)
+			}
+		})
+	}
+
+	// Tags add no nesting, so a long chain is skipped without recursion.
+	data := append(bytes.Repeat([]byte{0xc6}, 100000), 0x00)
+	r := NewCborReader(data)
+	if err := r.SkipValue(); err != nil {
+		t.Fatalf("SkipValue of tag chain failed: %v", err)
+	}
+	if r.BytesRemaining() != 0 {
+		t.Errorf("expected tag chain to be consumed, %d bytes remain", r.BytesRemaining())
+	}
+}
+
+// TestLongTagChain checks that every API walking nested items counts tags against the
+// reader's nesting limit, since tags nest without entering a container.
+func TestLongTagChain(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(data []byte, opts ...ReaderOption) error
+	}{
+		{"Diagnose", func(data []byte, opts ...ReaderOption) error {
+			_, err := Diagnose(data, opts...)
+			return err
+		}},
+		{"ToJSON", func(data []byte, opts ...ReaderOption) error {
+			_, err := ToJSON(data, WithJSONReaderOptions(opts...))
+			return err
+		}},
+		{"ReadAny", func(data []byte, opts ...ReaderOption) error {
+			_, err := NewCborReader(data, opts...).ReadAny()
+			return err
+		}},
+		{"Unmarshal", func(data []byte, opts ...ReaderOption) error {
+			var v any
+			return Unmarshal(data, &v, opts...)
+		}},
+		{"Decode", func(data []byte, opts ...ReaderOption) error {
+			var v any
+			return NewDecoder(bytes.NewReader(data), opts...).Decode(&v)
+		}},
+		{"Equal", func(data []byte, opts ...ReaderOption) error {
+			_, err := Equal(data, data, WithEqualReaderOptions(opts...))
+			return err
+		}},
+		{"Canonicalize", func(data []byte, opts ...ReaderOption) error {
+			_, err := Canonicalize(data, opts...)
+			return err
+		}},
+		{"CanonicalHash", func(data []byte, opts ...ReaderOption) error {
+			return CanonicalHash(data, sha256.New(), opts...)
+		}},
+	}
+
+	// 3 MB of tag 6 around a single 0.
+	long := append(bytes.Repeat([]byte{0xc6}, 3<<20), 0x00)
+	// [6(6(0))] nests three deep when tags and containers share the limit.
+	mixed := []byte{0x81, 0xc6, 0xc6, 0x00}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(long); !errors.Is(err, ErrNestingDepthExceeded) {
+				t.Errorf("long chain: expected ErrNestingDepthExceeded, got %v", err)
+			}
+			if err := tt.run(mixed, WithReaderMaxNestingDepth(2)); !errors.Is(err, ErrNestingDepthExceeded) {
+				t.Errorf("depth 2: expected ErrNestingDepthExceeded, got %v", err)
+			}
+			if err := tt.run(mixed, WithReaderMaxNestingDepth(3)); err != nil {
+				t.Errorf("depth 3: unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSkipValueDeepNestingRaisedLimit(t *testing.T) {
+	const depth = 200000
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"arrays", append(bytes.Repeat([]byte{0x81}, depth), 0x00)},
+		{"indefinite_arrays", append(bytes.Repeat([]byte{0x9f}, depth), bytes.Repeat([]byte{0xff}, depth)...)},
+		{"maps", append(bytes.Repeat([]byte{0xa1, 0x00}, depth), 0x00)},
+		{"tagged_arrays", append(bytes.Repeat([]byte{0xc6, 0x81}, depth), 0x00)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{0x82}, append(tt.data, 0x07)...)
+			r := NewCborReader(data, WithReaderMaxNestingDepth(depth+1))
+			if _, err := r.ReadStartArray(); err != nil {
+				t.Fatalf("ReadStartArray failed: %v", err)
+			}
+			if err := r.SkipValue(); err != nil {
+				t.Fatalf("SkipValue failed: %v", err)
+			}
+			if v, err := r.ReadUint64(); err != nil || v != 7 {
+				t.Fatalf("expected 7 after skipped value, got %d, %v", v, err)
+			}
+			if err := r.ReadEndArray(); err != nil {
+				t.Fatalf("ReadEndArray failed: %v", err)
+			}
+
+			truncated := tt.data[:len(tt.data)-1]
+			r = NewCborReader(truncated, WithReaderMaxNestingDepth(depth))
+			if err := r.SkipValue(); !errors.Is(err, ErrUnexpectedEndOfData) {
+				t.Fatalf("expected ErrUnexpectedEndOfData, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSkipValueAtContainerEnd(t *testing.T) {
+	r := NewCborReader([]byte{0x9f, 0xff})
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	err := r.SkipValue()
+	var cborErr *CborError
+	if !errors.As(err, &cborErr) || !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("expected ErrInvalidState, got %v", err)
+	}
+	if cborErr.Offset != 1 {
+		t.Errorf("expected error offset 1, got %d", cborErr.Offset)
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Errorf("ReadEndArray after failed skip: %v", err)
+	}
+}
+
+func TestSkipValueTruncatedTag(t *testing.T) {
+	for _, data := range [][]byte{{0xc6}, {0xc6, 0xc6}, {0xd8, 0x20}} {
+		if err := NewCborReader(data).SkipValue(); !errors.Is(err, ErrUnexpectedEndOfData) {
+			t.Errorf("%x: expected ErrUnexpectedEndOfData, got %v", data, err)
+		}
+	}
+}
+
+func TestSmallIntFastPathChecks(t *testing.T) {
+	// Small integers skip the full state computation but must still honor container
+	// state and reader limits.
+	r := NewCborReader([]byte{0x81, 0x01, 0x02})
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	if v, err := r.ReadUint64(); err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d, %v", v, err)
+	}
+	var mismatch *TypeMismatchError
+	if _, err := r.ReadInt64(); !errors.As(err, &mismatch) || mismatch.Actual != StateEndArray {
+		t.Errorf("expected mismatch with end of array, got %v", err)
+	}
+
+	r = NewCborReader([]byte{0xa1, 0x01, 0x02}, WithReaderRequireTextKeys(true))
+	if _, err := r.ReadStartMap(); err != nil {
+		t.Fatalf("ReadStartMap failed: %v", err)
+	}
+	if _, err := r.ReadUint64(); !errors.Is(err, ErrNonTextKey) {
+		t.Errorf("expected ErrNonTextKey, got %v", err)
+	}
+
+	r = NewCborReader([]byte{0x9f, 0x01, 0x02, 0xff}, WithReaderMaxArrayLength(1))
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	if _, err := r.ReadInt64(); err != nil {
+		t.Fatalf("ReadInt64 failed: %v", err)
+	}
+	if _, err := r.ReadInt64(); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("expected ErrValueTooLarge, got %v", err)
+	}
+
+	r = NewCborReader([]byte{0x20, 0x17, 0x37})
+	for _, want := range []int64{-1, 23, -24} {
+		if v, err := r.ReadInt64(); err != nil || v != want {
+			t.Errorf("expected %d, got %d, %v", want, v, err)
+		}
+	}
+	if state, err := r.PeekState(); err != nil || state != StateFinished {
+		t.Errorf("expected StateFinished, got %v, %v", state, err)
+	}
+}
+
+func BenchmarkReadInt64Array(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		base int64
+	}{
+		{"small", 0},     // value in the initial byte
+		{"uint8", 100},   // one-byte argument
+		{"uint16", 1000}, // two-byte argument
+	} {
+		w := NewCborWriter()
+		_ = w.WriteStartArray(1000)
+		for i := int64(0); i < 1000; i++ {
+			_ = w.WriteInt64(bc.base + i%24)
+		}
+		_ = w.WriteEndArray()
+		data := w.Bytes()
+
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			r := NewCborReader(data)
+			for i := 0; i < b.N; i++ {
+				r.ResetWithData(data)
+				n, err := r.ReadStartArray()
+				if err != nil {
+					b.Fatal(err)
+				}
+				for j := 0; j < n; j++ {
+					if _, err := r.ReadInt64(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := r.ReadEndArray(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPeekStateThenRead(b *testing.B) {
+	w := NewCborWriter()
+	_ = w.WriteStartArray(400)
+	for i := 0; i < 100; i++ {
+		_ = w.WriteUint64(uint64(1000 + i))
+		_ = w.WriteInt64(int64(-100 - i))
+		_ = w.WriteBoolean(i%2 == 0)
+		_ = w.WriteSimpleValue(SimpleValue(32 + i))
+	}
+	_ = w.WriteEndArray()
+	data := w.Bytes()
+
+	// Each scalar is peeked and then read with the matching method, as a hand-written
+	// decoder dispatching on the state does, so every read can reuse the peeked header.
+	// decodes/op counts the reads that find no cached header and decode the initial byte
+	// again.
+	b.SetBytes(int64(len(data)))
+	r := NewCborReader(data)
+	for i := 0; i < b.N; i++ {
+		r.ResetWithData(data)
+		n, err := r.ReadStartArray()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < n; j++ {
+			state, err := r.PeekState()
+			if err != nil {
+				b.Fatal(err)
+			}
+			switch state {
+			case StateUnsignedInteger:
+				_, err = r.ReadUint64()
+			case StateNegativeInteger:
+				_, err = r.ReadInt64()
+			case StateBoolean:
+				_, err = r.ReadBoolean()
+			default:
+				_, err = r.ReadSimpleValue()
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := r.ReadEndArray(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(r.headerDecodes)/float64(b.N), "decodes/op")
+}
+
+func TestHeaderCacheReuse(t *testing.T) {
+	r := NewCborReader([]byte{0x18, 0x64})
+	if _, err := r.PeekState(); err != nil {
+		t.Fatalf("PeekState failed: %v", err)
+	}
+	if !r.headerCached || r.headerOffset != 0 || r.headerMt != MajorTypeUnsignedInteger || r.headerAi != 24 {
+		t.Fatalf("expected header of the peeked item to be cached")
+	}
+	if v, err := r.ReadUint64(); err != nil || v != 100 {
+		t.Fatalf("expected 100, got %d, %v", v, err)
+	}
+	if r.headerDecodes != 0 {
+		t.Errorf("expected the read to reuse the peeked header, got %d decodes", r.headerDecodes)
+	}
+
+	// The cached header is tied to the data and must not leak into new data.
+	r.ResetWithData([]byte{0x38, 0x63})
+	if v, err := r.ReadInt64(); err != nil || v != -100 {
+		t.Errorf("expected -100 after reset, got %d, %v", v, err)
+	}
+
+	r = NewCborReader([]byte{0x42, 0x01, 0x02})
+	if _, err := r.PeekState(); err != nil {
+		t.Fatalf("PeekState failed: %v", err)
+	}
+	sub := r.newSubReader([]byte{0x63, 0x61, 0x62, 0x63})
+	if s, err := sub.ReadTextString(); err != nil || s != "abc" {
+		t.Errorf("expected abc from sub reader, got %q, %v", s, err)
+	}
+}
+
+func TestWriterGrow(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteTextString("abc"); err != nil {
+		t.Fatalf("WriteTextString failed: %v", err)
+	}
+	w.Grow(4096)
+	if cap(w.Bytes())-w.Len() < 4096 {
+		t.Fatalf("expected room for 4096 bytes, got %d", cap(w.Bytes())-w.Len())
+	}
+	if !bytes.Equal(w.Bytes(), []byte{0x63, 'a', 'b', 'c'}) {
+		t.Errorf("Grow changed the encoded data: %x", w.Bytes())
+	}
+
+	before := &w.Bytes()[0]
+	if err := w.WriteByteString(make([]byte, 4000)); err != nil {
+		t.Fatalf("WriteByteString failed: %v", err)
+	}
+	if &w.Bytes()[0] != before {
+		t.Error("expected write within the grown capacity not to reallocate")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Grow with a negative count to panic")
+		}
+	}()
+	w.Grow(-1)
+}
+
+func TestWithSizeHint(t *testing.T) {
+	w := NewCborWriter(WithSizeHint(10000))
+	if cap(w.Bytes()) < 10000 {
+		t.Errorf("expected capacity of at least 10000, got %d", cap(w.Bytes()))
+	}
+
+	// A hint smaller than the buffer keeps it.
+	w = NewCborWriter(WithInitialCapacity(1024), WithSizeHint(10))
+	if cap(w.Bytes()) != 1024 {
+		t.Errorf("expected capacity 1024 to be kept, got %d", cap(w.Bytes()))
+	}
+}
+
+func BenchmarkWriterSizeHint(b *testing.B) {
+	const items = 10000
+	encode := func(w *CborWriter) {
+		_ = w.WriteStartArray(items)
+		for i := 0; i < items; i++ {
+			_ = w.WriteTextString("item")
+			_ = w.WriteInt64(int64(i))
+		}
+		_ = w.WriteEndArray()
+	}
+	size := func() int {
+		w := NewCborWriter()
+		encode(w)
+		return w.Len()
+	}()
+
+	b.Run("none", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			encode(NewCborWriter())
+		}
+	})
+	b.Run("hint", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			encode(NewCborWriter(WithSizeHint(size)))
+		}
+	})
+}
+
+func TestWriteSpecialFloats(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*CborWriter) error
+		want  string
+		check func(float64) bool
+	}{
+		{"nan", (*CborWriter).WriteNaN, "f97e00", math.IsNaN},
+		{"positive_infinity", (*CborWriter).WritePositiveInfinity, "f97c00", func(f float64) bool { return math.IsInf(f, 1) }},
+		{"negative_infinity", (*CborWriter).WriteNegativeInfinity, "f9fc00", func(f float64) bool { return math.IsInf(f, -1) }},
+	}
+
+	modes := []struct {
+		name string
+		opts []WriterOption
+	}{
+		{"default", nil},
+		{"always_double", []WriterOption{WithWriterFloatMode(FloatAlwaysDouble)}},
+		{"ctap2", []WriterOption{WithConformanceMode(ConformanceCtap2Canonical)}},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range modes {
+			t.Run(tt.name+"/"+mode.name, func(t *testing.T) {
+				w := NewCborWriter(mode.opts...)
+				if err := tt.write(w); err != nil {
+					t.Fatalf("write failed: %v", err)
+				}
+				if got := hex.EncodeToString(w.Bytes()); got != tt.want {
+					t.Errorf("expected %s, got %s", tt.want, got)
+				}
+
+				r := NewCborReader(w.Bytes(), WithReaderConformanceMode(ConformanceCtap2Canonical))
+				f, err := r.ReadFloat()
+				if err != nil {
+					t.Fatalf("ReadFloat failed: %v", err)
+				}
+				if !tt.check(f) {
+					t.Errorf("unexpected value %v", f)
+				}
+			})
+		}
+	}
+
+	// The special values count as items of their container.
+	w := NewCborWriter()
+	_ = w.WriteStartArray(3)
+	_ = w.WriteNaN()
+	_ = w.WritePositiveInfinity()
+	_ = w.WriteNegativeInfinity()
+	if err := w.WriteEndArray(); err != nil {
+		t.Fatalf("WriteEndArray failed: %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()); got != "83f97e00f97c00f9fc00" {
+		t.Errorf("unexpected array encoding %s", got)
+	}
+}
+
+func TestTryReadNullUndefinedAsNull(t *testing.T) {
+	r := NewCborReader([]byte{0x82, 0xf7, 0xf6}, WithReaderUndefinedAsNull(true))
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if ok, err := r.TryReadNull(); err != nil || !ok {
+			t.Fatalf("item %d: expected null, got %v, %v", i, ok, err)
+		}
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Fatalf("ReadEndArray failed: %v", err)
+	}
+
+	// ReadNull itself is strict, and without the option undefined is not null.
+	r = NewCborReader([]byte{0xf7}, WithReaderUndefinedAsNull(true))
+	var mismatch *TypeMismatchError
+	if err := r.ReadNull(); !errors.As(err, &mismatch) {
+		t.Errorf("expected ReadNull to reject undefined, got %v", err)
+	}
+	r = NewCborReader([]byte{0xf7})
+	if ok, err := r.TryReadNull(); err != nil || ok {
+		t.Errorf("expected undefined not to be null by default, got %v, %v", ok, err)
+	}
+}
+
+func TestAtBreak(t *testing.T) {
+	// [_ 1, {_ "a": 2}] read with hand-written loops.
+	data := []byte{0x9f, 0x01, 0xbf, 0x61, 'a', 0x02, 0xff, 0xff}
+	r := NewCborReader(data)
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	var items int
+	for {
+		done, err := r.AtBreak()
+		if err != nil {
+			t.Fatalf("AtBreak failed: %v", err)
+		}
+		if done {
+			break
+		}
+		items++
+		if err := r.SkipValue(); err != nil {
+			t.Fatalf("SkipValue failed: %v", err)
+		}
+	}
+	if items != 2 {
+		t.Errorf("expected 2 items, got %d", items)
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Fatalf("ReadEndArray failed: %v", err)
+	}
+
+	// A definite-length container reports its end once its items are read.
+	r = NewCborReader([]byte{0x81, 0x01})
+	_, _ = r.ReadStartArray()
+	if done, err := r.AtBreak(); err != nil || done {
+		t.Errorf("expected item before end, got %v, %v", done, err)
+	}
+	_, _ = r.ReadInt64()
+	if done, err := r.AtBreak(); err != nil || !done {
+		t.Errorf("expected end of definite array, got %v, %v", done, err)
+	}
+
+	// A map waiting for a value is not at its end.
+	r = NewCborReader([]byte{0xbf, 0x01, 0xff})
+	_, _ = r.ReadStartMap()
+	_, _ = r.ReadInt64()
+	if _, err := r.AtBreak(); !errors.Is(err, ErrIncompleteContainer) {
+		t.Errorf("expected ErrIncompleteContainer, got %v", err)
+	}
+
+	for _, data := range [][]byte{{0xff}, {0x01}} {
+		r = NewCborReader(data)
+		if _, err := r.AtBreak(); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%x: expected ErrInvalidState at root, got %v", data, err)
+		}
+		if r.BytesRemaining() != 1 {
+			t.Errorf("%x: expected nothing consumed", data)
+		}
+	}
+}
+
+func TestReadByteStringStream(t *testing.T) {
+	blob := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+
+	w := NewCborWriter()
+	_ = w.WriteStartArray(3)
+	_ = w.WriteByteString(blob)
+	_ = w.WriteStartIndefiniteLengthByteString()
+	_ = w.WriteByteStringChunk(blob[:100])
+	_ = w.WriteByteStringChunk(nil)
+	_ = w.WriteByteStringChunk(blob[100:])
+	_ = w.WriteEndIndefiniteLengthByteString()
+	_ = w.WriteInt64(7)
+	_ = w.WriteEndArray()
+
+	r := NewCborReader(w.Bytes())
+	if _, err := r.ReadStartArray(); err != nil {
+		t.Fatalf("ReadStartArray failed: %v", err)
+	}
+	for _, name := range []string{"definite", "indefinite"} {
+		stream, err := r.ReadByteStringStream()
+		if err != nil {
+			t.Fatalf("%s: ReadByteStringStream failed: %v", name, err)
+		}
+		if err := iotest.TestReader(stream, blob); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// The reader is already past both strings.
+	if v, err := r.ReadInt64(); err != nil || v != 7 {
+		t.Fatalf("expected 7, got %d, %v", v, err)
+	}
+	if err := r.ReadEndArray(); err != nil {
+		t.Fatalf("ReadEndArray failed: %v", err)
+	}
+}
+
+func TestReadByteStringStreamErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts []ReaderOption
+		want error
+	}{
+		{"truncated", "4401", nil, ErrUnexpectedEndOfData},
+		{"too_large", "43010203", []ReaderOption{WithReaderMaxByteStringLength(2)}, ErrValueTooLarge},
+		{"indefinite_too_large", "5f4201024101ff", []ReaderOption{WithReaderMaxByteStringLength(2)}, ErrValueTooLarge},
+		{"wrong_chunk", "5f6101ff", nil, ErrInvalidIndefiniteChunk},
+		{"missing_break", "5f4101", nil, ErrUnexpectedEndOfData},
+		{"canonical_indefinite", "5f4101ff", []ReaderOption{WithReaderConformanceMode(ConformanceCanonical)}, ErrIndefiniteLengthNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			if _, err := NewCborReader(data, tt.opts...).ReadByteStringStream(); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	var mismatch *TypeMismatchError
+	if _, err := NewCborReader([]byte{0x61, 'a'}).ReadByteStringStream(); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError for a text string, got %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Diagnose renders data in the diagnostic notation of RFC 8949 Section 8, for example
+// [1, h'0102', {"a": 3}, 1(1363896240), [_ 1, 2]]. Readers that allow multiple root
+// values render each item separated by ", ".
+func Diagnose(data []byte, opts ...ReaderOption) (string, error) {
+	return diagnose(data, "", opts)
+}
+
+// DiagnoseIndent is like Diagnose but places each array element and map entry on its own
+// line, indented by one copy of indent per nesting level, similar to json.MarshalIndent.
+// Empty containers and the chunks of indefinite-length strings stay on one line.
+func DiagnoseIndent(data []byte, indent string, opts ...ReaderOption) (string, error) {
+	return diagnose(data, indent, opts)
+}
+
+// diagnose renders every root item in data using the given indentation.
+func diagnose(data []byte, indent string, opts []ReaderOption) (string, error) {
+	r := NewCborReader(data, opts...)
+	d := &diagnoser{r: r, indent: indent}
+
+	for {
+		if err := d.item(); err != nil {
+			return "", err
+		}
+		if r.BytesRemaining() == 0 {
+			break
+		}
+		if !r.allowMultipleRootValues {
+			return "", NewCborError(ErrNotAtEnd, r.offset, "")
+		}
+		if indent == "" {
+			d.sb.WriteString(", ")
+		} else {
+			d.sb.WriteString(",\n")
+		}
+	}
+	return d.sb.String(), nil
+}
+
+// diagnoser accumulates diagnostic notation while walking a reader.
+type diagnoser struct {
+	r      *CborReader
+	sb     strings.Builder
+	indent string // empty for single-line output
+	depth  int
+	tags   int // enclosing tags, which count toward the reader's nesting limit
+}
+
+// item renders the next data item, including any nested items.
+func (d *diagnoser) item() error {
+	r := d.r
+	state, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case StateUnsignedInteger, StateNegativeInteger:
+		value, err := r.ReadAny()
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(&d.sb, value)
+
+	case StateByteString:
+		value, err := r.ReadByteString()
+		if err != nil {
+			return err
+		}
+		d.byteString(value)
+
+	case StateTextString:
+		value, err := r.ReadTextString()
+		if err != nil {
+			return err
+		}
+		d.textString(value)
+
+	case StateStartIndefiniteLengthByteString, StateStartIndefiniteLengthTextString:
+		return d.indefiniteString(state)
+
+	case StateStartArray:
+		length, err := r.ReadStartArray()
+		if err != nil {
+			return err
+		}
+		d.sb.WriteByte('[')
+		d.depth++
+		i := 0
+		for ; ; i++ {
+			more, err := r.moreItems(length, i, StateEndArray)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			d.separator(i, length < 0)
+			if err := d.item(); err != nil {
+				return err
+			}
+		}
+		d.depth--
+		d.closing(i, length < 0)
+		d.sb.WriteByte(']')
+		return r.ReadEndArray()
+
+	case StateStartMap:
+		length, err := r.ReadStartMap()
+		if err != nil {
+			return err
+		}
+		d.sb.WriteByte('{')
+		d.depth++
+		i := 0
+		for ; ; i++ {
+			more, err := r.moreItems(length, i, StateEndMap)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			d.separator(i, length < 0)
+			if err := d.item(); err != nil {
+				return err
+			}
+			d.sb.WriteString(": ")
+			if err := d.item(); err != nil {
+				return err
+			}
+		}
+		d.depth--
+		d.closing(i, length < 0)
+		d.sb.WriteByte('}')
+		return r.ReadEndMap()
+
+	case StateTag:
+		if len(r.nestingStack)+d.tags >= r.maxNestingDepth {
+			return NewCborError(ErrNestingDepthExceeded, r.offset, "Diagnose")
+		}
+		tag, err := r.ReadTag()
+		if err != nil {
+			return err
+		}
+		d.sb.WriteString(strconv.FormatUint(uint64(tag), 10))
+		d.sb.WriteByte('(')
+		d.tags++
+		if err := d.item(); err != nil {
+			return err
+		}
+		d.tags--
+		d.sb.WriteByte(')')
+
+	case StateBoolean, StateNull, StateUndefinedValue, StateSimpleValue:
+		value, err := r.ReadSimpleValue()
+		if err != nil {
+			return err
+		}
+		switch value {
+		case SimpleValueFalse:
+			d.sb.WriteString("false")
+		case SimpleValueTrue:
+			d.sb.WriteString("true")
+		case SimpleValueNull:
+			d.sb.WriteString("null")
+		case SimpleValueUndefined:
+			d.sb.WriteString("undefined")
+		default:
+			fmt.Fprintf(&d.sb, "simple(%d)", value)
+		}
+
+	case StateHalfPrecisionFloat, StateSinglePrecisionFloat, StateDoublePrecisionFloat:
+		value, err := r.ReadFloat()
+		if err != nil {
+			return err
+		}
+		bitSize := 64
+		if state != StateDoublePrecisionFloat {
+			bitSize = 32
+		}
+		d.sb.WriteString(formatDiagnosticFloat(value, bitSize))
+
+	default:
+		return NewCborError(ErrInvalidState, r.offset, "Diagnose")
+	}
+	return nil
+}
+
+// separator writes what precedes element i of a container: the indefinite-length marker
+// before the first element, commas between elements, and newlines when indenting.
+func (d *diagnoser) separator(i int, indefinite bool) {
+	switch {
+	case i == 0 && indefinite:
+		d.sb.WriteByte('_')
+		if d.indent == "" {
+			d.sb.WriteByte(' ')
+		}
+	case i > 0:
+		d.sb.WriteByte(',')
+		if d.indent == "" {
+			d.sb.WriteByte(' ')
+		}
+	}
+	if d.indent != "" {
+		d.sb.WriteByte('\n')
+		d.sb.WriteString(strings.Repeat(d.indent, d.depth))
+	}
+}
+
+// closing writes what precedes the closing bracket of a container with n elements.
+func (d *diagnoser) closing(n int, indefinite bool) {
+	switch {
+	case n == 0 && indefinite:
+		d.sb.WriteString("_ ")
+	case n > 0 && d.indent != "":
+		d.sb.WriteByte('\n')
+		d.sb.WriteString(strings.Repeat(d.indent, d.depth))
+	}
+}
+
+// indefiniteString renders an indefinite-length byte or text string as (_ chunk, ...),
+// or as an empty string literal followed by _ when it has no chunks.
+func (d *diagnoser) indefiniteString(state CborReaderState) error {
+	r := d.r
+	if r.conformanceMode >= ConformanceCanonical {
+		return NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "Diagnose")
+	}
+
+	mt := MajorTypeByteString
+	if state == StateStartIndefiniteLengthTextString {
+		mt = MajorTypeTextString
+	}
+
+	chunks := 0
+	err := r.readIndefiniteChunks(mt, func(chunk []byte) error {
+		if chunks == 0 {
+			d.sb.WriteString("(_ ")
+		} else {
+			d.sb.WriteString(", ")
+		}
+		chunks++
+		if mt == MajorTypeByteString {
+			d.byteString(chunk)
+			return nil
+		}
+		if r.conformanceMode >= ConformanceStrict && !utf8.Valid(chunk) {
+			return NewCborError(ErrInvalidUtf8, r.offset, "Diagnose")
+		}
+		d.textString(string(chunk))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case chunks > 0:
+		d.sb.WriteByte(')')
+	case mt == MajorTypeByteString:
+		d.sb.WriteString("''_")
+	default:
+		d.sb.WriteString(`""_`)
+	}
+	r.advanceContainer()
+	return nil
+}
+
+// byteString renders a byte string in base16 notation.
+func (d *diagnoser) byteString(b []byte) {
+	d.sb.WriteString("h'")
+	d.sb.WriteString(hex.EncodeToString(b))
+	d.sb.WriteByte('\'')
+}
+
+// textString renders a text string as a JSON-style quoted string.
+func (d *diagnoser) textString(s string) {
+	writeQuoted(&d.sb, s)
+}
+
+// writeQuoted writes s as a JSON string literal. Invalid UTF-8 is replaced by U+FFFD.
+func writeQuoted(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(sb, `\u%04x`, c)
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// formatDiagnosticFloat formats a float with the shortest representation that round-trips
+// at the given precision, always including a decimal point for finite values.
+func formatDiagnosticFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if strings.Contains(s, ".") {
+		return s
+	}
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		return s[:i] + ".0" + s[i:]
+	}
+	return s + ".0"
+}
@@ -0,0 +1,142 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	// Vectors from RFC 8949 Appendix A
+	tests := []struct {
+		hex  string
+		diag string
+	}{
+		{"00", "0"},
+		{"1bffffffffffffffff", "18446744073709551615"},
+		{"3bffffffffffffffff", "-18446744073709551616"},
+		{"3903e7", "-1000"},
+		{"f90000", "0.0"},
+		{"f98000", "-0.0"},
+		{"f93c00", "1.0"},
+		{"fb3ff199999999999a", "1.1"},
+		{"f93e00", "1.5"},
+		{"fa47c35000", "100000.0"},
+		{"fb7e37e43c8800759c", "1.0e+300"},
+		{"f97c00", "Infinity"},
+		{"f97e00", "NaN"},
+		{"f9fc00", "-Infinity"},
+		{"f4", "false"},
+		{"f5", "true"},
+		{"f6", "null"},
+		{"f7", "undefined"},
+		{"f0", "simple(16)"},
+		{"f8ff", "simple(255)"},
+		{"c074323031332d30332d32315432303a30343a30305a", `0("2013-03-21T20:04:00Z")`},
+		{"c249010000000000000000", "2(h'010000000000000000')"},
+		{"40", "h''"},
+		{"4401020304", "h'01020304'"},
+		{"60", `""`},
+		{"62225c", `"\"\\"`},
+		{"63e6b0b4", `"水"`},
+		{"80", "[]"},
+		{"8301820203820405", "[1, [2, 3], [4, 5]]"},
+		{"a201020304", "{1: 2, 3: 4}"},
+		{"a26161016162820203", `{"a": 1, "b": [2, 3]}`},
+		{"5f42010243030405ff", "(_ h'0102', h'030405')"},
+		{"7f657374726561646d696e67ff", `(_ "strea", "ming")`},
+		{"5fff", "''_"},
+		{"7fff", `""_`},
+		{"9fff", "[_ ]"},
+		{"9f018202039f0405ffff", "[_ 1, [2, 3], [_ 4, 5]]"},
+		{"bf61610161629f0203ffff", `{_ "a": 1, "b": [_ 2, 3]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			got, err := Diagnose(data)
+			if err != nil {
+				t.Fatalf("Diagnose failed: %v", err)
+			}
+			if got != tt.diag {
+				t.Errorf("expected %s, got %s", tt.diag, got)
+			}
+		})
+	}
+}
+
+func TestDiagnoseMultipleRoots(t *testing.T) {
+	data := []byte{0x01, 0x82, 0x02, 0x03}
+	if _, err := Diagnose(data); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+
+	got, err := Diagnose(data, WithReaderAllowMultipleRootValues(true))
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if got != "1, [2, 3]" {
+		t.Errorf("expected 1, [2, 3], got %s", got)
+	}
+}
+
+func TestDiagnoseIndent(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		diag string
+	}{
+		{"scalar", "01", "1"},
+		{"empty", "8380a09fff", "[\n  [],\n  {},\n  [_ ]\n]"},
+		{"nested", "a26161016162820203", "{\n  \"a\": 1,\n  \"b\": [\n    2,\n    3\n  ]\n}"},
+		{"indefinite", "bf61619f01ffff", "{_\n  \"a\": [_\n    1\n  ]\n}"},
+		{"tagged", "c18201f93e00", "1([\n  1,\n  1.5\n])"},
+		{"chunks", "815f42010243030405ff", "[\n  (_ h'0102', h'030405')\n]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			got, err := DiagnoseIndent(data, "  ")
+			if err != nil {
+				t.Fatalf("DiagnoseIndent failed: %v", err)
+			}
+			if got != tt.diag {
+				t.Errorf("expected\n%s\ngot\n%s", tt.diag, got)
+			}
+		})
+	}
+}
+
+func TestDiagnoseFloatRoundTrip(t *testing.T) {
+	values := []float64{0.1, 1.0 / 3, math.MaxFloat64, math.SmallestNonzeroFloat64, -2.5e-300}
+	for _, v := range values {
+		w := NewCborWriter()
+		if err := w.WriteFloat64(v); err != nil {
+			t.Fatalf("WriteFloat64 failed: %v", err)
+		}
+		got, err := Diagnose(w.Bytes())
+		if err != nil {
+			t.Fatalf("Diagnose failed: %v", err)
+		}
+		parsed, err := strconv.ParseFloat(got, 64)
+		if err != nil || parsed != v {
+			t.Errorf("%v rendered as %s, which parses to %v (%v)", v, got, parsed, err)
+		}
+	}
+
+	w := NewCborWriter()
+	if err := w.WriteFloat32(math.MaxFloat32); err != nil {
+		t.Fatalf("WriteFloat32 failed: %v", err)
+	}
+	got, err := Diagnose(w.Bytes())
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if got != "3.4028235e+38" {
+		t.Errorf("expected 3.4028235e+38, got %s", got)
+	}
+}
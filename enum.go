@@ -0,0 +1,58 @@
+package cbor
+
+import "reflect"
+
+// WithEnumStrings makes the reflection codec encode values of the given signed integer
+// types as text strings, using the name mapped to each value. Values without a name are
+// still encoded as integers, as are all values of types not in names. Types implementing
+// Marshaler keep encoding themselves.
+func WithEnumStrings(names map[reflect.Type]map[int]string) WriterOption {
+	return func(w *CborWriter) {
+		w.enumNames = names
+	}
+}
+
+// WithReaderEnumStrings makes the reflection decoder accept, for the given signed integer
+// types, the names of WithEnumStrings as well as integers. A text string that is not one
+// of the type's names returns ErrInvalidEnum. Names must be unique within a type.
+func WithReaderEnumStrings(names map[reflect.Type]map[int]string) ReaderOption {
+	return func(r *CborReader) {
+		r.enumValues = make(map[reflect.Type]map[string]int64, len(names))
+		for t, byValue := range names {
+			values := make(map[string]int64, len(byValue))
+			for v, name := range byValue {
+				values[name] = int64(v)
+			}
+			r.enumValues[t] = values
+		}
+	}
+}
+
+// enumName returns the name of rv under WithEnumStrings, if it has one.
+func (w *CborWriter) enumName(rv reflect.Value) (string, bool) {
+	names, ok := w.enumNames[rv.Type()]
+	if !ok {
+		return "", false
+	}
+	name, ok := names[int(rv.Int())]
+	return name, ok
+}
+
+// decodeEnumName reads a text string naming a value of rv's type under
+// WithReaderEnumStrings and stores the value in rv.
+func (r *CborReader) decodeEnumName(rv reflect.Value, values map[string]int64) error {
+	start := r.offset
+	name, err := r.ReadTextString()
+	if err != nil {
+		return err
+	}
+	value, ok := values[name]
+	if !ok {
+		return NewCborError(ErrInvalidEnum, start, rv.Type().String())
+	}
+	if rv.OverflowInt(value) {
+		return NewCborError(ErrOverflow, start, "Unmarshal")
+	}
+	rv.SetInt(value)
+	return nil
+}
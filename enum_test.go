@@ -0,0 +1,103 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type enumColor int
+
+const (
+	enumRed enumColor = iota
+	enumGreen
+	enumBlue
+)
+
+type enumLevel int8
+
+type enumConfig struct {
+	Color   enumColor         `cbor:"color"`
+	Level   enumLevel         `cbor:"level"`
+	Palette []enumColor       `cbor:"palette"`
+	ByColor map[enumColor]int `cbor:"by_color"`
+}
+
+var enumNames = map[reflect.Type]map[int]string{
+	reflect.TypeOf(enumRed):      {0: "red", 1: "green", 2: "blue"},
+	reflect.TypeOf(enumLevel(0)): {-1: "low", 1: "high"},
+}
+
+func TestEnumStringsRoundTrip(t *testing.T) {
+	in := enumConfig{
+		Color:   enumGreen,
+		Level:   -1,
+		Palette: []enumColor{enumBlue, enumColor(7)},
+		ByColor: map[enumColor]int{enumRed: 1},
+	}
+
+	data, err := Marshal(in, WithEnumStrings(enumNames), WithConformanceMode(ConformanceCanonical))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	// {"color": "green", "level": "low", "palette": ["blue", 7], "by_color": {"red": 1}}
+	const want = "a465636f6c6f7265677265656e656c6576656c636c6f77677061" +
+		"6c657474658264626c7565076862795f636f6c6f72a16372656401"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	var out enumConfig
+	if err := Unmarshal(data, &out, WithReaderEnumStrings(enumNames)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %+v, got %+v", in, out)
+	}
+}
+
+func TestEnumStringsDefaultsToIntegers(t *testing.T) {
+	data, err := Marshal(enumBlue)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if hex.EncodeToString(data) != "02" {
+		t.Errorf("expected integer encoding, got %x", data)
+	}
+
+	// With a mapping the decoder accepts both representations.
+	for _, data := range []string{"02", "64626c7565"} {
+		raw, _ := hex.DecodeString(data)
+		var c enumColor
+		if err := Unmarshal(raw, &c, WithReaderEnumStrings(enumNames)); err != nil || c != enumBlue {
+			t.Errorf("%s: expected blue, got %d, %v", data, c, err)
+		}
+	}
+
+	// Without one, names are not integers.
+	raw, _ := hex.DecodeString("64626c7565")
+	var c enumColor
+	var mismatch *TypeMismatchError
+	if err := Unmarshal(raw, &c); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError without a mapping, got %v", err)
+	}
+}
+
+func TestEnumStringsUnknownName(t *testing.T) {
+	raw, _ := hex.DecodeString("66707572706c65")
+	var c enumColor
+	err := Unmarshal(raw, &c, WithReaderEnumStrings(enumNames))
+	var cborErr *CborError
+	if !errors.Is(err, ErrInvalidEnum) || !errors.As(err, &cborErr) || cborErr.Offset != 0 {
+		t.Errorf("expected ErrInvalidEnum at offset 0, got %v", err)
+	}
+
+	// A name mapped to a value outside the target type overflows.
+	names := map[reflect.Type]map[int]string{reflect.TypeOf(enumLevel(0)): {300: "huge"}}
+	raw, _ = hex.DecodeString("6468756765")
+	var l enumLevel
+	if err := Unmarshal(raw, &l, WithReaderEnumStrings(names)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package cbor
+
+import "errors"
+
+// WriteEnvelope writes a versioned envelope: a two-element array [version, payload].
+// The payload callback must write exactly one value.
+func (w *CborWriter) WriteEnvelope(version uint64, payload func(*CborWriter) error) error {
+	if err := w.WriteStartArray(2); err != nil {
+		return err
+	}
+	if err := w.WriteUint64(version); err != nil {
+		return err
+	}
+	if err := payload(w); err != nil {
+		return err
+	}
+	return w.WriteEndArray()
+}
+
+// ReadEnvelope reads a versioned envelope written by WriteEnvelope and returns the version
+// together with a reader over the payload item. The payload reader shares the underlying
+// data and uses the same options as r.
+func (r *CborReader) ReadEnvelope() (uint64, *CborReader, error) {
+	start := r.offset
+	length, err := r.ReadStartArray()
+	if err != nil {
+		return 0, nil, err
+	}
+	if length != 2 && length != -1 {
+		return 0, nil, NewCborError(ErrInvalidCbor, start, "envelope must be a two-element array")
+	}
+
+	version, err := r.ReadUint64()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if length == -1 {
+		if err := r.ReadExpecting(StateEndArray); err == nil {
+			return 0, nil, NewCborError(ErrInvalidCbor, start, "envelope must be a two-element array")
+		}
+	}
+
+	payloadStart := r.offset
+	if err := r.SkipValue(); err != nil {
+		return 0, nil, err
+	}
+	payload := r.data[payloadStart:r.offset]
+
+	if err := r.ReadEndArray(); err != nil {
+		var tmErr *TypeMismatchError
+		if errors.As(err, &tmErr) {
+			return 0, nil, NewCborError(ErrInvalidCbor, start, "envelope must be a two-element array")
+		}
+		return 0, nil, err
+	}
+
+	return version, r.newSubReader(payload), nil
+}
+
+// newSubReader returns a reader over data with the same configuration as r.
+func (r *CborReader) newSubReader(data []byte) *CborReader {
+	sub := *r
+	sub.data = data
+	sub.offset = 0
+	sub.nestingStack = make([]readerNestingInfo, 0, 16)
+	sub.cachedState = StateUndefined
+	sub.stateComputed = false
+	sub.headerCached = false
+	sub.rangeParent = nil
+	sub.lastRanges = nil
+	return &sub
+}
@@ -0,0 +1,100 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	w := NewCborWriter()
+	err := w.WriteEnvelope(3, func(w *CborWriter) error {
+		if err := w.WriteStartMap(1); err != nil {
+			return err
+		}
+		if err := w.WriteTextString("a"); err != nil {
+			return err
+		}
+		if err := w.WriteInt64(1); err != nil {
+			return err
+		}
+		return w.WriteEndMap()
+	})
+	if err != nil {
+		t.Fatalf("WriteEnvelope failed: %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()); got != "8203a1616101" {
+		t.Errorf("got %s, want 8203a1616101", got)
+	}
+
+	r := NewCborReader(w.Bytes())
+	version, payload, err := r.ReadEnvelope()
+	if err != nil {
+		t.Fatalf("ReadEnvelope failed: %v", err)
+	}
+	if version != 3 {
+		t.Errorf("got version %d, want 3", version)
+	}
+	if _, err := payload.ReadStartMap(); err != nil {
+		t.Fatalf("ReadStartMap failed: %v", err)
+	}
+	if key, err := payload.ReadTextString(); err != nil || key != "a" {
+		t.Fatalf("got key %q, err %v", key, err)
+	}
+	if val, err := payload.ReadInt64(); err != nil || val != 1 {
+		t.Fatalf("got value %d, err %v", val, err)
+	}
+	if err := payload.ReadEndMap(); err != nil {
+		t.Fatalf("ReadEndMap failed: %v", err)
+	}
+
+	state, err := r.PeekState()
+	if err != nil {
+		t.Fatalf("PeekState failed: %v", err)
+	}
+	if state != StateFinished {
+		t.Errorf("got state %v, want %v", state, StateFinished)
+	}
+}
+
+func TestEnvelopeRejectsInvalidShape(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"one_element", "8101"},
+		{"three_elements", "83010203"},
+		{"indefinite_one_element", "9f01ff"},
+		{"indefinite_three_elements", "9f010203ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			r := NewCborReader(data)
+			if _, _, err := r.ReadEnvelope(); !errors.Is(err, ErrInvalidCbor) {
+				t.Errorf("expected ErrInvalidCbor, got %v", err)
+			}
+		})
+	}
+
+	// The version must be an unsigned integer
+	r := NewCborReader([]byte{0x82, 0x61, 0x61, 0x01})
+	var tmErr *TypeMismatchError
+	if _, _, err := r.ReadEnvelope(); !errors.As(err, &tmErr) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+}
+
+func TestWriteEnvelopeRequiresSinglePayload(t *testing.T) {
+	w := NewCborWriter()
+	err := w.WriteEnvelope(1, func(w *CborWriter) error {
+		if err := w.WriteInt64(1); err != nil {
+			return err
+		}
+		return w.WriteInt64(2)
+	})
+	if !errors.Is(err, ErrExtraItems) {
+		t.Errorf("expected ErrExtraItems, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package cbor
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+)
+
+// equalNode is a decoded data item in the form compared by Equal.
+type equalNode struct {
+	kind   CborReaderState // one of the states below identifies the node kind
+	num    *big.Int        // StateUnsignedInteger: any integer, including bignums
+	float  float64         // StateDoublePrecisionFloat: any float width
+	str    []byte          // StateByteString or StateTextString
+	simple SimpleValue     // StateSimpleValue: booleans, null, undefined and other simple values
+	tag    CborTag         // StateTag
+	items  []equalNode     // array elements, alternating map keys and values, or tag content
+}
+
+// equalOptions holds the configuration of an Equal call.
+type equalOptions struct {
+	nanEqual   bool
+	readerOpts []ReaderOption
+}
+
+// EqualOption configures Equal.
+type EqualOption func(*equalOptions)
+
+// WithEqualNaN makes Equal treat NaN floats as equal to each other. By default NaN
+// compares unequal to everything, as in IEEE 754.
+func WithEqualNaN(enable bool) EqualOption {
+	return func(o *equalOptions) {
+		o.nanEqual = enable
+	}
+}
+
+// WithEqualReaderOptions configures the readers Equal decodes both items with, for example
+// to change their nesting depth limit.
+func WithEqualReaderOptions(opts ...ReaderOption) EqualOption {
+	return func(o *equalOptions) {
+		o.readerOpts = append(o.readerOpts, opts...)
+	}
+}
+
+// Equal reports whether a and b each hold a single data item and the two items are
+// semantically equal regardless of encoding: integers match across argument widths and
+// bignums, floats across widths, strings across definite and indefinite lengths, arrays in
+// order and maps as unordered collections of key/value pairs, in which a duplicate key
+// matches any equal pair. Floats never equal integers. The
+// reader's maximum nesting depth, counting tags, bounds the comparison.
+func Equal(a, b []byte, opts ...EqualOption) (bool, error) {
+	var o equalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ra := NewCborReader(a, o.readerOpts...)
+	x, err := ra.readEqualNode(0)
+	if err != nil {
+		return false, err
+	}
+	if ra.BytesRemaining() > 0 {
+		return false, NewCborError(ErrNotAtEnd, ra.offset, "")
+	}
+
+	rb := NewCborReader(b, o.readerOpts...)
+	y, err := rb.readEqualNode(0)
+	if err != nil {
+		return false, err
+	}
+	if rb.BytesRemaining() > 0 {
+		return false, NewCborError(ErrNotAtEnd, rb.offset, "")
+	}
+
+	return equalNodes(&x, &y, o.nanEqual), nil
+}
+
+// readEqualNode decodes the next item at the given depth of containers and tags.
+func (r *CborReader) readEqualNode(depth int) (equalNode, error) {
+	if depth > r.maxNestingDepth {
+		return equalNode{}, NewCborError(ErrNestingDepthExceeded, r.offset, "Equal")
+	}
+
+	state, err := r.PeekState()
+	if err != nil {
+		return equalNode{}, err
+	}
+
+	switch state {
+	case StateUnsignedInteger, StateNegativeInteger:
+		value, err := r.ReadAny()
+		if err != nil {
+			return equalNode{}, err
+		}
+		num := new(big.Int)
+		switch v := value.(type) {
+		case uint64:
+			num.SetUint64(v)
+		case int64:
+			num.SetInt64(v)
+		case *big.Int:
+			num = v
+		}
+		return equalNode{kind: StateUnsignedInteger, num: num}, nil
+
+	case StateByteString, StateStartIndefiniteLengthByteString:
+		value, err := r.ReadByteString()
+		return equalNode{kind: StateByteString, str: value}, err
+
+	case StateTextString, StateStartIndefiniteLengthTextString:
+		value, err := r.ReadTextString()
+		return equalNode{kind: StateTextString, str: []byte(value)}, err
+
+	case StateStartArray, StateStartMap:
+		end := StateEndArray
+		perItem := 1
+		var length int
+		if state == StateStartArray {
+			length, err = r.ReadStartArray()
+		} else {
+			end = StateEndMap
+			perItem = 2
+			length, err = r.ReadStartMap()
+		}
+		if err != nil {
+			return equalNode{}, err
+		}
+
+		node := equalNode{kind: state, items: make([]equalNode, 0, max(length, 0)*perItem)}
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, end)
+			if err != nil {
+				return equalNode{}, err
+			}
+			if !more {
+				break
+			}
+			for j := 0; j < perItem; j++ {
+				item, err := r.readEqualNode(depth + 1)
+				if err != nil {
+					return equalNode{}, err
+				}
+				node.items = append(node.items, item)
+			}
+		}
+		if state == StateStartArray {
+			err = r.ReadEndArray()
+		} else {
+			err = r.ReadEndMap()
+		}
+		return node, err
+
+	case StateTag:
+		tag, err := r.PeekTag()
+		if err != nil {
+			return equalNode{}, err
+		}
+		if tag == TagUnsignedBignum || tag == TagNegativeBignum {
+			value, err := r.ReadBigInt()
+			return equalNode{kind: StateUnsignedInteger, num: value}, err
+		}
+		if _, err := r.ReadTag(); err != nil {
+			return equalNode{}, err
+		}
+		content, err := r.readEqualNode(depth + 1)
+		if err != nil {
+			return equalNode{}, err
+		}
+		return equalNode{kind: StateTag, tag: tag, items: []equalNode{content}}, nil
+
+	case StateBoolean, StateNull, StateUndefinedValue, StateSimpleValue:
+		value, err := r.ReadSimpleValue()
+		return equalNode{kind: StateSimpleValue, simple: value}, err
+
+	case StateHalfPrecisionFloat, StateSinglePrecisionFloat, StateDoublePrecisionFloat:
+		value, err := r.ReadFloat()
+		return equalNode{kind: StateDoublePrecisionFloat, float: value}, err
+
+	default:
+		return equalNode{}, NewCborError(ErrInvalidState, r.offset, "Equal")
+	}
+}
+
+// equalNodes compares two decoded items, matching map entries regardless of order.
+func equalNodes(x, y *equalNode, nanEqual bool) bool {
+	if x.kind != y.kind {
+		return false
+	}
+
+	switch x.kind {
+	case StateUnsignedInteger:
+		return x.num.Cmp(y.num) == 0
+	case StateDoublePrecisionFloat:
+		if math.IsNaN(x.float) && math.IsNaN(y.float) {
+			return nanEqual
+		}
+		return x.float == y.float
+	case StateByteString, StateTextString:
+		return bytes.Equal(x.str, y.str)
+	case StateSimpleValue:
+		return x.simple == y.simple
+	case StateTag:
+		return x.tag == y.tag && equalNodes(&x.items[0], &y.items[0], nanEqual)
+	case StateStartArray:
+		if len(x.items) != len(y.items) {
+			return false
+		}
+		for i := range x.items {
+			if !equalNodes(&x.items[i], &y.items[i], nanEqual) {
+				return false
+			}
+		}
+		return true
+	case StateStartMap:
+		if len(x.items) != len(y.items) {
+			return false
+		}
+		// Entries match as whole pairs, so maps with duplicate keys compare as multisets.
+		used := make([]bool, len(y.items)/2)
+		for i := 0; i < len(x.items); i += 2 {
+			found := false
+			for j := 0; j < len(y.items); j += 2 {
+				if used[j/2] || !equalNodes(&x.items[i], &y.items[j], nanEqual) ||
+					!equalNodes(&x.items[i+1], &y.items[j+1], nanEqual) {
+					continue
+				}
+				used[j/2] = true
+				found = true
+				break
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
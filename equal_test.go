@@ -0,0 +1,76 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{"identical", "820102", "820102", true},
+		{"int_width", "01", "1b0000000000000001", true},
+		{"negative_width", "20", "3800", true},
+		{"bignum_leading_zero", "c249010000000000000000", "c24a00010000000000000000", true},
+		{"bignum_vs_int", "c24101", "01", true},
+		{"int_vs_float", "01", "f93c00", false},
+		{"float_width", "f93e00", "fb3ff8000000000000", true},
+		{"indefinite_bytes", "4401020304", "5f420102420305ff", false},
+		{"indefinite_bytes_equal", "4401020304", "5f420102420304ff", true},
+		{"indefinite_text", "6568656c6c6f", "7f626865636c6c6fff", true},
+		{"indefinite_array", "820102", "9f0102ff", true},
+		{"array_order", "820102", "820201", false},
+		{"map_order", "a2616101616202", "a2616202616101", true},
+		{"map_indefinite", "a16161820102", "bf61619f0102ffff", true},
+		{"map_value_differs", "a2616101616202", "a2616103616202", false},
+		{"map_size", "a1616101", "a2616101616202", false},
+		{"array_keys", "a1820102f5", "a1820102f5", true},
+		{"duplicate_keys", "a20102" + "0103", "a20103" + "0102", true},
+		{"duplicate_keys_differ", "a20102" + "0102", "a20102" + "0103", false},
+		{"duplicate_keys_differ_reversed", "a20102" + "0103", "a20102" + "0102", false},
+		{"tag", "c11a514b67b0", "c11a514b67b0", true},
+		{"tag_number", "c11a514b67b0", "c01a514b67b0", false},
+		{"tag_vs_untagged", "c101", "01", false},
+		{"simple", "f5", "f4", false},
+		{"null_undefined", "f6", "f7", false},
+		{"nan", "f97e00", "fb7ff8000000000000", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := hex.DecodeString(tt.a)
+			b, _ := hex.DecodeString(tt.b)
+			got, err := Equal(a, b)
+			if err != nil {
+				t.Fatalf("Equal failed: %v", err)
+			}
+			if got != tt.equal {
+				t.Errorf("expected %v, got %v", tt.equal, got)
+			}
+		})
+	}
+}
+
+func TestEqualNaN(t *testing.T) {
+	a, _ := hex.DecodeString("a1f97e0001")
+	b, _ := hex.DecodeString("a1fb7ff800000000000001")
+	if got, err := Equal(a, b); err != nil || got {
+		t.Errorf("expected NaN keys to differ, got %v (%v)", got, err)
+	}
+	if got, err := Equal(a, b, WithEqualNaN(true)); err != nil || !got {
+		t.Errorf("expected NaN keys to match, got %v (%v)", got, err)
+	}
+}
+
+func TestEqualErrors(t *testing.T) {
+	if _, err := Equal([]byte{0x01, 0x02}, []byte{0x01}); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+	if _, err := Equal([]byte{0x01}, []byte{0x82, 0x01}); !errors.Is(err, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+	}
+}
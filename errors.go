@@ -60,6 +60,46 @@ var (
 
 	// ErrExtraItems is returned when a container has more items than expected.
 	ErrExtraItems = errors.New("cbor: extra items in container")
+
+	// ErrValueTooLarge is returned when a declared length exceeds a configured limit.
+	ErrValueTooLarge = errors.New("cbor: value exceeds configured size limit")
+
+	// ErrUnsupportedType is returned when a Go type cannot be encoded or decoded.
+	ErrUnsupportedType = errors.New("cbor: unsupported Go type")
+
+	// ErrInvalidUnmarshalTarget is returned when decoding into a nil or non-pointer value.
+	ErrInvalidUnmarshalTarget = errors.New("cbor: decode target must be a non-nil pointer")
+
+	// ErrInvalidDate is returned when a date-only value is malformed or carries a time of day.
+	ErrInvalidDate = errors.New("cbor: invalid date")
+
+	// ErrInvalidIPAddress is returned when an IP address or prefix has an invalid form.
+	ErrInvalidIPAddress = errors.New("cbor: invalid IP address")
+
+	// ErrInvalidPath is returned when a path is empty, malformed or conflicts with another path.
+	ErrInvalidPath = errors.New("cbor: invalid or conflicting path")
+
+	// ErrInvalidIndefiniteChunk is returned when a chunk of an indefinite-length string has a
+	// different major type than the string.
+	ErrInvalidIndefiniteChunk = errors.New("cbor: indefinite-length string chunk of wrong type")
+
+	// ErrNonTextKey is returned when a map key is not a text string and text keys are required.
+	ErrNonTextKey = errors.New("cbor: map key is not a text string")
+
+	// ErrDuplicateElement is returned when a set contains the same element twice.
+	ErrDuplicateElement = errors.New("cbor: duplicate element in set")
+
+	// ErrInvalidEnum is returned when a text string is not a known name of an enum type.
+	ErrInvalidEnum = errors.New("cbor: unknown enum name")
+
+	// ErrLengthMismatch is returned when an array does not have the length of a fixed-size destination.
+	ErrLengthMismatch = errors.New("cbor: array length does not match destination")
+
+	// ErrInvalidJSON is returned when FromJSON input is not a valid JSON document.
+	ErrInvalidJSON = errors.New("cbor: invalid JSON")
+
+	// ErrCyclicValue is returned when a Go value being encoded refers back to itself.
+	ErrCyclicValue = errors.New("cbor: value contains a cycle")
 )
 
 // CborError provides detailed error information.
@@ -95,9 +135,10 @@ func NewCborError(err error, offset int, message string) *CborError {
 type TypeMismatchError struct {
 	Expected CborReaderState
 	Actual   CborReaderState
+	Offset   int // offset of the mismatched item
 }
 
 // Error implements the error interface.
 func (e *TypeMismatchError) Error() string {
-	return fmt.Sprintf("cbor: expected %s but got %s", e.Expected, e.Actual)
+	return fmt.Sprintf("cbor: expected %s but got %s at offset %d", e.Expected, e.Actual, e.Offset)
 }
@@ -0,0 +1,55 @@
+package cbor
+
+// WriteExpectedBase64URL writes data as a byte string inside tag 21, suggesting base64url
+// when it is converted to text.
+func (w *CborWriter) WriteExpectedBase64URL(data []byte) error {
+	return w.writeExpectedEncoding(TagExpectedBase64URL, data)
+}
+
+// WriteExpectedBase64 writes data as a byte string inside tag 22, suggesting base64 when it
+// is converted to text.
+func (w *CborWriter) WriteExpectedBase64(data []byte) error {
+	return w.writeExpectedEncoding(TagExpectedBase64, data)
+}
+
+// WriteExpectedBase16 writes data as a byte string inside tag 23, suggesting base16 when it
+// is converted to text.
+func (w *CborWriter) WriteExpectedBase16(data []byte) error {
+	return w.writeExpectedEncoding(TagExpectedBase16, data)
+}
+
+// writeExpectedEncoding writes an expected-conversion tag followed by data.
+func (w *CborWriter) writeExpectedEncoding(tag CborTag, data []byte) error {
+	if err := w.WriteTag(tag); err != nil {
+		return err
+	}
+	return w.WriteByteString(data)
+}
+
+// ReadExpectedEncoding reads a byte string inside an expected-conversion tag (21, 22 or 23)
+// and returns the suggested encoding together with the bytes.
+func (r *CborReader) ReadExpectedEncoding() (JSONByteEncoding, []byte, error) {
+	tagOffset := r.offset
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var enc JSONByteEncoding
+	switch tag {
+	case TagExpectedBase64URL:
+		enc = JSONBase64URL
+	case TagExpectedBase64:
+		enc = JSONBase64
+	case TagExpectedBase16:
+		enc = JSONBase16
+	default:
+		return 0, nil, NewCborError(ErrInvalidCbor, tagOffset, "expected an expected-conversion tag")
+	}
+
+	data, err := r.ReadByteString()
+	if err != nil {
+		return 0, nil, err
+	}
+	return enc, append([]byte(nil), data...), nil
+}
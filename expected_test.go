@@ -0,0 +1,76 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestExpectedEncoding(t *testing.T) {
+	data := []byte{0x01, 0x02, 0xff}
+	tests := []struct {
+		name  string
+		write func(*CborWriter, []byte) error
+		enc   JSONByteEncoding
+		hex   string
+		json  string
+	}{
+		{"base64url", (*CborWriter).WriteExpectedBase64URL, JSONBase64URL, "d5430102ff", `"AQL_"`},
+		{"base64", (*CborWriter).WriteExpectedBase64, JSONBase64, "d6430102ff", `"AQL/"`},
+		{"base16", (*CborWriter).WriteExpectedBase16, JSONBase16, "d7430102ff", `"0102ff"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := tt.write(w, data); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.hex {
+				t.Errorf("expected %s, got %s", tt.hex, got)
+			}
+
+			enc, got, err := NewCborReader(w.Bytes()).ReadExpectedEncoding()
+			if err != nil {
+				t.Fatalf("ReadExpectedEncoding failed: %v", err)
+			}
+			if enc != tt.enc || !bytes.Equal(got, data) {
+				t.Errorf("expected %v %x, got %v %x", tt.enc, data, enc, got)
+			}
+
+			js, err := ToJSON(w.Bytes(), WithJSONByteEncoding(JSONBase16))
+			if err != nil {
+				t.Fatalf("ToJSON failed: %v", err)
+			}
+			if string(js) != tt.json {
+				t.Errorf("expected JSON %s, got %s", tt.json, js)
+			}
+		})
+	}
+}
+
+func TestReadExpectedEncodingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		err  error
+	}{
+		{"wrong_tag", "c2430102ff", ErrInvalidCbor},
+		{"text_content", "d56161", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			_, _, err := NewCborReader(data).ReadExpectedEncoding()
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+			var mismatch *TypeMismatchError
+			if tt.err == nil && !errors.As(err, &mismatch) {
+				t.Errorf("expected TypeMismatchError, got %v", err)
+			}
+		})
+	}
+}
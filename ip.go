@@ -0,0 +1,111 @@
+package cbor
+
+import (
+	"net"
+	"net/netip"
+)
+
+// WriteIPAddress writes an IPv4 or IPv6 address (tag 260). IPv4 addresses, including
+// IPv4-mapped IPv6 addresses, are written as 4 bytes and all others as 16 bytes.
+func (w *CborWriter) WriteIPAddress(ip net.IP) error {
+	addr := ip.To4()
+	if addr == nil {
+		addr = ip.To16()
+	}
+	if addr == nil {
+		return NewCborError(ErrInvalidIPAddress, len(w.buffer), "address must be 4 or 16 bytes")
+	}
+
+	if err := w.WriteTag(TagNetworkAddress); err != nil {
+		return err
+	}
+	return w.WriteByteString(addr)
+}
+
+// WriteIPPrefix writes an IP prefix (tag 261) as a single-entry map from the address
+// bytes to the prefix length.
+func (w *CborWriter) WriteIPPrefix(prefix netip.Prefix) error {
+	if !prefix.IsValid() {
+		return NewCborError(ErrInvalidIPAddress, len(w.buffer), "invalid prefix")
+	}
+
+	if err := w.WriteTag(TagNetworkAddressPrefix); err != nil {
+		return err
+	}
+	if err := w.WriteStartMap(1); err != nil {
+		return err
+	}
+	addr := prefix.Addr().AsSlice()
+	if err := w.WriteByteString(addr); err != nil {
+		return err
+	}
+	if err := w.WriteInt(prefix.Bits()); err != nil {
+		return err
+	}
+	return w.WriteEndMap()
+}
+
+// ReadIPAddress reads an IP address (tag 260). A 4-byte payload is returned as an IPv4
+// address and a 16-byte payload as an IPv6 address.
+func (r *CborReader) ReadIPAddress() (net.IP, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return nil, err
+	}
+	if tag != TagNetworkAddress {
+		return nil, NewCborError(ErrInvalidCbor, r.offset, "expected network address tag")
+	}
+
+	start := r.offset
+	data, err := r.ReadByteString()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != net.IPv4len && len(data) != net.IPv6len {
+		return nil, NewCborError(ErrInvalidIPAddress, start, "address must be 4 or 16 bytes")
+	}
+	return net.IP(append([]byte(nil), data...)), nil
+}
+
+// ReadIPPrefix reads an IP prefix (tag 261) written as a single-entry map from the address
+// bytes to the prefix length.
+func (r *CborReader) ReadIPPrefix() (netip.Prefix, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if tag != TagNetworkAddressPrefix {
+		return netip.Prefix{}, NewCborError(ErrInvalidCbor, r.offset, "expected network address prefix tag")
+	}
+
+	start := r.offset
+	length, err := r.ReadStartMap()
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if length != 1 {
+		return netip.Prefix{}, NewCborError(ErrInvalidCbor, start, "prefix must be a single-entry map")
+	}
+
+	data, err := r.ReadByteString()
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	bits, err := r.ReadInt()
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if err := r.ReadEndMap(); err != nil {
+		return netip.Prefix{}, err
+	}
+
+	addr, ok := netip.AddrFromSlice(data)
+	if !ok {
+		return netip.Prefix{}, NewCborError(ErrInvalidIPAddress, start, "address must be 4 or 16 bytes")
+	}
+	prefix := netip.PrefixFrom(addr, bits)
+	if !prefix.IsValid() {
+		return netip.Prefix{}, NewCborError(ErrInvalidIPAddress, start, "prefix length out of range")
+	}
+	return prefix, nil
+}
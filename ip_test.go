@@ -0,0 +1,100 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestIPAddress(t *testing.T) {
+	tests := []struct {
+		ip  string
+		hex string
+	}{
+		{"192.0.2.1", "d9010444c0000201"},
+		{"::ffff:192.0.2.1", "d9010444c0000201"},
+		{"2001:db8::1", "d901045020010db8000000000000000000000001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := w.WriteIPAddress(net.ParseIP(tt.ip)); err != nil {
+				t.Fatalf("WriteIPAddress failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.hex {
+				t.Errorf("expected %s, got %s", tt.hex, got)
+			}
+
+			ip, err := NewCborReader(w.Bytes()).ReadIPAddress()
+			if err != nil {
+				t.Fatalf("ReadIPAddress failed: %v", err)
+			}
+			if !ip.Equal(net.ParseIP(tt.ip)) {
+				t.Errorf("expected %s, got %s", tt.ip, ip)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteIPAddress(net.IP{1, 2, 3}); !errors.Is(err, ErrInvalidIPAddress) {
+			t.Errorf("expected ErrInvalidIPAddress, got %v", err)
+		}
+
+		data, _ := hex.DecodeString("d9010446010203040506") // 6-byte MAC address
+		if _, err := NewCborReader(data).ReadIPAddress(); !errors.Is(err, ErrInvalidIPAddress) {
+			t.Errorf("expected ErrInvalidIPAddress, got %v", err)
+		}
+	})
+}
+
+func TestIPPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		hex    string
+	}{
+		{"192.0.2.0/24", "d90105a144c00002001818"},
+		{"2001:db8::/32", "d90105a15020010db80000000000000000000000001820"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tt.prefix)
+			w := NewCborWriter()
+			if err := w.WriteIPPrefix(prefix); err != nil {
+				t.Fatalf("WriteIPPrefix failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.hex {
+				t.Errorf("expected %s, got %s", tt.hex, got)
+			}
+
+			got, err := NewCborReader(w.Bytes()).ReadIPPrefix()
+			if err != nil {
+				t.Fatalf("ReadIPPrefix failed: %v", err)
+			}
+			if got != prefix {
+				t.Errorf("expected %s, got %s", prefix, got)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		w := NewCborWriter()
+		if err := w.WriteIPPrefix(netip.Prefix{}); !errors.Is(err, ErrInvalidIPAddress) {
+			t.Errorf("expected ErrInvalidIPAddress, got %v", err)
+		}
+
+		data, _ := hex.DecodeString("d90105a144c00002001821") // /33 on IPv4
+		if _, err := NewCborReader(data).ReadIPPrefix(); !errors.Is(err, ErrInvalidIPAddress) {
+			t.Errorf("expected ErrInvalidIPAddress, got %v", err)
+		}
+
+		data, _ = hex.DecodeString("d9010444c0000201")
+		if _, err := NewCborReader(data).ReadIPPrefix(); !errors.Is(err, ErrInvalidCbor) {
+			t.Errorf("expected ErrInvalidCbor, got %v", err)
+		}
+	})
+}
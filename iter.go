@@ -0,0 +1,74 @@
+//go:build go1.23
+
+package cbor
+
+import "iter"
+
+// ArrayElements reads the start of an array and yields the index of each element. The
+// loop body must read exactly one item per iteration; the end of the array is consumed
+// after the last element. Definite and indefinite-length arrays are both supported.
+//
+//	for i, err := range r.ArrayElements() {
+//		if err != nil {
+//			return err
+//		}
+//		v, err := r.ReadInt64()
+//		...
+//	}
+//
+// Errors are yielded once, ending the sequence. Breaking out of the loop leaves the reader
+// inside the array.
+func (r *CborReader) ArrayElements() iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		length, err := r.ReadStartArray()
+		if err != nil {
+			yield(0, err)
+			return
+		}
+		i, ok := r.containerItems(length, StateEndArray, yield)
+		if !ok {
+			return
+		}
+		if err := r.ReadEndArray(); err != nil {
+			yield(i, err)
+		}
+	}
+}
+
+// MapEntries reads the start of a map and yields the index of each entry. The loop body
+// must read the key and then the value on each iteration; the end of the map is consumed
+// after the last entry. It otherwise behaves like ArrayElements.
+func (r *CborReader) MapEntries() iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		length, err := r.ReadStartMap()
+		if err != nil {
+			yield(0, err)
+			return
+		}
+		i, ok := r.containerItems(length, StateEndMap, yield)
+		if !ok {
+			return
+		}
+		if err := r.ReadEndMap(); err != nil {
+			yield(i, err)
+		}
+	}
+}
+
+// containerItems yields each index of a container with the given length, or until end for
+// indefinite-length containers. It returns the item count and whether to read the end.
+func (r *CborReader) containerItems(length int, end CborReaderState, yield func(int, error) bool) (int, bool) {
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, end)
+		if err != nil {
+			yield(i, err)
+			return i, false
+		}
+		if !more {
+			return i, true
+		}
+		if !yield(i, nil) {
+			return i, false
+		}
+	}
+}
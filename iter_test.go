@@ -0,0 +1,96 @@
+//go:build go1.23
+
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestArrayElements(t *testing.T) {
+	for _, input := range []string{"83010203", "9f010203ff"} {
+		data, _ := hex.DecodeString(input)
+		r := NewCborReader(data)
+
+		var sum int64
+		count := 0
+		for i, err := range r.ArrayElements() {
+			if err != nil {
+				t.Fatalf("%s: ArrayElements failed: %v", input, err)
+			}
+			if i != count {
+				t.Errorf("%s: expected index %d, got %d", input, count, i)
+			}
+			v, err := r.ReadInt64()
+			if err != nil {
+				t.Fatalf("%s: ReadInt64 failed: %v", input, err)
+			}
+			sum += v
+			count++
+		}
+		if count != 3 || sum != 6 {
+			t.Errorf("%s: expected 3 elements summing to 6, got %d summing to %d", input, count, sum)
+		}
+		if r.BytesRemaining() != 0 {
+			t.Errorf("%s: expected array to be fully consumed", input)
+		}
+	}
+}
+
+func TestMapEntries(t *testing.T) {
+	for _, input := range []string{"a2616101616202", "bf616101616202ff"} {
+		data, _ := hex.DecodeString(input)
+		r := NewCborReader(data)
+
+		got := map[string]int64{}
+		for _, err := range r.MapEntries() {
+			if err != nil {
+				t.Fatalf("%s: MapEntries failed: %v", input, err)
+			}
+			key, err := r.ReadTextString()
+			if err != nil {
+				t.Fatalf("%s: ReadTextString failed: %v", input, err)
+			}
+			value, err := r.ReadInt64()
+			if err != nil {
+				t.Fatalf("%s: ReadInt64 failed: %v", input, err)
+			}
+			got[key] = value
+		}
+		if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+			t.Errorf("%s: unexpected entries %v", input, got)
+		}
+		if r.BytesRemaining() != 0 {
+			t.Errorf("%s: expected map to be fully consumed", input)
+		}
+	}
+}
+
+func TestArrayElementsErrors(t *testing.T) {
+	r := NewCborReader([]byte{0x01})
+	var last error
+	for _, err := range r.ArrayElements() {
+		last = err
+	}
+	var mismatch *TypeMismatchError
+	if !errors.As(last, &mismatch) {
+		t.Errorf("expected TypeMismatchError, got %v", last)
+	}
+
+	// Truncated indefinite array
+	r = NewCborReader([]byte{0x9f, 0x01})
+	last = nil
+	for _, err := range r.ArrayElements() {
+		if err != nil {
+			last = err
+			break
+		}
+		if _, err := r.ReadInt64(); err != nil {
+			t.Fatalf("ReadInt64 failed: %v", err)
+		}
+	}
+	if !errors.Is(last, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData, got %v", last)
+	}
+}
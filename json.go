@@ -0,0 +1,466 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONByteEncoding selects how ToJSON renders byte strings.
+type JSONByteEncoding int
+
+const (
+	// JSONBase64URL renders byte strings as base64url without padding (the default).
+	JSONBase64URL JSONByteEncoding = iota
+	// JSONBase64 renders byte strings as base64 with padding.
+	JSONBase64
+	// JSONBase16 renders byte strings as lowercase hex.
+	JSONBase16
+)
+
+// maxSafeJSONInteger is the largest magnitude a JSON number can carry without precision
+// loss in JavaScript (2^53).
+const maxSafeJSONInteger = 1 << 53
+
+// jsonOptions holds the configuration of a ToJSON call.
+type jsonOptions struct {
+	byteEncoding       JSONByteEncoding
+	largeIntsAsStrings bool
+	readerOpts         []ReaderOption
+}
+
+// JSONOption configures ToJSON.
+type JSONOption func(*jsonOptions)
+
+// WithJSONByteEncoding sets how ToJSON renders byte strings that are not inside an
+// expected-conversion tag (21, 22 or 23).
+func WithJSONByteEncoding(enc JSONByteEncoding) JSONOption {
+	return func(o *jsonOptions) {
+		o.byteEncoding = enc
+	}
+}
+
+// WithJSONLargeIntegersAsStrings makes ToJSON render integers and bignums whose
+// magnitude exceeds 2^53 as decimal strings instead of numbers.
+func WithJSONLargeIntegersAsStrings(enable bool) JSONOption {
+	return func(o *jsonOptions) {
+		o.largeIntsAsStrings = enable
+	}
+}
+
+// WithJSONReaderOptions configures the reader ToJSON decodes data with, for example to
+// change its nesting depth limit.
+func WithJSONReaderOptions(opts ...ReaderOption) JSONOption {
+	return func(o *jsonOptions) {
+		o.readerOpts = append(o.readerOpts, opts...)
+	}
+}
+
+// ToJSON converts the single CBOR item in data to JSON following RFC 8949 Section 6.1.
+//
+// Byte strings become base64url strings unless another encoding is selected, and tags 21,
+// 22 and 23 switch the encoding of the byte strings they enclose. Bignums become numbers,
+// other tags are dropped in favor of their content, and undefined, other simple values,
+// NaN and infinities become null. Map keys that are not text strings are converted to JSON
+// and used as strings.
+func ToJSON(data []byte, opts ...JSONOption) ([]byte, error) {
+	var o jsonOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := NewCborReader(data, o.readerOpts...)
+	c := &jsonConverter{r: r, largeIntsAsStrings: o.largeIntsAsStrings}
+	if err := c.item(o.byteEncoding); err != nil {
+		return nil, err
+	}
+	if r.BytesRemaining() > 0 {
+		return nil, NewCborError(ErrNotAtEnd, r.offset, "")
+	}
+	return []byte(c.sb.String()), nil
+}
+
+// jsonConverter accumulates JSON text while walking a reader.
+type jsonConverter struct {
+	r                  *CborReader
+	sb                 strings.Builder
+	tags               int // enclosing tags, which count toward the reader's nesting limit
+	largeIntsAsStrings bool
+}
+
+// item converts the next data item, rendering byte strings with enc.
+func (c *jsonConverter) item(enc JSONByteEncoding) error {
+	r := c.r
+	state, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case StateUnsignedInteger, StateNegativeInteger:
+		value, err := r.ReadAny()
+		if err != nil {
+			return err
+		}
+		c.integer(value)
+
+	case StateByteString, StateStartIndefiniteLengthByteString:
+		value, err := r.ReadByteString()
+		if err != nil {
+			return err
+		}
+		c.byteString(value, enc)
+
+	case StateTextString, StateStartIndefiniteLengthTextString:
+		value, err := r.ReadTextString()
+		if err != nil {
+			return err
+		}
+		writeQuoted(&c.sb, value)
+
+	case StateStartArray:
+		length, err := r.ReadStartArray()
+		if err != nil {
+			return err
+		}
+		c.sb.WriteByte('[')
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, StateEndArray)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			if i > 0 {
+				c.sb.WriteByte(',')
+			}
+			if err := c.item(enc); err != nil {
+				return err
+			}
+		}
+		c.sb.WriteByte(']')
+		return r.ReadEndArray()
+
+	case StateStartMap:
+		length, err := r.ReadStartMap()
+		if err != nil {
+			return err
+		}
+		c.sb.WriteByte('{')
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, StateEndMap)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			if i > 0 {
+				c.sb.WriteByte(',')
+			}
+			if err := c.key(enc); err != nil {
+				return err
+			}
+			c.sb.WriteByte(':')
+			if err := c.item(enc); err != nil {
+				return err
+			}
+		}
+		c.sb.WriteByte('}')
+		return r.ReadEndMap()
+
+	case StateTag:
+		tag, err := r.PeekTag()
+		if err != nil {
+			return err
+		}
+		if tag == TagUnsignedBignum || tag == TagNegativeBignum {
+			value, err := r.ReadBigInt()
+			if err != nil {
+				return err
+			}
+			c.integer(value)
+			return nil
+		}
+		if len(r.nestingStack)+c.tags >= r.maxNestingDepth {
+			return NewCborError(ErrNestingDepthExceeded, r.offset, "ToJSON")
+		}
+		if _, err := r.ReadTag(); err != nil {
+			return err
+		}
+		switch tag {
+		case TagExpectedBase64URL:
+			enc = JSONBase64URL
+		case TagExpectedBase64:
+			enc = JSONBase64
+		case TagExpectedBase16:
+			enc = JSONBase16
+		}
+		c.tags++
+		err = c.item(enc)
+		c.tags--
+		return err
+
+	case StateBoolean:
+		value, err := r.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		c.sb.WriteString(strconv.FormatBool(value))
+
+	case StateNull, StateUndefinedValue, StateSimpleValue:
+		if _, err := r.ReadSimpleValue(); err != nil {
+			return err
+		}
+		c.sb.WriteString("null")
+
+	case StateHalfPrecisionFloat, StateSinglePrecisionFloat, StateDoublePrecisionFloat:
+		value, err := r.ReadFloat()
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			c.sb.WriteString("null")
+			return nil
+		}
+		bitSize := 64
+		if state != StateDoublePrecisionFloat {
+			bitSize = 32
+		}
+		c.sb.WriteString(strconv.FormatFloat(value, 'g', -1, bitSize))
+
+	default:
+		return NewCborError(ErrInvalidState, r.offset, "ToJSON")
+	}
+	return nil
+}
+
+// key converts a map key, turning keys that are not text strings into strings.
+func (c *jsonConverter) key(enc JSONByteEncoding) error {
+	state, err := c.r.PeekState()
+	if err != nil {
+		return err
+	}
+	if state == StateTextString || state == StateStartIndefiniteLengthTextString {
+		return c.item(enc)
+	}
+
+	sub := &jsonConverter{r: c.r, tags: c.tags, largeIntsAsStrings: c.largeIntsAsStrings}
+	if err := sub.item(enc); err != nil {
+		return err
+	}
+	s := sub.sb.String()
+	if strings.HasPrefix(s, `"`) {
+		c.sb.WriteString(s)
+	} else {
+		writeQuoted(&c.sb, s)
+	}
+	return nil
+}
+
+// integer writes a uint64, int64 or *big.Int as a number, or as a string when large
+// integers are configured as strings and its magnitude exceeds 2^53.
+func (c *jsonConverter) integer(value any) {
+	var s string
+	large := false
+	switch v := value.(type) {
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+		large = v > maxSafeJSONInteger
+	case int64:
+		s = strconv.FormatInt(v, 10)
+		large = v > maxSafeJSONInteger || v < -maxSafeJSONInteger
+	case *big.Int:
+		s = v.String()
+		large = v.CmpAbs(big.NewInt(maxSafeJSONInteger)) > 0
+	}
+
+	if large && c.largeIntsAsStrings {
+		writeQuoted(&c.sb, s)
+		return
+	}
+	c.sb.WriteString(s)
+}
+
+// byteString writes b as a JSON string using enc.
+func (c *jsonConverter) byteString(b []byte, enc JSONByteEncoding) {
+	var s string
+	switch enc {
+	case JSONBase64:
+		s = base64.StdEncoding.EncodeToString(b)
+	case JSONBase16:
+		s = hex.EncodeToString(b)
+	default:
+		s = base64.RawURLEncoding.EncodeToString(b)
+	}
+	writeQuoted(&c.sb, s)
+}
+
+// jsonObject is a parsed JSON object that keeps its members in source order.
+type jsonObject struct {
+	keys   []string
+	values []any
+}
+
+// FromJSON converts a JSON document to a single CBOR item. Integer literals use the
+// smallest integer encoding, or a bignum when they exceed 64 bits, and other numbers become
+// float64 (the shortest lossless float in canonical modes). Object members become text keyed
+// map entries in source order unless the writer sorts map keys. Malformed JSON returns
+// ErrInvalidJSON, truncated JSON ErrUnexpectedEndOfData and numbers beyond the float64
+// range ErrOverflow, at the offset in jsonData where the problem was found.
+func FromJSON(jsonData []byte, opts ...WriterOption) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+
+	value, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			return nil, NewCborError(ErrNotAtEnd, int(dec.InputOffset()), "trailing JSON data")
+		}
+		return nil, jsonError(dec, err)
+	}
+
+	w := NewCborWriter(opts...)
+	if err := w.writeJSONValue(value); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// jsonError wraps an error from dec in a CborError at the decoder's input offset.
+// encoding/json reports input that ends inside a value as a syntax error, which is told
+// apart by its message.
+func jsonError(dec *json.Decoder, err error) error {
+	offset := int(dec.InputOffset())
+	var syntaxErr *json.SyntaxError
+	if err == io.EOF || err == io.ErrUnexpectedEOF ||
+		(errors.As(err, &syntaxErr) && syntaxErr.Error() == "unexpected end of JSON input") {
+		return NewCborError(ErrUnexpectedEndOfData, offset, "JSON")
+	}
+	return NewCborError(ErrInvalidJSON, offset, err.Error())
+}
+
+// parseJSONValue reads one JSON value, representing objects as *jsonObject, arrays as
+// []any, integer literals as json.Number and other numbers as float64.
+func parseJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, jsonError(dec, err)
+	}
+
+	switch tok {
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			item, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, jsonError(dec, err)
+		}
+		return items, nil
+
+	case json.Delim('{'):
+		obj := &jsonObject{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, jsonError(dec, err)
+			}
+			value, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, keyTok.(string))
+			obj.values = append(obj.values, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, jsonError(dec, err)
+		}
+		return obj, nil
+	}
+
+	if n, ok := tok.(json.Number); ok && strings.ContainsAny(string(n), ".eE") {
+		f, err := strconv.ParseFloat(string(n), 64)
+		if err != nil {
+			return nil, NewCborError(ErrOverflow, int(dec.InputOffset()), "JSON number "+string(n))
+		}
+		return f, nil
+	}
+	return tok, nil
+}
+
+// writeJSONValue writes a value produced by parseJSONValue.
+func (w *CborWriter) writeJSONValue(value any) error {
+	switch v := value.(type) {
+	case nil:
+		return w.WriteNull()
+	case bool:
+		return w.WriteBoolean(v)
+	case string:
+		return w.WriteTextString(v)
+	case json.Number:
+		return w.writeJSONInteger(v)
+	case float64:
+		if w.conformanceMode == ConformanceCanonical || w.conformanceMode == ConformanceCtap2Canonical {
+			return w.WriteFloat(v)
+		}
+		return w.WriteFloat64(v)
+	case []any:
+		if err := w.WriteStartArray(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := w.writeJSONValue(item); err != nil {
+				return err
+			}
+		}
+		return w.WriteEndArray()
+	case *jsonObject:
+		if err := w.WriteStartMap(len(v.keys)); err != nil {
+			return err
+		}
+		for i, key := range v.keys {
+			if err := w.writeTextKey(key); err != nil {
+				return err
+			}
+			if err := w.writeJSONValue(v.values[i]); err != nil {
+				return err
+			}
+		}
+		return w.WriteEndMap()
+	default:
+		return NewCborError(ErrUnsupportedType, len(w.buffer), reflect.TypeOf(value).String())
+	}
+}
+
+// writeJSONInteger writes an integer literal as an integer or a bignum.
+func (w *CborWriter) writeJSONInteger(n json.Number) error {
+	s := n.String()
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return w.WriteInt64(v)
+	}
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return w.WriteUint64(v)
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return NewCborError(ErrInvalidJSON, len(w.buffer), "JSON number "+s)
+	}
+	return w.WriteBigInt(v)
+}
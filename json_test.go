@@ -0,0 +1,175 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		json string
+	}{
+		{"uint", "1903e8", "1000"},
+		{"max_uint64", "1bffffffffffffffff", "18446744073709551615"},
+		{"min_negative", "3bffffffffffffffff", "-18446744073709551616"},
+		{"negative", "3903e7", "-1000"},
+		{"bignum", "c249010000000000000000", "18446744073709551616"},
+		{"float", "fb3ff199999999999a", "1.1"},
+		{"half", "f93e00", "1.5"},
+		{"single", "fa3f8ccccd", "1.1"},
+		{"nan", "f97e00", "null"},
+		{"infinity", "f97c00", "null"},
+		{"simple", "83f4f5f6", "[false,true,null]"},
+		{"undefined", "81f7", "[null]"},
+		{"bytes", "43fbff01", `"-_8B"`},
+		{"empty_bytes", "40", `""`},
+		{"text", "665c22e6b0b40a", `"\\\"水\n"`},
+		{"indefinite_text", "7f657374726561646d696e67ff", `"streaming"`},
+		{"array", "9f018202039f0405ffff", "[1,[2,3],[4,5]]"},
+		{"map", "a26161016162820203", `{"a":1,"b":[2,3]}`},
+		{"int_keys", "a201020304", `{"1":2,"3":4}`},
+		{"bytes_key", "a1420102f5", `{"AQI":true}`},
+		{"array_key", "a1820102f5", `{"[1,2]":true}`},
+		{"tag_dropped", "c11a514b67b0", "1363896240"},
+		{"expected_base64", "d64443010203", `"QwECAw=="`},
+		{"expected_base16", "d7824201024103", `["0102","03"]`},
+		{"expected_nested", "d7d5a1616143010203", `{"a":"AQID"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			got, err := ToJSON(data)
+			if err != nil {
+				t.Fatalf("ToJSON failed: %v", err)
+			}
+			if string(got) != tt.json {
+				t.Errorf("expected %s, got %s", tt.json, got)
+			}
+		})
+	}
+}
+
+func TestToJSONOptions(t *testing.T) {
+	data, _ := hex.DecodeString("84" + "1b0020000000000000" + "1b0020000000000001" + "3b0020000000000000" + "c249010000000000000000")
+	got, err := ToJSON(data, WithJSONLargeIntegersAsStrings(true))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	want := `[9007199254740992,"9007199254740993","-9007199254740993","18446744073709551616"]`
+	if string(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	data, _ = hex.DecodeString("82" + "43fbff01" + "d54101")
+	got, err = ToJSON(data, WithJSONByteEncoding(JSONBase16))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if want := `["fbff01","AQ"]`; string(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	if _, err := ToJSON([]byte{0x01, 0x02}); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+	if _, err := ToJSON([]byte{0x82, 0x01}); !errors.Is(err, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		hex  string
+	}{
+		{"small_int", "10", "0a"},
+		{"int", "1000", "1903e8"},
+		{"negative", "-1000", "3903e7"},
+		{"max_uint64", "18446744073709551615", "1bffffffffffffffff"},
+		{"bignum", "18446744073709551616", "c249010000000000000000"},
+		{"float", "1.5", "fb3ff8000000000000"},
+		{"exponent", "1e3", "fb408f400000000000"},
+		{"literals", "[true,false,null]", "83f5f4f6"},
+		{"string", `"aü"`, "6361c3bc"},
+		{"object_order", `{"b":1,"a":[]}`, "a2616201616180"},
+		{"empty", `{"a":{}}`, "a16161a0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSON([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("FromJSON failed: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.hex {
+				t.Errorf("expected %s, got %x", tt.hex, got)
+			}
+		})
+	}
+}
+
+func TestFromJSONCanonical(t *testing.T) {
+	got, err := FromJSON([]byte(`{"bb":1.5,"a":2}`), WithConformanceMode(ConformanceCanonical))
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if want := "a2616102626262f93e00"; hex.EncodeToString(got) != want {
+		t.Errorf("expected %s, got %x", want, got)
+	}
+
+	if _, err := FromJSON([]byte(`{"a":1,"a":2}`), WithConformanceMode(ConformanceCanonical)); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	in := `{"id":9007199254740993,"name":"x","vals":[1.25,-3,null],"nested":{"ok":true}}`
+	data, err := FromJSON([]byte(in))
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	out, err := ToJSON(data)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if string(out) != in {
+		t.Errorf("expected %s, got %s", in, out)
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		in     string
+		err    error
+		offset int
+	}{
+		{"", ErrUnexpectedEndOfData, 0},
+		{"[1,", ErrUnexpectedEndOfData, 2},
+		{`{"a":`, ErrUnexpectedEndOfData, 5},
+		{`{"a"}`, ErrInvalidJSON, 4},
+		{"[1}", ErrInvalidJSON, 2},
+		{"nope", ErrInvalidJSON, 0},
+		{"1 2", ErrNotAtEnd, 3},
+		{"1e400", ErrOverflow, 5},
+		{"[1, -1e400]", ErrOverflow, 10},
+	}
+
+	for _, tt := range tests {
+		_, err := FromJSON([]byte(tt.in))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: expected %v, got %v", tt.in, tt.err, err)
+			continue
+		}
+		var cerr *CborError
+		if !errors.As(err, &cerr) || cerr.Offset != tt.offset {
+			t.Errorf("%q: expected offset %d, got %v", tt.in, tt.offset, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cbor
+
+import (
+	"reflect"
+	"sort"
+)
+
+// keyTemplate caches the encodings of a known set of map keys and their sorted order.
+type keyTemplate struct {
+	encoded map[any][]byte // Go key to its encoded bytes
+	rank    map[string]int // encoded key to its position in sorted order
+}
+
+// WithWriterKeyTemplate declares the map keys a writer is expected to encode repeatedly,
+// such as the field names of a homogeneous record stream. Each key is encoded once and the
+// cached bytes are reused whenever the reflection codec or WriteAny writes it as a map key.
+// Sorting writers also order maps made only of template keys by their precomputed rank
+// instead of comparing encodings. Keys that are not comparable or cannot be encoded are
+// ignored.
+func WithWriterKeyTemplate(keys []any) WriterOption {
+	return func(w *CborWriter) {
+		w.templateKeys = keys
+	}
+}
+
+// buildKeyTemplate encodes and ranks the template keys using the writer's conformance and
+// float modes.
+func (w *CborWriter) buildKeyTemplate(keys []any) *keyTemplate {
+	t := &keyTemplate{
+		encoded: make(map[any][]byte, len(keys)),
+		rank:    make(map[string]int, len(keys)),
+	}
+
+	sorted := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		if k == nil || !reflect.TypeOf(k).Comparable() {
+			continue
+		}
+		if _, ok := t.encoded[k]; ok {
+			continue
+		}
+		kw := NewCborWriter(WithConformanceMode(w.conformanceMode), WithWriterFloatMode(w.floatMode))
+		if err := kw.WriteAny(k); err != nil {
+			continue
+		}
+		t.encoded[k] = kw.Bytes()
+		sorted = append(sorted, kw.Bytes())
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return w.compareKeys(sorted[i], sorted[j]) < 0
+	})
+	for i, enc := range sorted {
+		t.rank[string(enc)] = i
+	}
+	return t
+}
+
+// writeMapKey writes k as a map key, reusing its cached encoding when k is a template key.
+func (w *CborWriter) writeMapKey(k any) error {
+	if w.keyTemplate != nil && k != nil && reflect.TypeOf(k).Comparable() {
+		if enc, ok := w.keyTemplate.encoded[k]; ok {
+			w.buffer = append(w.buffer, enc...)
+			w.currentOffset = len(w.buffer)
+			w.advanceContainer()
+			return nil
+		}
+	}
+	return w.WriteAny(k)
+}
+
+// writeTextKey writes a text string map key, reusing its cached encoding when available.
+func (w *CborWriter) writeTextKey(k string) error {
+	if w.keyTemplate == nil {
+		return w.WriteTextString(k)
+	}
+	return w.writeMapKey(k)
+}
+
+// sortMapEntriesByTemplate orders a map whose keys are all template keys by their rank.
+// It reports false, leaving the buffer untouched, if any key is not in the template.
+func (w *CborWriter) sortMapEntriesByTemplate(entries []mapEntry) (bool, error) {
+	ranks := make([]int, len(entries))
+	inOrder := true
+	for i, e := range entries {
+		rank, ok := w.keyTemplate.rank[string(w.buffer[e.start:e.keyEnd])]
+		if !ok {
+			return false, nil
+		}
+		ranks[i] = rank
+		if i > 0 && rank <= ranks[i-1] {
+			inOrder = false
+		}
+	}
+
+	// Records written in template order need no rearranging
+	if inOrder {
+		return true, nil
+	}
+
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ranks[order[i]] < ranks[order[j]]
+	})
+
+	if w.conformanceMode == ConformanceCanonical || w.conformanceMode == ConformanceCtap2Canonical {
+		for i := 1; i < len(order); i++ {
+			if ranks[order[i-1]] == ranks[order[i]] {
+				return true, ErrDuplicateKey
+			}
+		}
+	}
+
+	sorted := make([]mapEntry, len(entries))
+	for i, idx := range order {
+		sorted[i] = entries[idx]
+	}
+	w.reorderMapEntries(entries, sorted)
+	return true, nil
+}
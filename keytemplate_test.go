@@ -0,0 +1,118 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type templateRecord struct {
+	Zeta  int    `cbor:"zeta"`
+	Alpha string `cbor:"alpha"`
+	ID    int    `cbor:"id"`
+}
+
+var templateRecordKeys = []any{"zeta", "alpha", "id"}
+
+func TestKeyTemplateMatchesPlainEncoding(t *testing.T) {
+	modes := []CborConformanceMode{ConformanceLax, ConformanceCanonical, ConformanceCtap2Canonical}
+	values := []any{
+		templateRecord{Zeta: 1, Alpha: "a", ID: 7},
+		map[string]any{"id": 1, "zeta": 2, "alpha": 3},
+		map[any]any{"alpha": uint64(1), "other": "x"},
+		[]any{map[string]any{"zeta": true}, map[string]any{}},
+	}
+
+	for _, mode := range modes {
+		for _, v := range values {
+			plain := NewCborWriter(WithConformanceMode(mode))
+			cached := NewCborWriter(WithConformanceMode(mode), WithWriterKeyTemplate(templateRecordKeys))
+			if err := plain.WriteAny(v); err != nil {
+				t.Fatalf("WriteAny failed: %v", err)
+			}
+			if err := cached.WriteAny(v); err != nil {
+				t.Fatalf("WriteAny with template failed: %v", err)
+			}
+			// Lax maps keep Go iteration order, so only single-key or struct values compare
+			if mode == ConformanceLax {
+				if _, ok := v.(templateRecord); !ok {
+					continue
+				}
+			}
+			if !bytes.Equal(plain.Bytes(), cached.Bytes()) {
+				t.Errorf("mode %d, %v: expected %x, got %x", mode, v, plain.Bytes(), cached.Bytes())
+			}
+		}
+	}
+}
+
+func TestKeyTemplateFloatMode(t *testing.T) {
+	v := map[any]any{1.5: true}
+	for _, mode := range []FloatMode{FloatShortest, FloatAlwaysDouble} {
+		plain := NewCborWriter(WithWriterFloatMode(mode))
+		cached := NewCborWriter(WithWriterFloatMode(mode), WithWriterKeyTemplate([]any{1.5}))
+		if err := plain.WriteAny(v); err != nil {
+			t.Fatalf("WriteAny failed: %v", err)
+		}
+		if err := cached.WriteAny(v); err != nil {
+			t.Fatalf("WriteAny with template failed: %v", err)
+		}
+		if !bytes.Equal(plain.Bytes(), cached.Bytes()) {
+			t.Errorf("float mode %d: expected %x, got %x", mode, plain.Bytes(), cached.Bytes())
+		}
+	}
+}
+
+func TestKeyTemplateCtap2Order(t *testing.T) {
+	w := NewCborWriter(WithConformanceMode(ConformanceCtap2Canonical), WithWriterKeyTemplate(templateRecordKeys))
+	if err := w.WriteValue(templateRecord{Zeta: 1, Alpha: "a", ID: 2}); err != nil {
+		t.Fatalf("WriteValue failed: %v", err)
+	}
+
+	r := NewCborReader(w.Bytes())
+	if _, err := r.ReadStartMap(); err != nil {
+		t.Fatalf("ReadStartMap failed: %v", err)
+	}
+	for _, want := range []string{"id", "zeta", "alpha"} {
+		key, err := r.ReadTextString()
+		if err != nil {
+			t.Fatalf("ReadTextString failed: %v", err)
+		}
+		if key != want {
+			t.Errorf("expected key %s, got %s", want, key)
+		}
+		if err := r.SkipValue(); err != nil {
+			t.Fatalf("SkipValue failed: %v", err)
+		}
+	}
+}
+
+func TestKeyTemplateDuplicateKey(t *testing.T) {
+	w := NewCborWriter(WithConformanceMode(ConformanceCanonical), WithWriterKeyTemplate(templateRecordKeys))
+	if err := w.WriteStartMap(2); err != nil {
+		t.Fatalf("WriteStartMap failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.WriteTextString("id"); err != nil {
+			t.Fatalf("WriteTextString failed: %v", err)
+		}
+		if err := w.WriteInt(i); err != nil {
+			t.Fatalf("WriteInt failed: %v", err)
+		}
+	}
+	if err := w.WriteEndMap(); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+func BenchmarkMarshalRecordsKeyTemplate(b *testing.B) {
+	record := templateRecord{Zeta: 1, Alpha: "a", ID: 7}
+	w := NewCborWriter(WithConformanceMode(ConformanceCanonical), WithWriterKeyTemplate(templateRecordKeys))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Reset()
+		if err := w.WriteValue(record); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,1235 @@
+package cbor
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RawMessage is a single encoded CBOR item. The reflection codec writes it verbatim and
+// captures the exact bytes of one item into it, which defers decoding or passes unknown
+// sub-trees through untouched. An empty RawMessage is written as null.
+type RawMessage []byte
+
+// Marshaler is implemented by types that produce their own CBOR encoding. The returned
+// bytes must hold exactly one well-formed data item.
+type Marshaler interface {
+	MarshalCBOR() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode their own CBOR encoding. UnmarshalCBOR
+// receives a copy of the encoded bytes of exactly one data item, including null.
+type Unmarshaler interface {
+	UnmarshalCBOR(data []byte) error
+}
+
+// TaggedValue represents a tagged data item whose tag has no dedicated Go mapping.
+type TaggedValue struct {
+	Tag     CborTag
+	Content any
+}
+
+var (
+	bigIntType      = reflect.TypeOf(big.Int{})
+	bigRatType      = reflect.TypeOf(big.Rat{})
+	timeType        = reflect.TypeOf(time.Time{})
+	simpleValueType = reflect.TypeOf(SimpleValue(0))
+	taggedValueType = reflect.TypeOf(TaggedValue{})
+	rawMessageType  = reflect.TypeOf(RawMessage(nil))
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// Marshal returns the CBOR encoding of v.
+//
+// Structs are encoded as maps keyed by field name. The "cbor" struct tag may rename a
+// field, mark it "omitempty", or exclude it with "-". Byte slices are encoded as byte
+// strings, time.Time as a tag 0 date/time string, *big.Int as an integer or bignum,
+// *big.Rat as a tag 30 rational number and RawMessage verbatim. Types implementing
+// Marshaler encode themselves, taking precedence over the default mapping and struct tags.
+// Nil pointers, slices, maps and interfaces are encoded as null.
+func Marshal(v any, opts ...WriterOption) ([]byte, error) {
+	w := NewCborWriter(opts...)
+	if err := w.WriteValue(v); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// Build encodes a spec made of nested maps, slices and primitives, in the form produced by
+// ReadAny, as a single CBOR item. Writer options select the conformance mode.
+func Build(spec any, opts ...WriterOption) ([]byte, error) {
+	w := NewCborWriter(opts...)
+	if err := w.WriteAny(spec); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// Unmarshal decodes the single CBOR item in data into the value pointed to by v.
+// It returns ErrNotAtEnd if data contains bytes after the item. Nil pointers are allocated
+// as needed, at every level of a nested pointer, and existing ones are decoded into. Null
+// sets pointers, interfaces, maps and slices to nil and leaves other values unchanged.
+// Map keys that decode to a value Go cannot use as a map key, such as a byte string
+// decoded into an interface key, return ErrUnsupportedType as in ReadAny.
+func Unmarshal(data []byte, v any, opts ...ReaderOption) error {
+	r := NewCborReader(data, opts...)
+	if err := r.ReadValue(v); err != nil {
+		return err
+	}
+	if r.BytesRemaining() > 0 {
+		return NewCborError(ErrNotAtEnd, r.offset, "")
+	}
+	return nil
+}
+
+// DecodeStrict decodes untrusted data into v with a ConformanceStrict reader. On top of
+// Unmarshal's well-formedness checks it rejects integer, length and tag arguments that are
+// not minimally encoded, text strings that are not valid UTF-8, two-byte simple values
+// below 32, maps with duplicate keys (ErrDuplicateKey) and trailing data (ErrNotAtEnd).
+func DecodeStrict(data []byte, v any) error {
+	return decodeChecked(data, v, ConformanceStrict)
+}
+
+// DecodeCanonical decodes untrusted data into v with a ConformanceCanonical reader. It
+// performs every DecodeStrict check, rejects indefinite-length items and requires map keys
+// in bytewise lexicographic order of their encodings (ErrUnsortedKeys).
+func DecodeCanonical(data []byte, v any) error {
+	return decodeChecked(data, v, ConformanceCanonical)
+}
+
+// decodeChecked validates every map in data for the given mode before unmarshaling.
+func decodeChecked(data []byte, v any, mode CborConformanceMode) error {
+	r := NewCborReader(data, WithReaderConformanceMode(mode))
+	if err := r.checkMapKeys(); err != nil {
+		return err
+	}
+	return Unmarshal(data, v, WithReaderConformanceMode(mode))
+}
+
+// WriteValue writes an arbitrary Go value using reflection. See Marshal for the mapping.
+func (w *CborWriter) WriteValue(v any) error {
+	return w.encodeValue(reflect.ValueOf(v))
+}
+
+// WriteStringMap writes m as a definite-length map whose entries are always in the same
+// order. The writer sorts the keys of m, and of every map nested in its values, bytewise by
+// their encoding for the duration of the call, as WithWriterDeterministicMaps does; CTAP2
+// canonical writers keep their length-first order. Values are written with WriteValue.
+func (w *CborWriter) WriteStringMap(m map[string]any) error {
+	if !w.deterministicMaps {
+		w.deterministicMaps = true
+		defer func() { w.deterministicMaps = false }()
+	}
+
+	if err := w.WriteStartMap(len(m)); err != nil {
+		return err
+	}
+	for k, v := range m {
+		if err := w.writeTextKey(k); err != nil {
+			return err
+		}
+		if err := w.WriteValue(v); err != nil {
+			return err
+		}
+	}
+	return w.WriteEndMap()
+}
+
+// WriteAny writes a generic Go value of the kinds produced by ReadAny without reflection,
+// falling back to WriteValue for any other type. Map keys are written in iteration order;
+// canonical and deterministic writers sort them.
+func (w *CborWriter) WriteAny(v any) error {
+	switch value := v.(type) {
+	case nil:
+		return w.WriteNull()
+	case bool:
+		return w.WriteBoolean(value)
+	case int:
+		return w.WriteInt64(int64(value))
+	case int64:
+		return w.WriteInt64(value)
+	case uint64:
+		return w.WriteUint64(value)
+	case float64:
+		return w.WriteFloat(value)
+	case string:
+		return w.WriteTextString(value)
+	case []byte:
+		return w.WriteByteString(value)
+	case *big.Int:
+		return w.WriteBigInt(value)
+	case *big.Rat:
+		return w.WriteRat(value)
+	case SimpleValue:
+		return w.WriteSimpleValue(value)
+	case TaggedValue:
+		if err := w.WriteTag(value.Tag); err != nil {
+			return err
+		}
+		return w.WriteAny(value.Content)
+	case []any:
+		if err := w.WriteStartArray(len(value)); err != nil {
+			return err
+		}
+		for _, item := range value {
+			if err := w.WriteAny(item); err != nil {
+				return err
+			}
+		}
+		return w.WriteEndArray()
+	case map[any]any:
+		if err := w.WriteStartMap(len(value)); err != nil {
+			return err
+		}
+		for k, item := range value {
+			if err := w.writeMapKey(k); err != nil {
+				return err
+			}
+			if err := w.WriteAny(item); err != nil {
+				return err
+			}
+		}
+		return w.WriteEndMap()
+	case map[string]any:
+		if err := w.WriteStartMap(len(value)); err != nil {
+			return err
+		}
+		for k, item := range value {
+			if err := w.writeTextKey(k); err != nil {
+				return err
+			}
+			if err := w.WriteAny(item); err != nil {
+				return err
+			}
+		}
+		return w.WriteEndMap()
+	default:
+		return w.WriteValue(v)
+	}
+}
+
+// maxPtrLevel is the number of nested pointers encodeValue follows before it starts
+// checking for cycles, which would otherwise recurse without bound when they pass through
+// no container.
+const maxPtrLevel = 1000
+
+// encodePointer writes the value a non-nil pointer refers to, returning ErrCyclicValue if
+// the pointer is already being encoded further up.
+func (w *CborWriter) encodePointer(rv reflect.Value) error {
+	w.ptrLevel++
+	defer func() { w.ptrLevel-- }()
+	if w.ptrLevel > maxPtrLevel {
+		ptr := rv.Interface()
+		if _, ok := w.ptrSeen[ptr]; ok {
+			return NewCborError(ErrCyclicValue, w.Len(), rv.Type().String())
+		}
+		if w.ptrSeen == nil {
+			w.ptrSeen = make(map[any]struct{})
+		}
+		w.ptrSeen[ptr] = struct{}{}
+		defer delete(w.ptrSeen, ptr)
+	}
+	return w.encodeValue(rv.Elem())
+}
+
+// encodeValue writes a reflected Go value.
+func (w *CborWriter) encodeValue(rv reflect.Value) error {
+	if !rv.IsValid() {
+		return w.WriteNull()
+	}
+
+	if m, ok := asMarshaler(rv); ok {
+		data, err := m.MarshalCBOR()
+		if err != nil {
+			return err
+		}
+		return w.writeRawMessage(data)
+	}
+
+	switch rv.Type() {
+	case bigIntType:
+		value := rv.Interface().(big.Int)
+		return w.WriteBigInt(&value)
+	case bigRatType:
+		value := rv.Interface().(big.Rat)
+		return w.WriteRat(&value)
+	case timeType:
+		return w.WriteDateTimeString(rv.Interface().(time.Time))
+	case simpleValueType:
+		return w.WriteSimpleValue(SimpleValue(rv.Uint()))
+	case rawMessageType:
+		return w.writeRawMessage(rv.Bytes())
+	case taggedValueType:
+		tv := rv.Interface().(TaggedValue)
+		if err := w.WriteTag(tv.Tag); err != nil {
+			return err
+		}
+		return w.encodeValue(reflect.ValueOf(tv.Content))
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		if rv.Kind() == reflect.Pointer {
+			return w.encodePointer(rv)
+		}
+		return w.encodeValue(rv.Elem())
+	case reflect.Bool:
+		return w.WriteBoolean(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if name, ok := w.enumName(rv); ok {
+			return w.WriteTextString(name)
+		}
+		return w.WriteInt64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return w.WriteUint64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return w.writeFloatOfWidth(rv.Float(), rv.Type().Bits())
+	case reflect.String:
+		return w.WriteTextString(rv.String())
+	case reflect.Slice:
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return w.WriteByteString(rv.Bytes())
+		}
+		return w.encodeArray(rv)
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return w.WriteByteString(data)
+		}
+		return w.encodeArray(rv)
+	case reflect.Map:
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		return w.encodeMap(rv)
+	case reflect.Struct:
+		return w.encodeStruct(rv)
+	default:
+		return NewCborError(ErrUnsupportedType, w.Len(), rv.Type().String())
+	}
+}
+
+// asMarshaler returns rv as a Marshaler, using its address for pointer receivers when rv
+// is addressable. Nil pointers and interfaces are left to encode as null.
+func asMarshaler(rv reflect.Value) (Marshaler, bool) {
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return nil, false
+	}
+	if rv.Type().Implements(marshalerType) {
+		return rv.Interface().(Marshaler), true
+	}
+	if rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(marshalerType) {
+		return rv.Addr().Interface().(Marshaler), true
+	}
+	return nil, false
+}
+
+// writeRawMessage appends an encoded item after checking that it is exactly one
+// well-formed item, so the enclosing container stays consistent.
+func (w *CborWriter) writeRawMessage(raw []byte) error {
+	if len(raw) == 0 {
+		return w.WriteNull()
+	}
+
+	r := NewCborReader(raw)
+	if err := r.SkipValue(); err != nil {
+		return err
+	}
+	if r.BytesRemaining() > 0 {
+		return NewCborError(ErrInvalidCbor, len(w.buffer), "RawMessage must hold exactly one item")
+	}
+
+	w.appendRawItem(raw)
+	return nil
+}
+
+// encodeArray writes a slice or array as a definite-length array.
+func (w *CborWriter) encodeArray(rv reflect.Value) error {
+	if err := w.WriteStartArray(rv.Len()); err != nil {
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := w.encodeValue(rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return w.WriteEndArray()
+}
+
+// encodeMap writes a Go map as a definite-length map.
+// Keys are written in iteration order; canonical and deterministic writers sort them.
+func (w *CborWriter) encodeMap(rv reflect.Value) error {
+	if err := w.WriteStartMap(rv.Len()); err != nil {
+		return err
+	}
+	iter := rv.MapRange()
+	for iter.Next() {
+		var err error
+		if w.keyTemplate != nil {
+			err = w.writeMapKey(iter.Key().Interface())
+		} else {
+			err = w.encodeValue(iter.Key())
+		}
+		if err != nil {
+			return err
+		}
+		if err := w.encodeValue(iter.Value()); err != nil {
+			return err
+		}
+	}
+	return w.WriteEndMap()
+}
+
+// encodeStruct writes a struct as a map keyed by field name.
+func (w *CborWriter) encodeStruct(rv reflect.Value) error {
+	fields := cachedStructFields(rv.Type())
+
+	values := make([]reflect.Value, len(fields.list))
+	count := 0
+	for i := range fields.list {
+		f := &fields.list[i]
+		fv := rv.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		values[i] = fv
+		count++
+	}
+
+	if err := w.WriteStartMap(count); err != nil {
+		return err
+	}
+	for i := range fields.list {
+		if !values[i].IsValid() {
+			continue
+		}
+		if err := w.writeTextKey(fields.list[i].name); err != nil {
+			return err
+		}
+		if err := w.encodeValue(values[i]); err != nil {
+			return err
+		}
+	}
+	return w.WriteEndMap()
+}
+
+// isEmptyValue reports whether a field is skipped by omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ReadValue decodes the next item into the value pointed to by v using reflection.
+// See Marshal for the mapping. Values decoded into an empty interface use ReadAny.
+func (r *CborReader) ReadValue(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrInvalidUnmarshalTarget
+	}
+	return r.decodeValue(rv.Elem())
+}
+
+// ReadMapInto decodes the next map into m, which is a non-nil map[K]V or a pointer to one.
+// Keys and values are decoded as K and V with the mapping described in Marshal, entries
+// are added to any existing ones, and a nil map behind a pointer is allocated with the
+// declared size. Other targets return ErrInvalidUnmarshalTarget.
+func (r *CborReader) ReadMapInto(m any) error {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Map {
+		return r.decodeMap(rv.Elem())
+	}
+	if rv.Kind() != reflect.Map || rv.IsNil() {
+		return ErrInvalidUnmarshalTarget
+	}
+	return r.decodeMap(rv)
+}
+
+// ReadSliceInto decodes the next array into ptr, which is a *[]T or a *[N]T. Slices are
+// replaced by one sized to fit the array. Fixed-size arrays must match the array's length
+// exactly, otherwise ErrLengthMismatch is returned; they are only assigned once the whole
+// array has decoded, so on any error their elements are left unchanged. Other targets
+// return ErrInvalidUnmarshalTarget.
+func (r *CborReader) ReadSliceInto(ptr any) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrInvalidUnmarshalTarget
+	}
+	rv = rv.Elem()
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		return r.decodeSlice(rv)
+	case reflect.Array:
+		return r.decodeFixedArray(rv)
+	default:
+		return ErrInvalidUnmarshalTarget
+	}
+}
+
+// decodeFixedArray decodes an array whose length must equal that of the Go array dst. The
+// items are decoded into a copy of dst, which is stored only when they all fit, since an
+// indefinite-length array reveals its length at the break.
+func (r *CborReader) decodeFixedArray(dst reflect.Value) error {
+	start := r.offset
+	length, err := r.ReadStartArray()
+	if err != nil {
+		return err
+	}
+	if length >= 0 && length != dst.Len() {
+		return NewCborError(ErrLengthMismatch, start, "ReadSliceInto")
+	}
+
+	rv := reflect.New(dst.Type()).Elem()
+	rv.Set(dst)
+
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndArray)
+		if err != nil {
+			return err
+		}
+		if !more {
+			if i != rv.Len() {
+				return NewCborError(ErrLengthMismatch, start, "ReadSliceInto")
+			}
+			break
+		}
+		if i == rv.Len() {
+			return NewCborError(ErrLengthMismatch, start, "ReadSliceInto")
+		}
+		if err := r.decodeValue(rv.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	if err := r.ReadEndArray(); err != nil {
+		return err
+	}
+	dst.Set(rv)
+	return nil
+}
+
+// decodeValue decodes the next item into a settable reflected value.
+func (r *CborReader) decodeValue(rv reflect.Value) error {
+	// Pointers are allocated below and their targets revisited, so only non-pointer
+	// values with a pointer-receiver UnmarshalCBOR are handed the encoded item here.
+	if rv.Kind() != reflect.Pointer && rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(unmarshalerType) {
+		raw, err := r.ReadEncodedValue()
+		if err != nil {
+			return err
+		}
+		return rv.Addr().Interface().(Unmarshaler).UnmarshalCBOR(raw)
+	}
+
+	if rv.Type() == rawMessageType {
+		raw, err := r.ReadEncodedValue()
+		if err != nil {
+			return err
+		}
+		rv.SetBytes(raw)
+		return nil
+	}
+
+	state, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+
+	if r.isNull(state) {
+		switch rv.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return r.skipNull()
+	}
+
+	switch rv.Type() {
+	case bigIntType:
+		value, err := r.ReadBigInt()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(value).Elem())
+		return nil
+	case bigRatType:
+		value, err := r.ReadRat()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(value).Elem())
+		return nil
+	case timeType:
+		t, err := r.readTime()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	case simpleValueType:
+		value, err := r.ReadSimpleValue()
+		if err != nil {
+			return err
+		}
+		rv.SetUint(uint64(value))
+		return nil
+	case taggedValueType:
+		if state != StateTag {
+			return &TypeMismatchError{Expected: StateTag, Actual: state, Offset: r.offset}
+		}
+		tag, err := r.ReadTag()
+		if err != nil {
+			return err
+		}
+		content, err := r.ReadAny()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(TaggedValue{Tag: tag, Content: content}))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return r.decodeValue(rv.Elem())
+
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			if !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+				return r.decodeValue(rv.Elem().Elem())
+			}
+			return NewCborError(ErrUnsupportedType, r.offset, rv.Type().String())
+		}
+		value, err := r.ReadAny()
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+		} else {
+			rv.Set(reflect.ValueOf(value))
+		}
+		return nil
+
+	case reflect.Bool:
+		value, err := r.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		rv.SetBool(value)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if values, ok := r.enumValues[rv.Type()]; ok && (state == StateTextString || state == StateStartIndefiniteLengthTextString) {
+			return r.decodeEnumName(rv, values)
+		}
+		value, err := r.ReadInt64()
+		if err != nil {
+			return err
+		}
+		if rv.OverflowInt(value) {
+			return NewCborError(ErrOverflow, r.offset, "Unmarshal")
+		}
+		rv.SetInt(value)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value, err := r.ReadUint64()
+		if err != nil {
+			return err
+		}
+		if rv.OverflowUint(value) {
+			return NewCborError(ErrOverflow, r.offset, "Unmarshal")
+		}
+		rv.SetUint(value)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var value float64
+		switch state {
+		case StateUnsignedInteger, StateNegativeInteger:
+			i, err := r.ReadInt64()
+			if err != nil {
+				return err
+			}
+			value = float64(i)
+		default:
+			value, err = r.ReadFloat()
+			if err != nil {
+				return err
+			}
+		}
+		rv.SetFloat(value)
+		return nil
+
+	case reflect.String:
+		value, err := r.ReadTextString()
+		if err != nil {
+			return err
+		}
+		rv.SetString(value)
+		return nil
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && state != StateStartArray {
+			value, err := r.ReadByteString()
+			if err != nil {
+				return err
+			}
+			rv.SetBytes(value)
+			return nil
+		}
+		return r.decodeSlice(rv)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && state != StateStartArray {
+			value, err := r.ReadByteString()
+			if err != nil {
+				return err
+			}
+			reflect.Copy(rv, reflect.ValueOf(value))
+			for i := len(value); i < rv.Len(); i++ {
+				rv.Index(i).SetUint(0)
+			}
+			return nil
+		}
+		return r.decodeArray(rv)
+
+	case reflect.Map:
+		return r.decodeMap(rv)
+
+	case reflect.Struct:
+		return r.decodeStruct(rv)
+
+	default:
+		return NewCborError(ErrUnsupportedType, r.offset, rv.Type().String())
+	}
+}
+
+// readTime decodes a tag 0 or tag 1 date/time, or an untagged RFC 3339 string.
+func (r *CborReader) readTime() (time.Time, error) {
+	if err := r.skipSelfDescribedTags(); err != nil {
+		return time.Time{}, err
+	}
+	state, err := r.PeekState()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if state == StateTextString || state == StateStartIndefiniteLengthTextString {
+		str, err := r.ReadTextString()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Parse(time.RFC3339Nano, str)
+	}
+
+	tag, err := r.PeekTag()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if tag == TagUnixTime {
+		return r.ReadUnixTime()
+	}
+	return r.ReadDateTimeString()
+}
+
+// moreItems reports whether another item follows in a container of the given length,
+// where i items have been read so far and end is the state that closes the container.
+func (r *CborReader) moreItems(length, i int, end CborReaderState) (bool, error) {
+	if length >= 0 {
+		return i < length, nil
+	}
+	state, err := r.PeekState()
+	if err != nil {
+		return false, err
+	}
+	return state != end, nil
+}
+
+// decodeSlice decodes an array into a slice, growing it as needed.
+func (r *CborReader) decodeSlice(rv reflect.Value) error {
+	length, err := r.ReadStartArray()
+	if err != nil {
+		return err
+	}
+
+	if length >= 0 {
+		rv.Set(reflect.MakeSlice(rv.Type(), length, length))
+	} else {
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+	}
+
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndArray)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+		if length < 0 {
+			rv.Set(reflect.Append(rv, reflect.Zero(rv.Type().Elem())))
+		}
+		if err := r.decodeValue(rv.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return r.ReadEndArray()
+}
+
+// decodeArray decodes an array into a Go array. Extra elements are skipped
+// and missing elements are set to their zero value.
+func (r *CborReader) decodeArray(rv reflect.Value) error {
+	length, err := r.ReadStartArray()
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for ; ; i++ {
+		more, err := r.moreItems(length, i, StateEndArray)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+		if i < rv.Len() {
+			err = r.decodeValue(rv.Index(i))
+		} else {
+			err = r.SkipValue()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for ; i < rv.Len(); i++ {
+		rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+	}
+
+	return r.ReadEndArray()
+}
+
+// decodeMap decodes a map into a Go map, allocating it if nil.
+func (r *CborReader) decodeMap(rv reflect.Value) error {
+	length, err := r.ReadStartMap()
+	if err != nil {
+		return err
+	}
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(rv.Type(), max(length, 0)))
+	}
+
+	keyType := rv.Type().Key()
+	elemType := rv.Type().Elem()
+
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndMap)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		keyOffset := r.offset
+		key := reflect.New(keyType).Elem()
+		if err := r.decodeValue(key); err != nil {
+			return err
+		}
+		if !key.Comparable() {
+			return NewCborError(ErrUnsupportedType, keyOffset, "unhashable map key")
+		}
+
+		elem := reflect.New(elemType).Elem()
+		if err := r.decodeValue(elem); err != nil {
+			return err
+		}
+		rv.SetMapIndex(key, elem)
+	}
+
+	return r.ReadEndMap()
+}
+
+// decodeStruct decodes a map keyed by field name into a struct.
+// Unknown keys and keys that are not text strings are skipped.
+func (r *CborReader) decodeStruct(rv reflect.Value) error {
+	length, err := r.ReadStartMap()
+	if err != nil {
+		return err
+	}
+
+	fields := cachedStructFields(rv.Type())
+
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndMap)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		state, err := r.PeekState()
+		if err != nil {
+			return err
+		}
+		if state != StateTextString && state != StateStartIndefiniteLengthTextString {
+			if err := r.SkipValue(); err != nil {
+				return err
+			}
+			if err := r.SkipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, err := r.ReadTextString()
+		if err != nil {
+			return err
+		}
+
+		f := fields.lookup(name)
+		if f == nil {
+			err = r.SkipValue()
+		} else {
+			err = r.decodeValue(rv.FieldByIndex(f.index))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.ReadEndMap()
+}
+
+// ReadStringMapTyped reads a map with text string keys, decoding each value into
+// the Go type returned by typeFor for its key. Values whose key maps to a nil type
+// are decoded with ReadAny.
+func (r *CborReader) ReadStringMapTyped(typeFor func(key string) reflect.Type) (map[string]any, error) {
+	length, err := r.ReadStartMap()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, max(length, 0))
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndMap)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+
+		key, err := r.ReadTextString()
+		if err != nil {
+			return nil, err
+		}
+
+		typ := typeFor(key)
+		if typ == nil {
+			value, err := r.ReadAny()
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+			continue
+		}
+
+		value := reflect.New(typ).Elem()
+		if err := r.decodeValue(value); err != nil {
+			return nil, err
+		}
+		result[key] = value.Interface()
+	}
+
+	return result, r.ReadEndMap()
+}
+
+// ReadAny decodes the next item into a generic Go value:
+// unsigned integers as uint64, negative integers as int64 (or *big.Int when they don't fit),
+// bignums as *big.Int, rational numbers as *big.Rat, byte strings as []byte, text strings
+// as string, arrays as []any, maps as map[any]any, floats as float64, booleans as bool,
+// null as nil, other simple values (including undefined) as SimpleValue and any other
+// tagged item as TaggedValue. Unassigned simple values are passed to the handler set by
+// WithReaderSimpleValueHandler, if any.
+// Map keys must be usable as Go map keys. Null keys decode as nil, but keys that decode
+// to []byte, []any or map[any]any, including byte strings, return ErrUnsupportedType
+// rather than being converted to a comparable form that could collide with another key;
+// read such maps item by item instead.
+// When range tracking is enabled, the byte range of every decoded item is recorded and
+// made available through LastValueRanges.
+func (r *CborReader) ReadAny() (any, error) {
+	if !r.trackRanges {
+		return r.readAny()
+	}
+
+	parent := r.rangeParent
+	node := &ValueRange{Start: r.offset}
+	r.rangeParent = node
+	value, err := r.readAny()
+	r.rangeParent = parent
+	if err != nil {
+		return nil, err
+	}
+
+	node.End = r.offset
+	if parent != nil {
+		parent.Children = append(parent.Children, *node)
+	} else {
+		r.lastRanges = node
+	}
+	return value, nil
+}
+
+// readAny implements ReadAny.
+func (r *CborReader) readAny() (any, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return nil, err
+	}
+
+	switch state {
+	case StateUnsignedInteger:
+		return r.ReadUint64()
+	case StateNegativeInteger:
+		r.invalidateState()
+		raw, err := r.readArgumentValue(MajorTypeNegativeInteger)
+		if err != nil {
+			return nil, err
+		}
+		r.advanceContainer()
+		if raw <= math.MaxInt64 {
+			return -1 - int64(raw), nil
+		}
+		result := new(big.Int).SetUint64(raw)
+		result.Add(result, big.NewInt(1))
+		return result.Neg(result), nil
+	case StateByteString, StateStartIndefiniteLengthByteString:
+		return r.ReadByteString()
+	case StateTextString, StateStartIndefiniteLengthTextString:
+		return r.ReadTextString()
+	case StateStartArray:
+		length, err := r.ReadStartArray()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]any, 0, max(length, 0))
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, StateEndArray)
+			if err != nil {
+				return nil, err
+			}
+			if !more {
+				break
+			}
+			item, err := r.ReadAny()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, item)
+		}
+		return result, r.ReadEndArray()
+	case StateStartMap:
+		length, err := r.ReadStartMap()
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[any]any, max(length, 0))
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, StateEndMap)
+			if err != nil {
+				return nil, err
+			}
+			if !more {
+				break
+			}
+			keyOffset := r.offset
+			key, err := r.ReadAny()
+			if err != nil {
+				return nil, err
+			}
+			if key != nil && !reflect.ValueOf(key).Comparable() {
+				return nil, NewCborError(ErrUnsupportedType, keyOffset, "unhashable map key")
+			}
+			value, err := r.ReadAny()
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+		return result, r.ReadEndMap()
+	case StateTag:
+		tag, err := r.PeekTag()
+		if err != nil {
+			return nil, err
+		}
+		if tag == TagUnsignedBignum || tag == TagNegativeBignum {
+			return r.ReadBigInt()
+		}
+		if tag == TagRationalNumber {
+			return r.ReadRat()
+		}
+		// Tags nest without entering a container, so they count against the depth limit here.
+		if len(r.nestingStack)+r.anyTagDepth >= r.maxNestingDepth {
+			return nil, NewCborError(ErrNestingDepthExceeded, r.offset, "ReadAny")
+		}
+		if _, err := r.ReadTag(); err != nil {
+			return nil, err
+		}
+		r.anyTagDepth++
+		content, err := r.ReadAny()
+		r.anyTagDepth--
+		if err != nil {
+			return nil, err
+		}
+		return TaggedValue{Tag: tag, Content: content}, nil
+	case StateBoolean:
+		return r.ReadBoolean()
+	case StateNull:
+		return nil, r.ReadNull()
+	case StateUndefinedValue:
+		if r.undefinedAsNull {
+			return nil, r.skipNull()
+		}
+		return r.ReadSimpleValue()
+	case StateSimpleValue:
+		value, err := r.ReadSimpleValue()
+		if err != nil || r.simpleValueHandler == nil {
+			return value, err
+		}
+		return r.simpleValueHandler(value)
+	case StateHalfPrecisionFloat, StateSinglePrecisionFloat, StateDoublePrecisionFloat:
+		return r.ReadFloat()
+	default:
+		return nil, NewCborError(ErrInvalidState, r.offset, "ReadAny")
+	}
+}
+
+// fieldInfo describes how a struct field is encoded.
+type fieldInfo struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields is the cached field descriptor of a struct type.
+type structFields struct {
+	list   []fieldInfo
+	byName map[string]int
+}
+
+// lookup finds a field by its encoded name, falling back to a case-insensitive match.
+func (sf *structFields) lookup(name string) *fieldInfo {
+	if i, ok := sf.byName[name]; ok {
+		return &sf.list[i]
+	}
+	for i := range sf.list {
+		if strings.EqualFold(sf.list[i].name, name) {
+			return &sf.list[i]
+		}
+	}
+	return nil
+}
+
+// fieldCache maps reflect.Type to *structFields.
+var fieldCache sync.Map
+
+// cachedStructFields returns the field descriptor for a struct type, computing it once.
+func cachedStructFields(t reflect.Type) *structFields {
+	if sf, ok := fieldCache.Load(t); ok {
+		return sf.(*structFields)
+	}
+	sf, _ := fieldCache.LoadOrStore(t, computeStructFields(t))
+	return sf.(*structFields)
+}
+
+// ClearTypeCache discards all cached struct field descriptors.
+func ClearTypeCache() {
+	fieldCache.Range(func(key, _ any) bool {
+		fieldCache.Delete(key)
+		return true
+	})
+}
+
+// computeStructFields builds the field descriptor for a struct type.
+// Fields of embedded structs without a name tag are promoted; outer fields win on conflicts.
+func computeStructFields(t reflect.Type) *structFields {
+	sf := &structFields{byName: make(map[string]int)}
+
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("cbor")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		sf.add(fieldInfo{
+			name:      name,
+			index:     field.Index,
+			omitEmpty: strings.Contains(opts, "omitempty"),
+		})
+	}
+
+	for _, field := range embedded {
+		inner := cachedStructFields(field.Type)
+		for _, f := range inner.list {
+			f.index = append([]int{field.Index[0]}, f.index...)
+			sf.add(f)
+		}
+	}
+
+	return sf
+}
+
+// add appends a field unless one with the same name already exists.
+func (sf *structFields) add(f fieldInfo) {
+	if _, ok := sf.byName[f.name]; ok {
+		return
+	}
+	sf.byName[f.name] = len(sf.list)
+	sf.list = append(sf.list, f)
+}
@@ -0,0 +1,951 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type marshalInner struct {
+	Label string `cbor:"label"`
+}
+
+type marshalEmbedded struct {
+	Shared string
+	Extra  int
+}
+
+type marshalRecord struct {
+	marshalEmbedded
+	Name     string            `cbor:"name"`
+	Age      int               `cbor:"age,omitempty"`
+	Tags     []string          `cbor:"tags"`
+	Data     []byte            `cbor:"data"`
+	Inner    *marshalInner     `cbor:"inner"`
+	Attrs    map[string]uint16 `cbor:"attrs"`
+	Score    float64           `cbor:"score"`
+	Big      *big.Int          `cbor:"big"`
+	When     time.Time         `cbor:"when"`
+	Any      any               `cbor:"any"`
+	Shared   string            `cbor:"Shared"`
+	Ignored  string            `cbor:"-"`
+	internal int
+}
+
+func TestMarshalUnmarshalStruct(t *testing.T) {
+	bigValue, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	in := marshalRecord{
+		marshalEmbedded: marshalEmbedded{Shared: "shadowed", Extra: 7},
+		Name:            "Alice",
+		Tags:            []string{"a", "b"},
+		Data:            []byte{1, 2, 3},
+		Inner:           &marshalInner{Label: "x"},
+		Attrs:           map[string]uint16{"k": 300},
+		Score:           1.5,
+		Big:             bigValue,
+		When:            time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+		Any:             []any{uint64(1), "two"},
+		Shared:          "outer",
+		Ignored:         "ignored",
+		internal:        1,
+	}
+
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var out marshalRecord
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	in.Ignored = ""
+	in.internal = 0
+	in.marshalEmbedded.Shared = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+}
+
+func TestMarshalOmitEmpty(t *testing.T) {
+	data, err := Marshal(struct {
+		A int    `cbor:"a,omitempty"`
+		B string `cbor:"b"`
+	}{B: "x"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "a161626178" {
+		t.Errorf("got %s, want a161626178", got)
+	}
+}
+
+func TestMarshalCanonicalSortsStructFields(t *testing.T) {
+	data, err := Marshal(struct {
+		B int `cbor:"bb"`
+		A int `cbor:"a"`
+	}{B: 1, A: 2}, WithConformanceMode(ConformanceCanonical))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "a261610262626201" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestMarshalUnsupportedType(t *testing.T) {
+	_, err := Marshal(make(chan int))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var v int
+	if err := Unmarshal([]byte{0x01}, v); !errors.Is(err, ErrInvalidUnmarshalTarget) {
+		t.Errorf("expected ErrInvalidUnmarshalTarget, got %v", err)
+	}
+	if err := Unmarshal([]byte{0x01, 0x02}, &v); !errors.Is(err, ErrNotAtEnd) {
+		t.Errorf("expected ErrNotAtEnd, got %v", err)
+	}
+	var small int8
+	if err := Unmarshal([]byte{0x19, 0x01, 0x00}, &small); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+	var s string
+	var tmErr *TypeMismatchError
+	if err := Unmarshal([]byte{0x01}, &s); !errors.As(err, &tmErr) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+}
+
+func TestUnmarshalUnknownFieldsSkipped(t *testing.T) {
+	// {"unknown": [1, 2], 1: 2, "label": "v"}
+	data, _ := hex.DecodeString("a367756e6b6e6f776e820102010265" + "6c6162656c6176")
+	var out marshalInner
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Label != "v" {
+		t.Errorf("got %q, want %q", out.Label, "v")
+	}
+}
+
+func TestReadAny(t *testing.T) {
+	// [1, -2, h'01', "a", {"k": [true, null]}, 1.5, 32("u"), f7, -18446744073709551616]
+	data, _ := hex.DecodeString("89" + "01" + "21" + "4101" + "6161" + "a1616b82f5f6" + "f93e00" + "d8206175" + "f7" + "3bffffffffffffffff")
+	r := NewCborReader(data)
+	got, err := r.ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+
+	minBig, _ := new(big.Int).SetString("-18446744073709551616", 10)
+	want := []any{
+		uint64(1),
+		int64(-2),
+		[]byte{1},
+		"a",
+		map[any]any{"k": []any{true, nil}},
+		1.5,
+		TaggedValue{Tag: TagURI, Content: "u"},
+		SimpleValueUndefined,
+		minBig,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestReadAnyRejectsUnhashableKeys(t *testing.T) {
+	// {[1]: 2}
+	r := NewCborReader([]byte{0xa1, 0x81, 0x01, 0x02})
+	if _, err := r.ReadAny(); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+
+	// {h'01': 2}: byte strings decode to []byte, which cannot key a Go map
+	data := []byte{0xa1, 0x41, 0x01, 0x02}
+	if _, err := NewCborReader(data).ReadAny(); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("byte string key: expected ErrUnsupportedType, got %v", err)
+	}
+	var v any
+	if err := Unmarshal(data, &v); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("byte string key: expected ErrUnsupportedType from Unmarshal, got %v", err)
+	}
+}
+
+func TestReadAnyNullKey(t *testing.T) {
+	// {null: 1}
+	data := []byte{0xa1, 0xf6, 0x01}
+	want := map[any]any{nil: uint64(1)}
+
+	got, err := NewCborReader(data).ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	var v any
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+
+	var m map[any]int
+	if err := Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal into map[any]int failed: %v", err)
+	}
+	if len(m) != 1 || m[nil] != 1 {
+		t.Errorf("got %#v", m)
+	}
+}
+
+func TestMarshalCycle(t *testing.T) {
+	var x any
+	x = &x
+	if _, err := Marshal(x); !errors.Is(err, ErrCyclicValue) {
+		t.Errorf("expected ErrCyclicValue, got %v", err)
+	}
+
+	tv := &TaggedValue{Tag: 6}
+	tv.Content = tv
+	if _, err := Marshal(tv); !errors.Is(err, ErrCyclicValue) {
+		t.Errorf("TaggedValue: expected ErrCyclicValue, got %v", err)
+	}
+
+	// the same pointer twice is not a cycle
+	n := new(int)
+	*n = 1
+	data, err := Marshal([]*int{n, n})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "820101" {
+		t.Errorf("expected 820101, got %s", got)
+	}
+}
+
+func TestWriteValueReadAnyRoundTrip(t *testing.T) {
+	value := []any{uint64(1), int64(-1), "s", []byte{9}, map[any]any{"a": true}, TaggedValue{Tag: 100, Content: uint64(5)}}
+	w := NewCborWriter()
+	if err := w.WriteValue(value); err != nil {
+		t.Fatalf("WriteValue failed: %v", err)
+	}
+	r := NewCborReader(w.Bytes())
+	got, err := r.ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, value) {
+		t.Errorf("got %#v, want %#v", got, value)
+	}
+}
+
+func TestStructFieldCache(t *testing.T) {
+	ClearTypeCache()
+	typ := reflect.TypeOf(marshalRecord{})
+	first := cachedStructFields(typ)
+	if second := cachedStructFields(typ); first != second {
+		t.Error("expected cached descriptor to be reused")
+	}
+
+	ClearTypeCache()
+	if _, ok := fieldCache.Load(typ); ok {
+		t.Error("expected cache to be empty after ClearTypeCache")
+	}
+}
+
+func TestUnmarshalIndefiniteContainers(t *testing.T) {
+	// {_ "tags": [_ "a", "b"], "data": (_ h'01', h'02')}
+	data, _ := hex.DecodeString("bf" + "6474616773" + "9f61616162ff" + "6464617461" + "5f41014102ff" + "ff")
+	var out marshalRecord
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out.Tags, []string{"a", "b"}) {
+		t.Errorf("got tags %v", out.Tags)
+	}
+	if !bytes.Equal(out.Data, []byte{1, 2}) {
+		t.Errorf("got data %v", out.Data)
+	}
+}
+
+func BenchmarkUnmarshalStruct(b *testing.B) {
+	data, err := Marshal(marshalInner{Label: "benchmark"})
+	if err != nil {
+		b.Fatalf("Marshal failed: %v", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var out marshalInner
+		if err := Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReadStringMapTyped(t *testing.T) {
+	in := map[string]any{
+		"port":  uint64(8080),
+		"inner": marshalInner{Label: "x"},
+		"other": []any{"a"},
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	r := NewCborReader(data)
+	got, err := r.ReadStringMapTyped(func(key string) reflect.Type {
+		switch key {
+		case "port":
+			return reflect.TypeOf(uint16(0))
+		case "inner":
+			return reflect.TypeOf(&marshalInner{})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadStringMapTyped failed: %v", err)
+	}
+
+	want := map[string]any{
+		"port":  uint16(8080),
+		"inner": &marshalInner{Label: "x"},
+		"other": []any{"a"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Keys must be text strings
+	r = NewCborReader([]byte{0xa1, 0x01, 0x02})
+	var tmErr *TypeMismatchError
+	if _, err := r.ReadStringMapTyped(func(string) reflect.Type { return nil }); !errors.As(err, &tmErr) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+}
+
+func TestMarshalRat(t *testing.T) {
+	in := big.NewRat(-5, 8)
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var out big.Rat
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Cmp(in) != 0 {
+		t.Errorf("expected %s, got %s", in, &out)
+	}
+
+	v, err := NewCborReader(data).ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if r, ok := v.(*big.Rat); !ok || r.Cmp(in) != 0 {
+		t.Errorf("expected *big.Rat %s, got %#v", in, v)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	spec := map[any]any{
+		"name":  "widget",
+		"count": uint64(3),
+		"delta": int64(-2),
+		"tags":  []any{"a", true, nil, 1.5},
+		"blob":  []byte{0x01, 0x02},
+		"when":  TaggedValue{Tag: TagUnixTime, Content: uint64(1363896240)},
+	}
+
+	data, err := Build(spec, WithConformanceMode(ConformanceCanonical))
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	again, err := Build(spec, WithConformanceMode(ConformanceCanonical))
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("canonical Build is not deterministic: %x vs %x", data, again)
+	}
+
+	got, err := NewCborReader(data).ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, spec) {
+		t.Errorf("expected %#v, got %#v", spec, got)
+	}
+
+	// Types outside the ReadAny forms fall back to reflection
+	data, err = Build(map[string]any{"n": int32(7)})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "a1616e07" {
+		t.Errorf("expected a1616e07, got %s", got)
+	}
+}
+
+func TestReadAnySimpleValueHandler(t *testing.T) {
+	errUnknown := errors.New("unknown simple value")
+	handler := func(v SimpleValue) (any, error) {
+		if v == 99 {
+			return "pending", nil
+		}
+		return nil, errUnknown
+	}
+
+	// [simple(99), true, undefined]
+	data := []byte{0x83, 0xf8, 0x63, 0xf5, 0xf7}
+	got, err := NewCborReader(data, WithReaderSimpleValueHandler(handler)).ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	want := []any{"pending", true, SimpleValueUndefined}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+
+	r := NewCborReader([]byte{0xf0}, WithReaderSimpleValueHandler(handler))
+	if _, err := r.ReadAny(); err != errUnknown {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	got, err = NewCborReader([]byte{0xf8, 0x63}).ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if got != SimpleValue(99) {
+		t.Errorf("expected SimpleValue(99), got %#v", got)
+	}
+}
+
+func TestDecodeStrictAndCanonical(t *testing.T) {
+	type pair struct {
+		A int `cbor:"a"`
+		B int `cbor:"b"`
+	}
+
+	tests := []struct {
+		name      string
+		hex       string
+		strict    error
+		canonical error
+	}{
+		{"canonical", "a2616101616202", nil, nil},
+		{"unsorted", "a2616202616101", nil, ErrUnsortedKeys},
+		{"duplicate", "a2616101616102", ErrDuplicateKey, ErrDuplicateKey},
+		{"non_minimal", "a261611801616202", ErrNonCanonical, ErrNonCanonical},
+		{"indefinite", "bf616101616202ff", nil, ErrIndefiniteLengthNotAllowed},
+		{"trailing", "a161610102", ErrNotAtEnd, ErrNotAtEnd},
+		{"nested_unsorted", "a1616181a2616202616101", nil, ErrUnsortedKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			var v any
+			if err := DecodeStrict(data, &v); !errors.Is(err, tt.strict) {
+				t.Errorf("DecodeStrict: expected %v, got %v", tt.strict, err)
+			}
+			if err := DecodeCanonical(data, &v); !errors.Is(err, tt.canonical) {
+				t.Errorf("DecodeCanonical: expected %v, got %v", tt.canonical, err)
+			}
+		})
+	}
+
+	data, _ := hex.DecodeString("a2616101616202")
+	var out pair
+	if err := DecodeCanonical(data, &out); err != nil {
+		t.Fatalf("DecodeCanonical failed: %v", err)
+	}
+	if out != (pair{A: 1, B: 2}) {
+		t.Errorf("expected {1 2}, got %+v", out)
+	}
+}
+
+type rawEnvelope struct {
+	Kind    string     `cbor:"kind"`
+	Payload RawMessage `cbor:"payload"`
+}
+
+func TestRawMessage(t *testing.T) {
+	// Non-canonical payload: uint 1 encoded in two bytes
+	payload, _ := hex.DecodeString("a161611801")
+
+	data, err := Marshal(rawEnvelope{Kind: "x", Payload: payload})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Contains(data, payload) {
+		t.Errorf("expected payload %x verbatim in %x", payload, data)
+	}
+
+	var decoded rawEnvelope
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Kind != "x" || !bytes.Equal(decoded.Payload, payload) {
+		t.Errorf("unexpected result: %+v", decoded)
+	}
+
+	var inner map[string]int
+	if err := Unmarshal(decoded.Payload, &inner); err != nil {
+		t.Fatalf("Unmarshal payload failed: %v", err)
+	}
+	if inner["a"] != 1 {
+		t.Errorf("expected a=1, got %v", inner)
+	}
+}
+
+func TestRawMessageNull(t *testing.T) {
+	data, err := Marshal(rawEnvelope{Kind: "x"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded rawEnvelope
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !bytes.Equal(decoded.Payload, []byte{0xf6}) {
+		t.Errorf("expected f6, got %x", decoded.Payload)
+	}
+}
+
+func TestRawMessageInvalid(t *testing.T) {
+	for _, raw := range []string{"0102", "82", "ff"} {
+		payload, _ := hex.DecodeString(raw)
+		_, err := Marshal(rawEnvelope{Kind: "x", Payload: payload})
+		if err == nil {
+			t.Errorf("%s: expected error", raw)
+		}
+	}
+}
+
+func TestRawMessageCanonicalSort(t *testing.T) {
+	w := NewCborWriter(WithConformanceMode(ConformanceCanonical))
+	if err := w.WriteValue(map[string]RawMessage{"b": {0x02}, "a": {0x01}}); err != nil {
+		t.Fatalf("WriteValue failed: %v", err)
+	}
+	expected, _ := hex.DecodeString("a2616101616202")
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("expected %x, got %x", expected, w.Bytes())
+	}
+}
+
+// unixTime encodes as an integer epoch instead of the default tag 0 string.
+type unixTime struct {
+	time.Time
+}
+
+func (u unixTime) MarshalCBOR() ([]byte, error) {
+	w := NewCborWriter()
+	if err := w.WriteUnixTime(u.Time); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+func (u *unixTime) UnmarshalCBOR(data []byte) error {
+	t, err := NewCborReader(data).ReadUnixTime()
+	if err != nil {
+		return err
+	}
+	u.Time = t
+	return nil
+}
+
+// level is an enum encoded by name, overriding its struct tags.
+type level struct {
+	Value int `cbor:"value"`
+}
+
+var levelNames = []string{"low", "high"}
+
+func (l level) MarshalCBOR() ([]byte, error) {
+	w := NewCborWriter()
+	if err := w.WriteTextString(levelNames[l.Value]); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+func (l *level) UnmarshalCBOR(data []byte) error {
+	name, err := NewCborReader(data).ReadTextString()
+	if err != nil {
+		return err
+	}
+	for i, n := range levelNames {
+		if n == name {
+			l.Value = i
+			return nil
+		}
+	}
+	return errors.New("unknown level " + name)
+}
+
+type customRecord struct {
+	At    unixTime  `cbor:"at"`
+	Level level     `cbor:"level"`
+	Ptr   *unixTime `cbor:"ptr"`
+}
+
+func TestMarshalerUnmarshaler(t *testing.T) {
+	at := unixTime{time.Unix(1363896240, 0).UTC()}
+	data, err := Marshal(customRecord{At: at, Level: level{Value: 1}, Ptr: &at})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	// {"at": 1(1363896240), "level": "high", "ptr": 1(1363896240)}
+	expected, _ := hex.DecodeString("a3626174c11a514b67b0656c6576656c646869676863707472c11a514b67b0")
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %x, got %x", expected, data)
+	}
+
+	var decoded customRecord
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !decoded.At.Equal(at.Time) || decoded.Level.Value != 1 || decoded.Ptr == nil || !decoded.Ptr.Equal(at.Time) {
+		t.Errorf("unexpected result: %+v", decoded)
+	}
+}
+
+type badMarshaler struct{}
+
+func (badMarshaler) MarshalCBOR() ([]byte, error) {
+	return []byte{0x01, 0x02}, nil
+}
+
+func TestMarshalerInvalidOutput(t *testing.T) {
+	if _, err := Marshal(badMarshaler{}); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor, got %v", err)
+	}
+}
+
+func TestReadMapInto(t *testing.T) {
+	// {1: "a", 2: "b"}
+	data, _ := hex.DecodeString("a2016161026162")
+
+	byInt := map[int]string{3: "c"}
+	if err := NewCborReader(data).ReadMapInto(byInt); err != nil {
+		t.Fatalf("ReadMapInto failed: %v", err)
+	}
+	if len(byInt) != 3 || byInt[1] != "a" || byInt[2] != "b" {
+		t.Errorf("unexpected map: %v", byInt)
+	}
+
+	// {"x": 1.5}
+	data, _ = hex.DecodeString("a16178f93e00")
+	var byName map[string]float64
+	if err := NewCborReader(data).ReadMapInto(&byName); err != nil {
+		t.Fatalf("ReadMapInto failed: %v", err)
+	}
+	if byName["x"] != 1.5 {
+		t.Errorf("unexpected map: %v", byName)
+	}
+}
+
+func TestReadMapIntoErrors(t *testing.T) {
+	data, _ := hex.DecodeString("a16178f93e00")
+
+	var mismatch *TypeMismatchError
+	if err := NewCborReader(data).ReadMapInto(map[int]float64{}); !errors.As(err, &mismatch) || mismatch.Offset != 1 {
+		t.Errorf("expected TypeMismatchError at offset 1, got %v", err)
+	}
+	if err := NewCborReader(data).ReadMapInto(map[string]string{}); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+	if err := NewCborReader([]byte{0x80}).ReadMapInto(map[string]int{}); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+
+	var nilMap map[string]int
+	for _, target := range []any{nilMap, []int{}, nil} {
+		if err := NewCborReader(data).ReadMapInto(target); !errors.Is(err, ErrInvalidUnmarshalTarget) {
+			t.Errorf("%T: expected ErrInvalidUnmarshalTarget, got %v", target, err)
+		}
+	}
+}
+
+func TestReadSliceInto(t *testing.T) {
+	for _, input := range []string{"83010203", "9f010203ff"} {
+		data, _ := hex.DecodeString(input)
+
+		var slice []int
+		if err := NewCborReader(data).ReadSliceInto(&slice); err != nil {
+			t.Fatalf("%s: ReadSliceInto slice failed: %v", input, err)
+		}
+		if !reflect.DeepEqual(slice, []int{1, 2, 3}) {
+			t.Errorf("%s: unexpected slice %v", input, slice)
+		}
+
+		var array [3]uint8
+		if err := NewCborReader(data).ReadSliceInto(&array); err != nil {
+			t.Fatalf("%s: ReadSliceInto array failed: %v", input, err)
+		}
+		if array != [3]uint8{1, 2, 3} {
+			t.Errorf("%s: unexpected array %v", input, array)
+		}
+
+		short := [2]int{7, 7}
+		if err := NewCborReader(data).ReadSliceInto(&short); !errors.Is(err, ErrLengthMismatch) {
+			t.Errorf("%s: expected ErrLengthMismatch, got %v", input, err)
+		}
+		if short != [2]int{7, 7} {
+			t.Errorf("%s: expected the array to be left unchanged, got %v", input, short)
+		}
+		long := [4]int{7, 7, 7, 7}
+		if err := NewCborReader(data).ReadSliceInto(&long); !errors.Is(err, ErrLengthMismatch) {
+			t.Errorf("%s: expected ErrLengthMismatch, got %v", input, err)
+		}
+		if long != [4]int{7, 7, 7, 7} {
+			t.Errorf("%s: expected the array to be left unchanged, got %v", input, long)
+		}
+	}
+
+	// an element that fails to decode leaves the earlier ones unassigned too
+	data, _ := hex.DecodeString("9f016161ff")
+	pair := [2]int{7, 7}
+	var mismatch *TypeMismatchError
+	if err := NewCborReader(data).ReadSliceInto(&pair); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError, got %v", err)
+	}
+	if pair != [2]int{7, 7} {
+		t.Errorf("expected the array to be left unchanged, got %v", pair)
+	}
+}
+
+func TestReadSliceIntoErrors(t *testing.T) {
+	data, _ := hex.DecodeString("82016161")
+	var ints []int
+	var mismatch *TypeMismatchError
+	if err := NewCborReader(data).ReadSliceInto(&ints); !errors.As(err, &mismatch) || mismatch.Offset != 2 {
+		t.Errorf("expected TypeMismatchError at offset 2, got %v", err)
+	}
+
+	var m map[string]int
+	for _, target := range []any{ints, &m, nil} {
+		if err := NewCborReader(data).ReadSliceInto(target); !errors.Is(err, ErrInvalidUnmarshalTarget) {
+			t.Errorf("%T: expected ErrInvalidUnmarshalTarget, got %v", target, err)
+		}
+	}
+}
+
+func TestWriteStringMap(t *testing.T) {
+	m := map[string]any{
+		"aa": 1,
+		"b":  []any{map[string]any{"z": 1, "y": 2}},
+	}
+
+	tests := []struct {
+		name string
+		opts []WriterOption
+		hex  string
+	}{
+		{"lax", nil, "a2" + "6162" + "81a2617902617a01" + "626161" + "01"},
+		{"canonical", []WriterOption{WithConformanceMode(ConformanceCanonical)}, "a2" + "6162" + "81a2617902617a01" + "626161" + "01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				w := NewCborWriter(tt.opts...)
+				if err := w.WriteStringMap(m); err != nil {
+					t.Fatalf("WriteStringMap failed: %v", err)
+				}
+				if got := hex.EncodeToString(w.Bytes()); got != tt.hex {
+					t.Fatalf("expected %s, got %s", tt.hex, got)
+				}
+			}
+		})
+	}
+
+	w := NewCborWriter()
+	if err := w.WriteStringMap(nil); err != nil {
+		t.Fatalf("WriteStringMap(nil) failed: %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()); got != "a0" {
+		t.Errorf("expected a0, got %s", got)
+	}
+}
+
+func TestWriteStringMapTypedNestedMaps(t *testing.T) {
+	m := map[string]any{
+		"x": map[string]int{"a": 1, "b": 2, "c": 3, "d": 4},
+		"y": []map[string]any{{"p": 1, "q": 2, "r": 3}},
+	}
+	want := "a2" + "6178" + "a4616101616202616303616404" + "6179" + "81a3617001617102617203"
+
+	for i := 0; i < 50; i++ {
+		w := NewCborWriter()
+		if err := w.WriteStringMap(m); err != nil {
+			t.Fatalf("WriteStringMap failed: %v", err)
+		}
+		if got := hex.EncodeToString(w.Bytes()); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+
+		// sorting stops with the call
+		if w.deterministicMaps {
+			t.Fatal("expected deterministic maps to be turned off again")
+		}
+	}
+}
+
+func TestUnmarshalUndefinedAsNull(t *testing.T) {
+	type target struct {
+		Ptr   *int           `cbor:"ptr"`
+		Map   map[string]int `cbor:"map"`
+		Slice []any          `cbor:"slice"`
+		Any   any            `cbor:"any"`
+	}
+
+	w := NewCborWriter()
+	_ = w.WriteStartMap(4)
+	_ = w.WriteTextString("ptr")
+	_ = w.WriteUndefined()
+	_ = w.WriteTextString("map")
+	_ = w.WriteUndefined()
+	_ = w.WriteTextString("slice")
+	_ = w.WriteStartArray(1)
+	_ = w.WriteUndefined()
+	_ = w.WriteEndArray()
+	_ = w.WriteTextString("any")
+	_ = w.WriteUndefined()
+	_ = w.WriteEndMap()
+	data := w.Bytes()
+
+	one := 1
+	got := target{Ptr: &one, Map: map[string]int{"a": 1}, Any: "set"}
+	if err := Unmarshal(data, &got, WithReaderUndefinedAsNull(true)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := target{Slice: []any{nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	// Without the option undefined stays a simple value and cannot fill a pointer.
+	var plain target
+	var mismatch *TypeMismatchError
+	if err := Unmarshal(data, &plain); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError without the option, got %v", err)
+	}
+	value, err := NewCborReader([]byte{0xf7}).ReadAny()
+	if err != nil || value != SimpleValueUndefined {
+		t.Errorf("expected SimpleValueUndefined without the option, got %v, %v", value, err)
+	}
+}
+
+type optionalInner struct {
+	N int `cbor:"n"`
+}
+
+type optionalRecord struct {
+	Int    *int              `cbor:"int"`
+	Name   **string          `cbor:"name"`
+	Inner  *optionalInner    `cbor:"inner"`
+	Items  []*int            `cbor:"items"`
+	Lookup map[string]*int   `cbor:"lookup"`
+	Any    any               `cbor:"any"`
+	Deep   ***int            `cbor:"deep"`
+	Tags   []string          `cbor:"tags"`
+	Attrs  map[string]string `cbor:"attrs"`
+}
+
+func TestUnmarshalOptionalPointerFields(t *testing.T) {
+	name := "x"
+	pname := &name
+	one := 1
+	pone := &one
+	ppone := &pone
+	full := optionalRecord{
+		Int:    &one,
+		Name:   &pname,
+		Inner:  &optionalInner{N: 3},
+		Items:  []*int{nil, &one},
+		Lookup: map[string]*int{"k": nil},
+		Deep:   &ppone,
+		Tags:   []string{"a"},
+		Attrs:  map[string]string{"k": "v"},
+	}
+
+	data, err := Marshal(full)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got optionalRecord
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, full) {
+		t.Errorf("expected %+v, got %+v", full, got)
+	}
+
+	// Every nil field, including a pointer to a nil pointer, is encoded as null.
+	var nilName *string
+	data, err = Marshal(optionalRecord{Name: &nilName, Any: (*int)(nil)})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	r := NewCborReader(data)
+	count, _ := r.ReadStartMap()
+	for i := 0; i < count; i++ {
+		key, _ := r.ReadTextString()
+		if isNull, err := r.TryReadNull(); err != nil || !isNull {
+			t.Errorf("%s: expected null, got %v", key, err)
+		}
+	}
+
+	// Decoding the nulls into set fields clears them.
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, optionalRecord{}) {
+		t.Errorf("expected all fields cleared, got %+v", got)
+	}
+}
+
+func TestUnmarshalNestedPointer(t *testing.T) {
+	var pp **int
+	if err := Unmarshal([]byte{0x05}, &pp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if pp == nil || *pp == nil || **pp != 5 {
+		t.Fatalf("expected **int 5 to be allocated, got %v", pp)
+	}
+
+	// An existing pointer is reused.
+	target := *pp
+	if err := Unmarshal([]byte{0x07}, &pp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if *pp != target || *target != 7 {
+		t.Errorf("expected existing *int to be reused and set to 7")
+	}
+
+	if err := Unmarshal([]byte{0xf6}, &pp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if pp != nil {
+		t.Errorf("expected null to set **int to nil, got %v", pp)
+	}
+}
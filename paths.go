@@ -0,0 +1,127 @@
+package cbor
+
+import "reflect"
+
+// PathValue is a leaf value together with its location in a nested document. Each path
+// element of type int is an array index; any other comparable value is a map key.
+type PathValue struct {
+	Path  []any
+	Value any
+}
+
+// pathNode is a node in the document tree assembled by WriteFromPaths.
+type pathNode struct {
+	leaf     bool
+	value    any
+	isArray  bool
+	elems    []*pathNode       // array elements, nil for gaps
+	keys     []any             // map keys in first-insertion order
+	children map[any]*pathNode // map values by key
+}
+
+// WriteFromPaths builds the minimal nested structure containing every leaf in paths and
+// encodes it, merging shared prefixes. Arrays are as long as their largest index and gaps
+// are written as null; map keys keep the order in which they were first seen unless the
+// writer sorts them. Leaves are written with WriteAny. It returns ErrInvalidPath if paths
+// is empty, if an index is negative, or if two paths disagree about a node's kind.
+func WriteFromPaths(paths []PathValue, opts ...WriterOption) ([]byte, error) {
+	if len(paths) == 0 {
+		return nil, ErrInvalidPath
+	}
+
+	root := &pathNode{}
+	for _, pv := range paths {
+		if err := root.insert(pv.Path, pv.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	w := NewCborWriter(opts...)
+	if err := root.write(w); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// insert adds value at path below n.
+func (n *pathNode) insert(path []any, value any) error {
+	if len(path) == 0 {
+		if n.leaf || n.isArray || n.children != nil {
+			return ErrInvalidPath
+		}
+		n.leaf = true
+		n.value = value
+		return nil
+	}
+	if n.leaf {
+		return ErrInvalidPath
+	}
+
+	if index, ok := path[0].(int); ok {
+		if index < 0 || n.children != nil {
+			return ErrInvalidPath
+		}
+		n.isArray = true
+		if index >= len(n.elems) {
+			n.elems = append(n.elems, make([]*pathNode, index+1-len(n.elems))...)
+		}
+		if n.elems[index] == nil {
+			n.elems[index] = &pathNode{}
+		}
+		return n.elems[index].insert(path[1:], value)
+	}
+
+	key := path[0]
+	if n.isArray || key == nil || !reflect.TypeOf(key).Comparable() {
+		return ErrInvalidPath
+	}
+	if n.children == nil {
+		n.children = make(map[any]*pathNode)
+	}
+	child, ok := n.children[key]
+	if !ok {
+		child = &pathNode{}
+		n.children[key] = child
+		n.keys = append(n.keys, key)
+	}
+	return child.insert(path[1:], value)
+}
+
+// write encodes the subtree rooted at n.
+func (n *pathNode) write(w *CborWriter) error {
+	switch {
+	case n.leaf:
+		return w.WriteAny(n.value)
+
+	case n.isArray:
+		if err := w.WriteStartArray(len(n.elems)); err != nil {
+			return err
+		}
+		for _, elem := range n.elems {
+			var err error
+			if elem == nil {
+				err = w.WriteNull()
+			} else {
+				err = elem.write(w)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return w.WriteEndArray()
+
+	default:
+		if err := w.WriteStartMap(len(n.keys)); err != nil {
+			return err
+		}
+		for _, key := range n.keys {
+			if err := w.writeMapKey(key); err != nil {
+				return err
+			}
+			if err := n.children[key].write(w); err != nil {
+				return err
+			}
+		}
+		return w.WriteEndMap()
+	}
+}
@@ -0,0 +1,93 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestWriteFromPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []PathValue
+		diag  string
+	}{
+		{
+			name:  "root",
+			paths: []PathValue{{Path: nil, Value: "x"}},
+			diag:  `"x"`,
+		},
+		{
+			name: "shared_prefix",
+			paths: []PathValue{
+				{Path: []any{"user", "name"}, Value: "alice"},
+				{Path: []any{"user", "tags", 1}, Value: "b"},
+				{Path: []any{"user", "tags", 0}, Value: "a"},
+				{Path: []any{"id"}, Value: 7},
+			},
+			diag: `{"user": {"name": "alice", "tags": ["a", "b"]}, "id": 7}`,
+		},
+		{
+			name:  "array_gap",
+			paths: []PathValue{{Path: []any{2, "k"}, Value: true}},
+			diag:  `[null, null, {"k": true}]`,
+		},
+		{
+			name:  "non_string_keys",
+			paths: []PathValue{{Path: []any{uint64(1), int64(-1)}, Value: []byte{0x01}}},
+			diag:  `{1: {-1: h'01'}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := WriteFromPaths(tt.paths)
+			if err != nil {
+				t.Fatalf("WriteFromPaths failed: %v", err)
+			}
+			got, err := Diagnose(data)
+			if err != nil {
+				t.Fatalf("Diagnose failed: %v", err)
+			}
+			if got != tt.diag {
+				t.Errorf("expected %s, got %s", tt.diag, got)
+			}
+		})
+	}
+}
+
+func TestWriteFromPathsCanonical(t *testing.T) {
+	data, err := WriteFromPaths([]PathValue{
+		{Path: []any{"b"}, Value: 2},
+		{Path: []any{"a"}, Value: 1},
+	}, WithConformanceMode(ConformanceCanonical))
+	if err != nil {
+		t.Fatalf("WriteFromPaths failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "a2616101616202" {
+		t.Errorf("expected a2616101616202, got %s", got)
+	}
+}
+
+func TestWriteFromPathsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []PathValue
+	}{
+		{"empty", nil},
+		{"duplicate_leaf", []PathValue{{Path: []any{"a"}, Value: 1}, {Path: []any{"a"}, Value: 2}}},
+		{"leaf_then_container", []PathValue{{Path: []any{"a"}, Value: 1}, {Path: []any{"a", "b"}, Value: 2}}},
+		{"container_then_leaf", []PathValue{{Path: []any{"a", "b"}, Value: 1}, {Path: []any{"a"}, Value: 2}}},
+		{"array_and_map", []PathValue{{Path: []any{0}, Value: 1}, {Path: []any{"k"}, Value: 2}}},
+		{"negative_index", []PathValue{{Path: []any{-1}, Value: 1}}},
+		{"unhashable_key", []PathValue{{Path: []any{[]byte{1}}, Value: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := WriteFromPaths(tt.paths); !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("expected ErrInvalidPath, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package cbor
+
+import "sync"
+
+// maxPooledBufferSize bounds the buffer capacity a released writer may keep, so that one
+// unusually large message does not pin its memory in the pool.
+const maxPooledBufferSize = 64 << 10
+
+var (
+	writerPool = sync.Pool{
+		New: func() any {
+			return NewCborWriter()
+		},
+	}
+	readerPool = sync.Pool{
+		New: func() any {
+			return NewCborReader(nil)
+		},
+	}
+)
+
+// AcquireWriter returns a writer from a shared pool, configured with opts as if by
+// NewCborWriter. Its buffer and nesting stack keep the capacity of earlier uses.
+func AcquireWriter(opts ...WriterOption) *CborWriter {
+	w := writerPool.Get().(*CborWriter)
+	w.applyOptions(opts)
+	return w
+}
+
+// ReleaseWriter resets w and returns it to the pool used by AcquireWriter. The slice
+// returned by w.Bytes() is reused by later writers, so copy it before releasing. w must not
+// be used after release.
+func ReleaseWriter(w *CborWriter) {
+	if w == nil {
+		return
+	}
+	w.Reset()
+	if cap(w.buffer) > maxPooledBufferSize {
+		w.buffer = make([]byte, 0, 256)
+	}
+	w.conformanceMode = ConformanceLax
+	w.maxNestingDepth = 64
+	w.allowMultipleRootValues = false
+	w.deterministicMaps = false
+	w.floatMode = FloatShortest
+	w.dateTimeUTC = false
+	w.enumNames = nil
+	w.templateKeys = nil
+	w.keyTemplate = nil
+	writerPool.Put(w)
+}
+
+// AcquireReader returns a reader over data from a shared pool, configured with opts as if
+// by NewCborReader. Its nesting stack keeps the capacity of earlier uses.
+func AcquireReader(data []byte, opts ...ReaderOption) *CborReader {
+	r := readerPool.Get().(*CborReader)
+	r.data = data
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.skipSelfDescribePrefix()
+	return r
+}
+
+// ReleaseReader restores r to its default configuration, detaches its data and returns it
+// to the pool used by AcquireReader. Slices and strings previously read from r remain valid.
+// r must not be used after release.
+func ReleaseReader(r *CborReader) {
+	if r == nil {
+		return
+	}
+	*r = CborReader{
+		conformanceMode: ConformanceLax,
+		nestingStack:    r.nestingStack[:0],
+		maxNestingDepth: 64,
+	}
+	readerPool.Put(r)
+}
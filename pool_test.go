@@ -0,0 +1,86 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestWriterPool(t *testing.T) {
+	w := AcquireWriter(WithConformanceMode(ConformanceCanonical))
+	if err := w.WriteValue(map[string]int{"b": 2, "a": 1}); err != nil {
+		t.Fatalf("WriteValue failed: %v", err)
+	}
+	expected, _ := hex.DecodeString("a2616101616202")
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("expected %x, got %x", expected, w.Bytes())
+	}
+	ReleaseWriter(w)
+
+	// A reacquired writer starts empty with default options
+	w = AcquireWriter()
+	defer ReleaseWriter(w)
+	if w.Len() != 0 || w.conformanceMode != ConformanceLax || w.keyTemplate != nil {
+		t.Errorf("expected a reset writer, got length %d and mode %d", w.Len(), w.conformanceMode)
+	}
+	if err := w.WriteInt(1); err != nil {
+		t.Fatalf("WriteInt failed: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), []byte{0x01}) {
+		t.Errorf("expected 01, got %x", w.Bytes())
+	}
+}
+
+func BenchmarkWriterPool(b *testing.B) {
+	record := templateRecord{Zeta: 1, Alpha: "a", ID: 7}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := AcquireWriter()
+		if err := w.WriteValue(record); err != nil {
+			b.Fatal(err)
+		}
+		ReleaseWriter(w)
+	}
+}
+
+func TestReaderPool(t *testing.T) {
+	data, _ := hex.DecodeString("8181818101")
+	r := AcquireReader(data, WithReaderMaxNestingDepth(2))
+	if _, err := r.ReadAny(); err == nil {
+		t.Fatal("expected nesting depth error")
+	}
+	stack := cap(r.nestingStack)
+	ReleaseReader(r)
+
+	r = AcquireReader(data)
+	defer ReleaseReader(r)
+	if r.maxNestingDepth != 64 || r.offset != 0 || len(r.nestingStack) != 0 {
+		t.Errorf("expected a reset reader, got depth %d, offset %d", r.maxNestingDepth, r.offset)
+	}
+	if cap(r.nestingStack) < stack {
+		t.Errorf("expected nesting stack capacity %d to be kept, got %d", stack, cap(r.nestingStack))
+	}
+	value, err := r.ReadAny()
+	if err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if _, ok := value.([]any); !ok {
+		t.Errorf("expected array, got %T", value)
+	}
+}
+
+func BenchmarkReaderPool(b *testing.B) {
+	data, err := Marshal(templateRecord{Zeta: 1, Alpha: "a", ID: 7})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var record templateRecord
+		r := AcquireReader(data)
+		if err := r.ReadValue(&record); err != nil {
+			b.Fatal(err)
+		}
+		ReleaseReader(r)
+	}
+}
@@ -0,0 +1,24 @@
+package cbor
+
+// ValueRange is the byte range [Start, End) of an encoded item decoded by ReadAny, together
+// with the ranges of its nested items in encoding order. Array elements appear in order,
+// map keys and values alternate (key at 2*i, value at 2*i+1), and the content of a tag
+// without a dedicated Go mapping is its only child.
+type ValueRange struct {
+	Start    int
+	End      int
+	Children []ValueRange
+}
+
+// WithRangeTracking enables recording of byte ranges during ReadAny and returns r.
+// Tracking is off by default because it allocates a node per decoded item.
+func (r *CborReader) WithRangeTracking() *CborReader {
+	r.trackRanges = true
+	return r
+}
+
+// LastValueRanges returns the range tree of the item decoded by the most recent top-level
+// ReadAny call, or nil if range tracking is disabled or nothing has been decoded.
+func (r *CborReader) LastValueRanges() *ValueRange {
+	return r.lastRanges
+}
@@ -0,0 +1,52 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestRangeTracking(t *testing.T) {
+	// [1, {"a": h'0102'}, 1(2)]
+	data, _ := hex.DecodeString("8301a16161420102c102")
+	r := NewCborReader(data).WithRangeTracking()
+	if _, err := r.ReadAny(); err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+
+	want := &ValueRange{Start: 0, End: 10, Children: []ValueRange{
+		{Start: 1, End: 2},
+		{Start: 2, End: 8, Children: []ValueRange{
+			{Start: 3, End: 5},
+			{Start: 5, End: 8},
+		}},
+		{Start: 8, End: 10, Children: []ValueRange{
+			{Start: 9, End: 10},
+		}},
+	}}
+	if got := r.LastValueRanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRangeTrackingDisabled(t *testing.T) {
+	r := NewCborReader([]byte{0x82, 0x01, 0x02})
+	if _, err := r.ReadAny(); err != nil {
+		t.Fatalf("ReadAny failed: %v", err)
+	}
+	if got := r.LastValueRanges(); got != nil {
+		t.Errorf("expected no ranges, got %+v", got)
+	}
+}
+
+func TestRangeTrackingSequence(t *testing.T) {
+	r := NewCborReader([]byte{0x01, 0x62, 0x68, 0x69}, WithReaderAllowMultipleRootValues(true)).WithRangeTracking()
+	for _, want := range []ValueRange{{Start: 0, End: 1}, {Start: 1, End: 4}} {
+		if _, err := r.ReadAny(); err != nil {
+			t.Fatalf("ReadAny failed: %v", err)
+		}
+		if got := r.LastValueRanges(); got == nil || !reflect.DeepEqual(*got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	}
+}
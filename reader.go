@@ -3,8 +3,12 @@ package cbor
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"math"
 	"math/big"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 	"unicode/utf8"
 )
@@ -18,7 +22,26 @@ type CborReader struct {
 	maxNestingDepth         int
 	cachedState             CborReaderState
 	stateComputed           bool
+	headerCached            bool // headerMt and headerAi hold the initial byte at headerOffset
+	headerOffset            int
+	headerMt                MajorType
+	headerAi                byte
+	headerDecodes           int // initial bytes initialByteInfo decoded again, for tests
 	allowMultipleRootValues bool
+	maxByteStringLength     int
+	maxTextStringLength     int
+	maxArrayLength          int
+	maxMapLength            int
+	simpleValueHandler      func(SimpleValue) (any, error)
+	undefinedAsNull         bool
+	enumValues              map[reflect.Type]map[string]int64
+	trackRanges             bool
+	rangeParent             *ValueRange // range of the ReadAny item being decoded
+	anyTagDepth             int         // tags whose content ReadAny is decoding
+	lastRanges              *ValueRange
+	stripSelfDescribe       bool
+	requireTextKeys         bool
+	dateTimeFormats         []string
 }
 
 // readerNestingInfo tracks the state of nested containers during reading.
@@ -55,6 +78,80 @@ func WithReaderAllowMultipleRootValues(allow bool) ReaderOption {
 	}
 }
 
+// WithReaderMaxByteStringLength limits the length of byte strings the reader accepts.
+// Longer byte strings return ErrValueTooLarge before any allocation. Zero means no limit.
+func WithReaderMaxByteStringLength(n int) ReaderOption {
+	return func(r *CborReader) {
+		r.maxByteStringLength = n
+	}
+}
+
+// WithReaderMaxTextStringLength limits the length in bytes of text strings the reader accepts.
+// Longer text strings return ErrValueTooLarge before any allocation. Zero means no limit.
+func WithReaderMaxTextStringLength(n int) ReaderOption {
+	return func(r *CborReader) {
+		r.maxTextStringLength = n
+	}
+}
+
+// WithReaderMaxArrayLength limits the number of elements in arrays the reader accepts.
+// Definite-length arrays are checked against their declared length and indefinite-length
+// arrays as elements are read. Exceeding the limit returns ErrValueTooLarge. Zero means no limit.
+func WithReaderMaxArrayLength(n int) ReaderOption {
+	return func(r *CborReader) {
+		r.maxArrayLength = n
+	}
+}
+
+// WithReaderMaxMapLength limits the number of key/value pairs in maps the reader accepts.
+// Definite-length maps are checked against their declared length and indefinite-length
+// maps as pairs are read. Exceeding the limit returns ErrValueTooLarge. Zero means no limit.
+func WithReaderMaxMapLength(n int) ReaderOption {
+	return func(r *CborReader) {
+		r.maxMapLength = n
+	}
+}
+
+// WithReaderSimpleValueHandler sets a function that ReadAny consults for simple values
+// other than false, true, null and undefined. Its result becomes the decoded value.
+func WithReaderSimpleValueHandler(fn func(SimpleValue) (any, error)) ReaderOption {
+	return func(r *CborReader) {
+		r.simpleValueHandler = fn
+	}
+}
+
+// WithReaderUndefinedAsNull makes TryReadNull, ReadAny, ReadValue and the other reflection
+// decoders treat undefined (0xf7) like null. ReadNull and ReadUndefined are unaffected.
+func WithReaderUndefinedAsNull(enable bool) ReaderOption {
+	return func(r *CborReader) {
+		r.undefinedAsNull = enable
+	}
+}
+
+// WithReaderRequireTextKeys rejects map keys that are not text strings with ErrNonTextKey
+// as each key is reached, as required by JSON-compatible profiles.
+func WithReaderRequireTextKeys(require bool) ReaderOption {
+	return func(r *CborReader) {
+		r.requireTextKeys = require
+	}
+}
+
+// WithReaderStripSelfDescribe consumes self-described CBOR tags (55799, the d9d9f7 magic)
+// at the start of the data, so the first read sees the tagged item itself.
+func WithReaderStripSelfDescribe(strip bool) ReaderOption {
+	return func(r *CborReader) {
+		r.stripSelfDescribe = strip
+	}
+}
+
+// WithReaderDateTimeFormats sets the layouts ReadDateTimeString tries, in order, for tag 0
+// strings; the first successful parse wins. The default is time.RFC3339Nano alone.
+func WithReaderDateTimeFormats(layouts ...string) ReaderOption {
+	return func(r *CborReader) {
+		r.dateTimeFormats = layouts
+	}
+}
+
 // NewCborReader creates a new CborReader for the given data.
 func NewCborReader(data []byte, opts ...ReaderOption) *CborReader {
 	r := &CborReader{
@@ -68,16 +165,42 @@ func NewCborReader(data []byte, opts ...ReaderOption) *CborReader {
 	for _, opt := range opts {
 		opt(r)
 	}
+	r.skipSelfDescribePrefix()
 
 	return r
 }
 
+// skipSelfDescribePrefix moves past leading self-described CBOR tags when stripping is
+// enabled. It must only be called at the start of the data.
+func (r *CborReader) skipSelfDescribePrefix() {
+	if !r.stripSelfDescribe {
+		return
+	}
+	for bytes.HasPrefix(r.data[r.offset:], selfDescribedPrefix) {
+		r.offset += len(selfDescribedPrefix)
+	}
+}
+
 // Reset resets the reader to the beginning.
 func (r *CborReader) Reset() {
 	r.offset = 0
 	r.nestingStack = r.nestingStack[:0]
 	r.cachedState = StateUndefined
 	r.stateComputed = false
+	r.headerCached = false
+	r.anyTagDepth = 0
+	r.skipSelfDescribePrefix()
+}
+
+// Clone returns an independent reader at the same position with the same options. The
+// clone shares the underlying data, which is never modified, but has its own offset and
+// nesting state, so either reader can advance without affecting the other. The data must
+// stay unchanged for as long as the clone is used.
+func (r *CborReader) Clone() *CborReader {
+	c := *r
+	c.nestingStack = append(make([]readerNestingInfo, 0, cap(r.nestingStack)), r.nestingStack...)
+	c.rangeParent = nil
+	return &c
 }
 
 // ResetWithData resets the reader with new data.
@@ -86,6 +209,71 @@ func (r *CborReader) ResetWithData(data []byte) {
 	r.Reset()
 }
 
+// ReadFrom reads src until EOF into a new buffer and resets the reader to decode it,
+// implementing io.ReaderFrom. It returns the number of bytes read. On a read error other
+// than io.EOF the reader keeps its previous data.
+func (r *CborReader) ReadFrom(src io.Reader) (int64, error) {
+	buf := make([]byte, 0, 512)
+	for {
+		if len(buf) == cap(buf) {
+			buf = slices.Grow(buf, cap(buf))
+		}
+		n, err := src.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return int64(len(buf)), err
+		}
+	}
+
+	r.ResetWithData(buf)
+	return int64(len(buf)), nil
+}
+
+// ReaderBookmark captures a reader position so it can be restored later.
+// It holds its own copy of the nesting state and remains valid while the reader advances.
+type ReaderBookmark struct {
+	offset        int
+	nestingStack  []readerNestingInfo
+	cachedState   CborReaderState
+	stateComputed bool
+}
+
+// Bookmark captures the current reader position.
+func (r *CborReader) Bookmark() ReaderBookmark {
+	stack := make([]readerNestingInfo, len(r.nestingStack))
+	copy(stack, r.nestingStack)
+	return ReaderBookmark{
+		offset:        r.offset,
+		nestingStack:  stack,
+		cachedState:   r.cachedState,
+		stateComputed: r.stateComputed,
+	}
+}
+
+// Restore rewinds the reader to a position captured by Bookmark.
+func (r *CborReader) Restore(b ReaderBookmark) {
+	r.offset = b.offset
+	r.nestingStack = append(r.nestingStack[:0], b.nestingStack...)
+	r.cachedState = b.cachedState
+	r.stateComputed = b.stateComputed
+}
+
+// Finish checks that the reader has consumed complete input. It returns
+// ErrIncompleteContainer if a container is still open and, unless multiple root values are
+// allowed, ErrNotAtEnd if bytes remain after the root value.
+func (r *CborReader) Finish() error {
+	if len(r.nestingStack) > 0 {
+		return NewCborError(ErrIncompleteContainer, r.offset, "Finish")
+	}
+	if !r.allowMultipleRootValues && r.offset < len(r.data) {
+		return NewCborError(ErrNotAtEnd, r.offset, "Finish")
+	}
+	return nil
+}
+
 // BytesRemaining returns the number of bytes remaining to be read.
 func (r *CborReader) BytesRemaining() int {
 	return len(r.data) - r.offset
@@ -101,6 +289,23 @@ func (r *CborReader) NestingDepth() int {
 	return len(r.nestingStack)
 }
 
+// CurrentContainer describes the innermost open container. mt is its major type, isMap
+// reports whether it is a map and expectingKey whether the next item is a map key.
+// remaining counts the items (map entries for maps) not yet fully read, or -1 for an
+// indefinite-length container. ok is false at the root level.
+func (r *CborReader) CurrentContainer() (mt MajorType, isMap bool, expectingKey bool, remaining int64, ok bool) {
+	if len(r.nestingStack) == 0 {
+		return 0, false, false, 0, false
+	}
+
+	info := r.nestingStack[len(r.nestingStack)-1]
+	remaining = -1
+	if !info.isIndefinite {
+		remaining = info.definiteLength - info.itemsRead
+	}
+	return info.majorType, info.isMap, info.isMap && !info.keyRead, remaining, true
+}
+
 // invalidateState clears the cached state.
 func (r *CborReader) invalidateState() {
 	r.stateComputed = false
@@ -122,6 +327,19 @@ func (r *CborReader) PeekState() (CborReaderState, error) {
 	return state, nil
 }
 
+// ReadExpecting checks that the next item has the expected state without consuming it.
+// It returns a TypeMismatchError if the state differs.
+func (r *CborReader) ReadExpecting(state CborReaderState) error {
+	actual, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+	if actual != state {
+		return &TypeMismatchError{Expected: state, Actual: actual, Offset: r.offset}
+	}
+	return nil
+}
+
 // computeState determines the current reader state.
 func (r *CborReader) computeState() (CborReaderState, error) {
 	// Check if we're at the end of a container
@@ -138,22 +356,45 @@ func (r *CborReader) computeState() (CborReaderState, error) {
 
 	if r.offset >= len(r.data) {
 		if len(r.nestingStack) > 0 {
-			return StateUndefined, ErrUnexpectedEndOfData
+			return StateUndefined, NewCborError(ErrUnexpectedEndOfData, r.offset, "PeekState")
 		}
 		return StateFinished, nil
 	}
 
 	initialByte := r.data[r.offset]
+	mt, ai := decodeInitialByte(initialByte)
+
+	// The read that follows decodes its header from these values instead of the data.
+	r.headerCached = true
+	r.headerOffset = r.offset
+	r.headerMt = mt
+	r.headerAi = ai
+
+	// Enforce element limits on indefinite-length containers and the key type before the
+	// next item
+	if len(r.nestingStack) > 0 && initialByte != breakByte {
+		info := &r.nestingStack[len(r.nestingStack)-1]
+		if info.isIndefinite && !info.keyRead {
+			if limit := r.containerLengthLimit(info.majorType); limit > 0 && info.itemsRead >= int64(limit) {
+				return StateUndefined, NewCborError(ErrValueTooLarge, r.offset, "PeekState")
+			}
+		}
+		if r.requireTextKeys && info.isMap && !info.keyRead {
+			if mt != MajorTypeTextString {
+				return StateUndefined, NewCborError(ErrNonTextKey, r.offset, "PeekState")
+			}
+		}
+	}
 
 	// Check for break byte
 	if initialByte == breakByte {
 		if len(r.nestingStack) == 0 {
-			return StateUndefined, ErrUnexpectedBreak
+			return StateUndefined, NewCborError(ErrUnexpectedBreak, r.offset, "PeekState")
 		}
 
 		info := &r.nestingStack[len(r.nestingStack)-1]
 		if !info.isIndefinite {
-			return StateUndefined, ErrUnexpectedBreak
+			return StateUndefined, NewCborError(ErrUnexpectedBreak, r.offset, "PeekState")
 		}
 
 		switch info.majorType {
@@ -161,7 +402,7 @@ func (r *CborReader) computeState() (CborReaderState, error) {
 			return StateEndArray, nil
 		case MajorTypeMap:
 			if info.keyRead {
-				return StateUndefined, ErrIncompleteContainer
+				return StateUndefined, NewCborError(ErrIncompleteContainer, r.offset, "PeekState")
 			}
 			return StateEndMap, nil
 		case MajorTypeByteString:
@@ -171,7 +412,14 @@ func (r *CborReader) computeState() (CborReaderState, error) {
 		}
 	}
 
-	mt, ai := decodeInitialByte(initialByte)
+	// Additional information 28-30 is reserved in every major type, and 31 has no
+	// meaning for integers and tags (RFC 8949 Section 3)
+	if ai >= 28 && ai <= 30 {
+		return StateUndefined, NewCborError(ErrInvalidCbor, r.offset, "reserved additional information")
+	}
+	if ai == byte(AdditionalInfoIndefiniteLength) && (mt == MajorTypeUnsignedInteger || mt == MajorTypeNegativeInteger || mt == MajorTypeTag) {
+		return StateUndefined, NewCborError(ErrInvalidCbor, r.offset, "indefinite length for integer or tag")
+	}
 
 	switch mt {
 	case MajorTypeUnsignedInteger:
@@ -211,27 +459,35 @@ func (r *CborReader) computeState() (CborReaderState, error) {
 		case 27:
 			return StateDoublePrecisionFloat, nil
 		default:
-			if ai < 24 {
-				return StateSimpleValue, nil
-			}
-			return StateUndefined, ErrInvalidSimpleValue
+			return StateSimpleValue, nil
 		}
 	}
 
-	return StateUndefined, ErrInvalidMajorType
+	return StateUndefined, NewCborError(ErrInvalidMajorType, r.offset, "PeekState")
+}
+
+// initialByteInfo returns the major type and additional information of the item at the
+// current offset, reusing the values decoded by the last state computation when they are
+// for this offset. The offset must be within the data.
+func (r *CborReader) initialByteInfo() (MajorType, byte) {
+	if r.headerCached && r.headerOffset == r.offset {
+		return r.headerMt, r.headerAi
+	}
+	r.headerDecodes++
+	return decodeInitialByte(r.data[r.offset])
 }
 
-// readInitialByte reads the initial byte and returns the additional information value.
+// readArgumentValue reads the item header and returns its argument value.
 func (r *CborReader) readArgumentValue(mt MajorType) (uint64, error) {
+	start := r.offset
 	if r.offset >= len(r.data) {
-		return 0, ErrUnexpectedEndOfData
+		return 0, NewCborError(ErrUnexpectedEndOfData, start, "read item header")
 	}
 
-	initialByte := r.data[r.offset]
-	actualMt, ai := decodeInitialByte(initialByte)
+	actualMt, ai := r.initialByteInfo()
 
 	if actualMt != mt {
-		return 0, &TypeMismatchError{Expected: CborReaderState(mt), Actual: CborReaderState(actualMt)}
+		return 0, &TypeMismatchError{Expected: CborReaderState(mt), Actual: CborReaderState(actualMt), Offset: r.offset}
 	}
 
 	r.offset++
@@ -241,56 +497,56 @@ func (r *CborReader) readArgumentValue(mt MajorType) (uint64, error) {
 		return uint64(ai), nil
 	case ai == 24:
 		if r.offset >= len(r.data) {
-			return 0, ErrUnexpectedEndOfData
+			return 0, NewCborError(ErrUnexpectedEndOfData, start, "read item header")
 		}
 		val := r.data[r.offset]
 		r.offset++
 
 		// Canonical check: value must be >= 24
 		if r.conformanceMode >= ConformanceStrict && val < 24 {
-			return 0, ErrNonCanonical
+			return 0, NewCborError(ErrNonCanonical, start, "read item header")
 		}
 		return uint64(val), nil
 	case ai == 25:
 		if r.offset+2 > len(r.data) {
-			return 0, ErrUnexpectedEndOfData
+			return 0, NewCborError(ErrUnexpectedEndOfData, start, "read item header")
 		}
 		val := binary.BigEndian.Uint16(r.data[r.offset:])
 		r.offset += 2
 
 		// Canonical check: value must be > 255
 		if r.conformanceMode >= ConformanceStrict && val <= 0xFF {
-			return 0, ErrNonCanonical
+			return 0, NewCborError(ErrNonCanonical, start, "read item header")
 		}
 		return uint64(val), nil
 	case ai == 26:
 		if r.offset+4 > len(r.data) {
-			return 0, ErrUnexpectedEndOfData
+			return 0, NewCborError(ErrUnexpectedEndOfData, start, "read item header")
 		}
 		val := binary.BigEndian.Uint32(r.data[r.offset:])
 		r.offset += 4
 
 		// Canonical check: value must be > 65535
 		if r.conformanceMode >= ConformanceStrict && val <= 0xFFFF {
-			return 0, ErrNonCanonical
+			return 0, NewCborError(ErrNonCanonical, start, "read item header")
 		}
 		return uint64(val), nil
 	case ai == 27:
 		if r.offset+8 > len(r.data) {
-			return 0, ErrUnexpectedEndOfData
+			return 0, NewCborError(ErrUnexpectedEndOfData, start, "read item header")
 		}
 		val := binary.BigEndian.Uint64(r.data[r.offset:])
 		r.offset += 8
 
 		// Canonical check: value must be > 4294967295
 		if r.conformanceMode >= ConformanceStrict && val <= 0xFFFFFFFF {
-			return 0, ErrNonCanonical
+			return 0, NewCborError(ErrNonCanonical, start, "read item header")
 		}
 		return uint64(val), nil
 	case ai == 31:
 		return 0, nil // Indefinite length
 	default:
-		return 0, ErrInvalidCbor
+		return 0, NewCborError(ErrInvalidCbor, start, "read item header")
 	}
 }
 
@@ -316,14 +572,47 @@ func (r *CborReader) advanceContainer() {
 	r.invalidateState()
 }
 
+// peekSmallInt returns the initial byte of an integer of major type 0 or 1 whose value is
+// held in the additional information, when reading it needs none of the checks done by
+// computeState. Callers fall back to PeekState when ok is false.
+func (r *CborReader) peekSmallInt() (b byte, ok bool) {
+	if r.offset >= len(r.data) {
+		return 0, false
+	}
+	b = r.data[r.offset]
+	if b >= 0x40 || b&0x1f >= 24 {
+		return 0, false
+	}
+	if r.stateComputed {
+		return b, r.cachedState == StateUnsignedInteger || r.cachedState == StateNegativeInteger
+	}
+	if n := len(r.nestingStack); n > 0 {
+		info := &r.nestingStack[n-1]
+		if !info.isIndefinite && info.itemsRead >= info.definiteLength {
+			return 0, false
+		}
+		if !info.keyRead && ((info.isMap && r.requireTextKeys) || (info.isIndefinite && r.containerLengthLimit(info.majorType) > 0)) {
+			return 0, false
+		}
+	}
+	return b, true
+}
+
 // ReadUint64 reads an unsigned 64-bit integer.
 func (r *CborReader) ReadUint64() (uint64, error) {
+	if b, ok := r.peekSmallInt(); ok && b < 0x18 {
+		r.offset++
+		r.invalidateState()
+		r.advanceContainer()
+		return uint64(b), nil
+	}
+
 	state, err := r.PeekState()
 	if err != nil {
 		return 0, err
 	}
 	if state != StateUnsignedInteger {
-		return 0, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
@@ -336,13 +625,26 @@ func (r *CborReader) ReadUint64() (uint64, error) {
 	return val, nil
 }
 
-// ReadInt64 reads a signed 64-bit integer (can be positive or negative).
+// ReadInt64 reads a signed 64-bit integer (can be positive or negative). Values outside
+// the int64 range return ErrOverflow without consuming the item, so ReadBigInt or
+// ReadIntegerValue can read it instead.
 func (r *CborReader) ReadInt64() (int64, error) {
+	if b, ok := r.peekSmallInt(); ok {
+		r.offset++
+		r.invalidateState()
+		r.advanceContainer()
+		if b < 0x18 {
+			return int64(b), nil
+		}
+		return -1 - int64(b&0x1f), nil
+	}
+
 	state, err := r.PeekState()
 	if err != nil {
 		return 0, err
 	}
 
+	start := r.offset
 	r.invalidateState()
 
 	switch state {
@@ -352,7 +654,8 @@ func (r *CborReader) ReadInt64() (int64, error) {
 			return 0, err
 		}
 		if val > math.MaxInt64 {
-			return 0, ErrOverflow
+			r.offset = start
+			return 0, NewCborError(ErrOverflow, r.offset, "ReadInt64")
 		}
 		r.advanceContainer()
 		return int64(val), nil
@@ -364,14 +667,52 @@ func (r *CborReader) ReadInt64() (int64, error) {
 		}
 		// CBOR negative integers are encoded as -1 - n
 		if val > math.MaxInt64 {
-			return 0, ErrOverflow
+			r.offset = start
+			return 0, NewCborError(ErrOverflow, r.offset, "ReadInt64")
 		}
 		r.advanceContainer()
 		return -1 - int64(val), nil
 
 	default:
-		return 0, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state, Offset: r.offset}
+	}
+}
+
+// ReadIntegerValue reads an integer of major type 0 or 1 as an int64 when it fits and as a
+// *big.Int otherwise, covering the full range from -2^64 to 2^64-1.
+func (r *CborReader) ReadIntegerValue() (any, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return nil, err
+	}
+	if state != StateUnsignedInteger && state != StateNegativeInteger {
+		return nil, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state, Offset: r.offset}
+	}
+
+	mt := MajorTypeUnsignedInteger
+	if state == StateNegativeInteger {
+		mt = MajorTypeNegativeInteger
 	}
+	r.invalidateState()
+	raw, err := r.readArgumentValue(mt)
+	if err != nil {
+		return nil, err
+	}
+	r.advanceContainer()
+
+	if raw <= math.MaxInt64 {
+		if mt == MajorTypeNegativeInteger {
+			// CBOR negative integers are encoded as -1 - n
+			return -1 - int64(raw), nil
+		}
+		return int64(raw), nil
+	}
+	result := new(big.Int).SetUint64(raw)
+	if mt == MajorTypeNegativeInteger {
+		result.Add(result, big.NewInt(1))
+		result.Neg(result)
+	}
+	return result, nil
 }
 
 // ReadInt32 reads a signed 32-bit integer.
@@ -381,7 +722,7 @@ func (r *CborReader) ReadInt32() (int32, error) {
 		return 0, err
 	}
 	if val < math.MinInt32 || val > math.MaxInt32 {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadInt32")
 	}
 	return int32(val), nil
 }
@@ -393,7 +734,7 @@ func (r *CborReader) ReadUint32() (uint32, error) {
 		return 0, err
 	}
 	if val > math.MaxUint32 {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadUint32")
 	}
 	return uint32(val), nil
 }
@@ -405,7 +746,7 @@ func (r *CborReader) ReadInt16() (int16, error) {
 		return 0, err
 	}
 	if val < math.MinInt16 || val > math.MaxInt16 {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadInt16")
 	}
 	return int16(val), nil
 }
@@ -417,7 +758,7 @@ func (r *CborReader) ReadUint16() (uint16, error) {
 		return 0, err
 	}
 	if val > math.MaxUint16 {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadUint16")
 	}
 	return uint16(val), nil
 }
@@ -429,7 +770,7 @@ func (r *CborReader) ReadInt8() (int8, error) {
 		return 0, err
 	}
 	if val < math.MinInt8 || val > math.MaxInt8 {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadInt8")
 	}
 	return int8(val), nil
 }
@@ -441,7 +782,7 @@ func (r *CborReader) ReadUint8() (uint8, error) {
 		return 0, err
 	}
 	if val > math.MaxUint8 {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadUint8")
 	}
 	return uint8(val), nil
 }
@@ -454,46 +795,31 @@ func (r *CborReader) ReadInt() (int, error) {
 	}
 	// Check for overflow on 32-bit systems
 	if val < math.MinInt || val > math.MaxInt {
-		return 0, ErrOverflow
+		return 0, NewCborError(ErrOverflow, r.offset, "ReadInt")
 	}
 	return int(val), nil
 }
 
 // ReadBigInt reads an integer as a big.Int, handling bignums if tagged.
 func (r *CborReader) ReadBigInt() (*big.Int, error) {
+	if err := r.skipSelfDescribedTags(); err != nil {
+		return nil, err
+	}
 	state, err := r.PeekState()
 	if err != nil {
 		return nil, err
 	}
 
 	switch state {
-	case StateUnsignedInteger:
-		val, err := r.ReadUint64()
+	case StateUnsignedInteger, StateNegativeInteger:
+		value, err := r.ReadIntegerValue()
 		if err != nil {
 			return nil, err
 		}
-		return new(big.Int).SetUint64(val), nil
-
-	case StateNegativeInteger:
-		val, err := r.ReadInt64()
-		if err != nil {
-			// Might be a bignum that overflows int64
-			r.stateComputed = true
-			r.cachedState = StateNegativeInteger
-			// Read as raw negative value
-			r.invalidateState()
-			raw, err2 := r.readArgumentValue(MajorTypeNegativeInteger)
-			if err2 != nil {
-				return nil, err2
-			}
-			r.advanceContainer()
-			// -1 - raw
-			result := new(big.Int).SetUint64(raw)
-			result.Add(result, big.NewInt(1))
-			result.Neg(result)
-			return result, nil
+		if v, ok := value.(int64); ok {
+			return big.NewInt(v), nil
 		}
-		return big.NewInt(val), nil
+		return value.(*big.Int), nil
 
 	case StateTag:
 		tag, err := r.ReadTag()
@@ -521,12 +847,93 @@ func (r *CborReader) ReadBigInt() (*big.Int, error) {
 			return result, nil
 
 		default:
-			return nil, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: StateTag}
+			return nil, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: StateTag, Offset: r.offset}
 		}
 
 	default:
-		return nil, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state}
+		return nil, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state, Offset: r.offset}
+	}
+}
+
+// ReadBigIntChecked reads an integer like ReadBigInt and additionally reports whether a
+// tag 2/3 bignum byte string was minimally encoded, i.e. has no leading zero bytes.
+// Plain integers always report true.
+func (r *CborReader) ReadBigIntChecked() (*big.Int, bool, error) {
+	if err := r.skipSelfDescribedTags(); err != nil {
+		return nil, false, err
+	}
+	state, err := r.PeekState()
+	if err != nil {
+		return nil, false, err
+	}
+	if state != StateTag {
+		value, err := r.ReadBigInt()
+		return value, err == nil, err
+	}
+
+	tag, err := r.PeekTag()
+	if err != nil {
+		return nil, false, err
 	}
+	if tag != TagUnsignedBignum && tag != TagNegativeBignum {
+		return nil, false, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: StateTag, Offset: r.offset}
+	}
+	if _, err := r.ReadTag(); err != nil {
+		return nil, false, err
+	}
+	data, err := r.ReadByteString()
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := new(big.Int).SetBytes(data)
+	if tag == TagNegativeBignum {
+		// -1 - n
+		result.Add(result, big.NewInt(1))
+		result.Neg(result)
+	}
+	return result, len(data) == 0 || data[0] != 0, nil
+}
+
+// ReadRat reads a rational number (tag 30) encoded as a [numerator, denominator] array.
+func (r *CborReader) ReadRat() (*big.Rat, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return nil, err
+	}
+	if tag != TagRationalNumber {
+		return nil, NewCborError(ErrInvalidCbor, r.offset, "expected rational number tag")
+	}
+
+	start := r.offset
+	length, err := r.ReadStartArray()
+	if err != nil {
+		return nil, err
+	}
+	if length != 2 {
+		return nil, NewCborError(ErrInvalidCbor, start, "rational number must be a two-element array")
+	}
+
+	num, err := r.ReadBigInt()
+	if err != nil {
+		return nil, err
+	}
+	denomOffset := r.offset
+	denom, err := r.ReadBigInt()
+	if err != nil {
+		return nil, err
+	}
+	switch denom.Sign() {
+	case 0:
+		return nil, NewCborError(ErrInvalidCbor, denomOffset, "rational number has zero denominator")
+	case -1:
+		return nil, NewCborError(ErrInvalidCbor, denomOffset, "rational number has negative denominator")
+	}
+
+	if err := r.ReadEndArray(); err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
 }
 
 // ReadByteString reads a byte string.
@@ -541,7 +948,7 @@ func (r *CborReader) ReadByteString() ([]byte, error) {
 	}
 
 	if state != StateByteString {
-		return nil, &TypeMismatchError{Expected: StateByteString, Actual: state}
+		return nil, &TypeMismatchError{Expected: StateByteString, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
@@ -550,8 +957,8 @@ func (r *CborReader) ReadByteString() ([]byte, error) {
 		return nil, err
 	}
 
-	if r.offset+int(length) > len(r.data) {
-		return nil, ErrUnexpectedEndOfData
+	if err := r.checkStringLength(MajorTypeByteString, length); err != nil {
+		return nil, err
 	}
 
 	result := make([]byte, length)
@@ -561,64 +968,213 @@ func (r *CborReader) ReadByteString() ([]byte, error) {
 	return result, nil
 }
 
+// stringLengthLimit returns the configured length limit for byte or text strings.
+func (r *CborReader) stringLengthLimit(mt MajorType) int {
+	if mt == MajorTypeTextString {
+		return r.maxTextStringLength
+	}
+	return r.maxByteStringLength
+}
+
+// checkStringLength validates a declared string length against the configured limit
+// and the remaining data. It must run before the string content is allocated.
+func (r *CborReader) checkStringLength(mt MajorType, length uint64) error {
+	if limit := r.stringLengthLimit(mt); limit > 0 && length > uint64(limit) {
+		return NewCborError(ErrValueTooLarge, r.offset, "read string length")
+	}
+	if length > uint64(len(r.data)-r.offset) {
+		return NewCborError(ErrUnexpectedEndOfData, r.offset, "read string length")
+	}
+	return nil
+}
+
 // readIndefiniteByteString reads an indefinite-length byte string.
 func (r *CborReader) readIndefiniteByteString() ([]byte, error) {
 	if r.conformanceMode >= ConformanceCanonical {
-		return nil, ErrIndefiniteLengthNotAllowed
+		return nil, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadByteString")
+	}
+
+	// Non-nil even without chunks, matching an empty definite-length string
+	result := []byte{}
+
+	err := r.readIndefiniteChunks(MajorTypeByteString, func(chunk []byte) error {
+		result = append(result, chunk...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.advanceContainer()
+	return result, nil
+}
+
+// ReadByteStringStream reads the next byte string and returns its content as an io.Reader
+// instead of a single slice. The stream reads directly from the reader's data: a definite
+// string from its window of the data and an indefinite-length one chunk by chunk, so no
+// content is copied until it is read. The headers are validated and the reader advances past
+// the whole string before this returns. The data must stay unchanged while the stream is used.
+func (r *CborReader) ReadByteStringStream() (io.Reader, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return nil, err
+	}
+
+	switch state {
+	case StateByteString:
+		r.invalidateState()
+		length, err := r.readArgumentValue(MajorTypeByteString)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.checkStringLength(MajorTypeByteString, length); err != nil {
+			return nil, err
+		}
+		content := r.data[r.offset : r.offset+int(length)]
+		r.offset += int(length)
+		r.advanceContainer()
+		return bytes.NewReader(content), nil
+
+	case StateStartIndefiniteLengthByteString:
+		if r.conformanceMode >= ConformanceCanonical {
+			return nil, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadByteStringStream")
+		}
+		var chunks []io.Reader
+		err := r.readIndefiniteChunks(MajorTypeByteString, func(chunk []byte) error {
+			chunks = append(chunks, bytes.NewReader(chunk))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.advanceContainer()
+		return io.MultiReader(chunks...), nil
+
+	default:
+		return nil, &TypeMismatchError{Expected: StateByteString, Actual: state, Offset: r.offset}
 	}
+}
 
+// readIndefiniteChunks consumes the initial byte, the definite-length chunks and the break
+// of an indefinite-length string, passing the content of each chunk to fn.
+// It does not advance the enclosing container.
+func (r *CborReader) readIndefiniteChunks(mt MajorType, fn func(chunk []byte) error) error {
 	// Skip the initial byte
 	r.offset++
 	r.invalidateState()
 
-	var result bytes.Buffer
+	limit := r.stringLengthLimit(mt)
+	total := 0
 
 	for {
 		if r.offset >= len(r.data) {
-			return nil, ErrUnexpectedEndOfData
+			return NewCborError(ErrUnexpectedEndOfData, r.offset, "read indefinite-length string")
 		}
 
 		if r.data[r.offset] == breakByte {
 			r.offset++
-			break
+			return nil
 		}
 
-		// Read a definite-length byte string chunk
-		mt, _ := decodeInitialByte(r.data[r.offset])
-		if mt != MajorTypeByteString {
-			return nil, ErrInvalidCbor
+		// Read a definite-length chunk of the same major type
+		chunkMt, ai := decodeInitialByte(r.data[r.offset])
+		if chunkMt != mt {
+			return NewCborError(ErrInvalidIndefiniteChunk, r.offset, "read indefinite-length string")
+		}
+		if ai == byte(AdditionalInfoIndefiniteLength) {
+			return NewCborError(ErrInvalidCbor, r.offset, "nested indefinite-length string chunk")
 		}
 
-		length, err := r.readArgumentValue(MajorTypeByteString)
+		length, err := r.readArgumentValue(mt)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if r.offset+int(length) > len(r.data) {
-			return nil, ErrUnexpectedEndOfData
+		if err := r.checkStringLength(mt, length); err != nil {
+			return err
+		}
+		total += int(length)
+		if limit > 0 && total > limit {
+			return NewCborError(ErrValueTooLarge, r.offset, "read indefinite-length string")
 		}
 
-		result.Write(r.data[r.offset : r.offset+int(length)])
+		if err := fn(r.data[r.offset : r.offset+int(length)]); err != nil {
+			return err
+		}
 		r.offset += int(length)
 	}
-
-	r.advanceContainer()
-	return result.Bytes(), nil
 }
 
-// ReadTextString reads a UTF-8 text string.
-func (r *CborReader) ReadTextString() (string, error) {
+// ReadByteStringInto copies the next byte string into dst and returns the number of bytes written.
+// Both definite and indefinite-length byte strings are supported. If dst is too small,
+// nothing is consumed and the required length is returned together with ErrBufferTooSmall.
+func (r *CborReader) ReadByteStringInto(dst []byte) (int, error) {
 	state, err := r.PeekState()
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	if state == StateStartIndefiniteLengthTextString {
-		return r.readIndefiniteTextString()
-	}
+	start := r.offset
+	needed := 0
+
+	switch state {
+	case StateByteString:
+		r.invalidateState()
+		length, err := r.readArgumentValue(MajorTypeByteString)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.checkStringLength(MajorTypeByteString, length); err != nil {
+			return 0, err
+		}
+		needed = int(length)
+		if needed <= len(dst) {
+			copy(dst, r.data[r.offset:r.offset+needed])
+		}
+		r.offset += needed
+
+	case StateStartIndefiniteLengthByteString:
+		if r.conformanceMode >= ConformanceCanonical {
+			return 0, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadByteStringInto")
+		}
+		err := r.readIndefiniteChunks(MajorTypeByteString, func(chunk []byte) error {
+			if needed < len(dst) {
+				copy(dst[needed:], chunk)
+			}
+			needed += len(chunk)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+
+	default:
+		return 0, &TypeMismatchError{Expected: StateByteString, Actual: state, Offset: r.offset}
+	}
+
+	if needed > len(dst) {
+		r.offset = start
+		r.invalidateState()
+		return needed, NewCborError(ErrBufferTooSmall, r.offset, "ReadByteStringInto")
+	}
+
+	r.advanceContainer()
+	return needed, nil
+}
+
+// ReadTextString reads a UTF-8 text string.
+func (r *CborReader) ReadTextString() (string, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return "", err
+	}
+
+	if state == StateStartIndefiniteLengthTextString {
+		return r.readIndefiniteTextString()
+	}
 
 	if state != StateTextString {
-		return "", &TypeMismatchError{Expected: StateTextString, Actual: state}
+		return "", &TypeMismatchError{Expected: StateTextString, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
@@ -627,15 +1183,15 @@ func (r *CborReader) ReadTextString() (string, error) {
 		return "", err
 	}
 
-	if r.offset+int(length) > len(r.data) {
-		return "", ErrUnexpectedEndOfData
+	if err := r.checkStringLength(MajorTypeTextString, length); err != nil {
+		return "", err
 	}
 
 	strBytes := r.data[r.offset : r.offset+int(length)]
 
 	// Validate UTF-8 in strict mode
 	if r.conformanceMode >= ConformanceStrict && !utf8.Valid(strBytes) {
-		return "", ErrInvalidUtf8
+		return "", NewCborError(ErrInvalidUtf8, r.offset, "ReadTextString")
 	}
 
 	result := string(strBytes)
@@ -644,51 +1200,139 @@ func (r *CborReader) ReadTextString() (string, error) {
 	return result, nil
 }
 
-// readIndefiniteTextString reads an indefinite-length text string.
-func (r *CborReader) readIndefiniteTextString() (string, error) {
-	if r.conformanceMode >= ConformanceCanonical {
-		return "", ErrIndefiniteLengthNotAllowed
+// ReadTextStringAppend appends the bytes of the next text string, definite or
+// indefinite length, to dst and returns the extended slice, so one buffer can be reused
+// across many reads. UTF-8 is validated in strict mode as in ReadTextString. On error the
+// returned slice has the length of dst.
+func (r *CborReader) ReadTextStringAppend(dst []byte) ([]byte, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return dst, err
 	}
 
-	// Skip the initial byte
-	r.offset++
-	r.invalidateState()
-
-	var result bytes.Buffer
+	strict := r.conformanceMode >= ConformanceStrict
+	switch state {
+	case StateTextString:
+		r.invalidateState()
+		length, err := r.readArgumentValue(MajorTypeTextString)
+		if err != nil {
+			return dst, err
+		}
+		if err := r.checkStringLength(MajorTypeTextString, length); err != nil {
+			return dst, err
+		}
+		strBytes := r.data[r.offset : r.offset+int(length)]
+		if strict && !utf8.Valid(strBytes) {
+			return dst, NewCborError(ErrInvalidUtf8, r.offset, "ReadTextStringAppend")
+		}
+		r.offset += int(length)
+		r.advanceContainer()
+		return append(dst, strBytes...), nil
 
-	for {
-		if r.offset >= len(r.data) {
-			return "", ErrUnexpectedEndOfData
+	case StateStartIndefiniteLengthTextString:
+		if r.conformanceMode >= ConformanceCanonical {
+			return dst, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadTextStringAppend")
+		}
+		out := dst
+		err := r.readIndefiniteChunks(MajorTypeTextString, func(chunk []byte) error {
+			if strict && !utf8.Valid(chunk) {
+				return NewCborError(ErrInvalidUtf8, r.offset, "ReadTextStringAppend")
+			}
+			out = append(out, chunk...)
+			return nil
+		})
+		if err != nil {
+			return dst, err
 		}
+		r.advanceContainer()
+		return out, nil
 
-		if r.data[r.offset] == breakByte {
-			r.offset++
-			break
+	default:
+		return dst, &TypeMismatchError{Expected: StateTextString, Actual: state, Offset: r.offset}
+	}
+}
+
+// ReadTextStringToBuilder appends the next text string to b and returns the number of
+// bytes written. The text is always validated as UTF-8, and indefinite-length strings are
+// validated in full before any chunk is appended, so b is unchanged on error.
+func (r *CborReader) ReadTextStringToBuilder(b *strings.Builder) (int, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return 0, err
+	}
+
+	switch state {
+	case StateTextString:
+		r.invalidateState()
+		length, err := r.readArgumentValue(MajorTypeTextString)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.checkStringLength(MajorTypeTextString, length); err != nil {
+			return 0, err
 		}
+		strBytes := r.data[r.offset : r.offset+int(length)]
+		if !utf8.Valid(strBytes) {
+			return 0, NewCborError(ErrInvalidUtf8, r.offset, "ReadTextStringToBuilder")
+		}
+		b.Write(strBytes)
+		r.offset += int(length)
+		r.advanceContainer()
+		return int(length), nil
 
-		// Read a definite-length text string chunk
-		mt, _ := decodeInitialByte(r.data[r.offset])
-		if mt != MajorTypeTextString {
-			return "", ErrInvalidCbor
+	case StateStartIndefiniteLengthTextString:
+		if r.conformanceMode >= ConformanceCanonical {
+			return 0, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadTextStringToBuilder")
 		}
 
-		length, err := r.readArgumentValue(MajorTypeTextString)
+		// First pass validates every chunk and sizes the builder
+		mark := r.Bookmark()
+		total := 0
+		err := r.readIndefiniteChunks(MajorTypeTextString, func(chunk []byte) error {
+			if !utf8.Valid(chunk) {
+				return NewCborError(ErrInvalidUtf8, r.offset, "ReadTextStringToBuilder")
+			}
+			total += len(chunk)
+			return nil
+		})
 		if err != nil {
-			return "", err
+			return 0, err
 		}
 
-		if r.offset+int(length) > len(r.data) {
-			return "", ErrUnexpectedEndOfData
+		r.Restore(mark)
+		b.Grow(total)
+		err = r.readIndefiniteChunks(MajorTypeTextString, func(chunk []byte) error {
+			b.Write(chunk)
+			return nil
+		})
+		if err != nil {
+			return 0, err
 		}
+		r.advanceContainer()
+		return total, nil
 
-		chunk := r.data[r.offset : r.offset+int(length)]
+	default:
+		return 0, &TypeMismatchError{Expected: StateTextString, Actual: state, Offset: r.offset}
+	}
+}
 
+// readIndefiniteTextString reads an indefinite-length text string.
+func (r *CborReader) readIndefiniteTextString() (string, error) {
+	if r.conformanceMode >= ConformanceCanonical {
+		return "", NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadTextString")
+	}
+
+	var result bytes.Buffer
+
+	err := r.readIndefiniteChunks(MajorTypeTextString, func(chunk []byte) error {
 		if r.conformanceMode >= ConformanceStrict && !utf8.Valid(chunk) {
-			return "", ErrInvalidUtf8
+			return NewCborError(ErrInvalidUtf8, r.offset, "ReadTextString")
 		}
-
 		result.Write(chunk)
-		r.offset += int(length)
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	r.advanceContainer()
@@ -703,18 +1347,18 @@ func (r *CborReader) ReadStartArray() (int, error) {
 		return 0, err
 	}
 	if state != StateStartArray {
-		return 0, &TypeMismatchError{Expected: StateStartArray, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateStartArray, Actual: state, Offset: r.offset}
 	}
 
 	if len(r.nestingStack) >= r.maxNestingDepth {
-		return 0, ErrNestingDepthExceeded
+		return 0, NewCborError(ErrNestingDepthExceeded, r.offset, "ReadStartArray")
 	}
 
 	r.invalidateState()
 
 	if r.data[r.offset] == encodeInitialByte(MajorTypeArray, byte(AdditionalInfoIndefiniteLength)) {
 		if r.conformanceMode >= ConformanceCanonical {
-			return 0, ErrIndefiniteLengthNotAllowed
+			return 0, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadStartArray")
 		}
 		r.offset++
 		r.nestingStack = append(r.nestingStack, readerNestingInfo{
@@ -729,6 +1373,9 @@ func (r *CborReader) ReadStartArray() (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err := r.checkContainerLength(MajorTypeArray, length); err != nil {
+		return 0, err
+	}
 
 	r.nestingStack = append(r.nestingStack, readerNestingInfo{
 		majorType:      MajorTypeArray,
@@ -738,6 +1385,33 @@ func (r *CborReader) ReadStartArray() (int, error) {
 	return int(length), nil
 }
 
+// containerLengthLimit returns the configured element limit for arrays or maps.
+func (r *CborReader) containerLengthLimit(mt MajorType) int {
+	if mt == MajorTypeMap {
+		return r.maxMapLength
+	}
+	if mt == MajorTypeArray {
+		return r.maxArrayLength
+	}
+	return 0
+}
+
+// checkContainerLength validates a declared array or map length against the configured
+// limit and the remaining data, where every item takes at least one byte.
+func (r *CborReader) checkContainerLength(mt MajorType, length uint64) error {
+	if limit := r.containerLengthLimit(mt); limit > 0 && length > uint64(limit) {
+		return NewCborError(ErrValueTooLarge, r.offset, "read container length")
+	}
+	minSize := length
+	if mt == MajorTypeMap {
+		minSize *= 2
+	}
+	if length > uint64(len(r.data)) || minSize > uint64(len(r.data)-r.offset) {
+		return NewCborError(ErrUnexpectedEndOfData, r.offset, "read container length")
+	}
+	return nil
+}
+
 // ReadEndArray reads the end of an array.
 func (r *CborReader) ReadEndArray() error {
 	state, err := r.PeekState()
@@ -745,21 +1419,21 @@ func (r *CborReader) ReadEndArray() error {
 		return err
 	}
 	if state != StateEndArray {
-		return &TypeMismatchError{Expected: StateEndArray, Actual: state}
+		return &TypeMismatchError{Expected: StateEndArray, Actual: state, Offset: r.offset}
 	}
 
 	if len(r.nestingStack) == 0 {
-		return ErrInvalidState
+		return NewCborError(ErrInvalidState, r.offset, "ReadEndArray")
 	}
 
 	info := &r.nestingStack[len(r.nestingStack)-1]
 	if info.majorType != MajorTypeArray {
-		return ErrInvalidState
+		return NewCborError(ErrInvalidState, r.offset, "ReadEndArray")
 	}
 
 	if info.isIndefinite {
 		if r.data[r.offset] != breakByte {
-			return ErrMissingBreak
+			return NewCborError(ErrMissingBreak, r.offset, "ReadEndArray")
 		}
 		r.offset++
 	}
@@ -778,18 +1452,18 @@ func (r *CborReader) ReadStartMap() (int, error) {
 		return 0, err
 	}
 	if state != StateStartMap {
-		return 0, &TypeMismatchError{Expected: StateStartMap, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateStartMap, Actual: state, Offset: r.offset}
 	}
 
 	if len(r.nestingStack) >= r.maxNestingDepth {
-		return 0, ErrNestingDepthExceeded
+		return 0, NewCborError(ErrNestingDepthExceeded, r.offset, "ReadStartMap")
 	}
 
 	r.invalidateState()
 
 	if r.data[r.offset] == encodeInitialByte(MajorTypeMap, byte(AdditionalInfoIndefiniteLength)) {
 		if r.conformanceMode >= ConformanceCanonical {
-			return 0, ErrIndefiniteLengthNotAllowed
+			return 0, NewCborError(ErrIndefiniteLengthNotAllowed, r.offset, "ReadStartMap")
 		}
 		r.offset++
 		r.nestingStack = append(r.nestingStack, readerNestingInfo{
@@ -805,6 +1479,9 @@ func (r *CborReader) ReadStartMap() (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err := r.checkContainerLength(MajorTypeMap, length); err != nil {
+		return 0, err
+	}
 
 	r.nestingStack = append(r.nestingStack, readerNestingInfo{
 		majorType:      MajorTypeMap,
@@ -822,21 +1499,21 @@ func (r *CborReader) ReadEndMap() error {
 		return err
 	}
 	if state != StateEndMap {
-		return &TypeMismatchError{Expected: StateEndMap, Actual: state}
+		return &TypeMismatchError{Expected: StateEndMap, Actual: state, Offset: r.offset}
 	}
 
 	if len(r.nestingStack) == 0 {
-		return ErrInvalidState
+		return NewCborError(ErrInvalidState, r.offset, "ReadEndMap")
 	}
 
 	info := &r.nestingStack[len(r.nestingStack)-1]
 	if info.majorType != MajorTypeMap {
-		return ErrInvalidState
+		return NewCborError(ErrInvalidState, r.offset, "ReadEndMap")
 	}
 
 	if info.isIndefinite {
 		if r.data[r.offset] != breakByte {
-			return ErrMissingBreak
+			return NewCborError(ErrMissingBreak, r.offset, "ReadEndMap")
 		}
 		r.offset++
 	}
@@ -854,7 +1531,7 @@ func (r *CborReader) ReadTag() (CborTag, error) {
 		return 0, err
 	}
 	if state != StateTag {
-		return 0, &TypeMismatchError{Expected: StateTag, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateTag, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
@@ -867,6 +1544,116 @@ func (r *CborReader) ReadTag() (CborTag, error) {
 	return CborTag(val), nil
 }
 
+// PeekTag returns the next semantic tag without consuming it.
+func (r *CborReader) PeekTag() (CborTag, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return 0, err
+	}
+	if state != StateTag {
+		return 0, &TypeMismatchError{Expected: StateTag, Actual: state, Offset: r.offset}
+	}
+
+	start := r.offset
+	val, err := r.readArgumentValue(MajorTypeTag)
+	r.offset = start
+	if err != nil {
+		return 0, err
+	}
+
+	return CborTag(val), nil
+}
+
+// ReadTags consumes a run of consecutive semantic tags and returns them outermost first,
+// leaving the reader at the tagged content. It returns no tags if the next item is not
+// tagged, and an error if the tags are not followed by content.
+func (r *CborReader) ReadTags() ([]CborTag, error) {
+	var tags []CborTag
+	for {
+		state, err := r.PeekState()
+		if err != nil {
+			return nil, err
+		}
+		if state != StateTag {
+			if len(tags) > 0 {
+				switch state {
+				case StateFinished:
+					return nil, NewCborError(ErrUnexpectedEndOfData, r.offset, "ReadTags")
+				case StateEndArray, StateEndMap:
+					return nil, NewCborError(ErrInvalidCbor, r.offset, "ReadTags")
+				}
+			}
+			return tags, nil
+		}
+
+		tag, err := r.ReadTag()
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+}
+
+// IsSelfDescribed reports whether the next item is a self-described CBOR tag (55799).
+func (r *CborReader) IsSelfDescribed() (bool, error) {
+	state, err := r.PeekState()
+	if err != nil || state != StateTag {
+		return false, err
+	}
+	tag, err := r.PeekTag()
+	if err != nil {
+		return false, err
+	}
+	return tag == TagSelfDescribedCbor, nil
+}
+
+// skipSelfDescribedTags consumes any self-described CBOR tags (55799) at the current
+// position. The tag carries no meaning, so typed readers look through it.
+func (r *CborReader) skipSelfDescribedTags() error {
+	for {
+		state, err := r.PeekState()
+		if err != nil || state != StateTag {
+			return err
+		}
+		tag, err := r.PeekTag()
+		if err != nil || tag != TagSelfDescribedCbor {
+			return err
+		}
+		if _, err := r.ReadTag(); err != nil {
+			return err
+		}
+	}
+}
+
+// readSemanticTag reads a tag after skipping any self-described CBOR tags.
+func (r *CborReader) readSemanticTag() (CborTag, error) {
+	if err := r.skipSelfDescribedTags(); err != nil {
+		return 0, err
+	}
+	return r.ReadTag()
+}
+
+// PeekIsIndefinite reports whether the next array, map, byte string or text string uses
+// indefinite-length encoding, without consuming its header.
+func (r *CborReader) PeekIsIndefinite() (bool, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return false, err
+	}
+
+	switch state {
+	case StateStartIndefiniteLengthByteString, StateStartIndefiniteLengthTextString:
+		return true, nil
+	case StateByteString, StateTextString:
+		return false, nil
+	case StateStartArray, StateStartMap:
+		_, ai := r.initialByteInfo()
+		return ai == byte(AdditionalInfoIndefiniteLength), nil
+	default:
+		return false, &TypeMismatchError{Expected: StateStartArray, Actual: state, Offset: r.offset}
+	}
+}
+
 // ReadBoolean reads a boolean value.
 func (r *CborReader) ReadBoolean() (bool, error) {
 	state, err := r.PeekState()
@@ -874,11 +1661,11 @@ func (r *CborReader) ReadBoolean() (bool, error) {
 		return false, err
 	}
 	if state != StateBoolean {
-		return false, &TypeMismatchError{Expected: StateBoolean, Actual: state}
+		return false, &TypeMismatchError{Expected: StateBoolean, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
-	_, ai := decodeInitialByte(r.data[r.offset])
+	_, ai := r.initialByteInfo()
 	r.offset++
 	r.advanceContainer()
 
@@ -892,7 +1679,7 @@ func (r *CborReader) ReadNull() error {
 		return err
 	}
 	if state != StateNull {
-		return &TypeMismatchError{Expected: StateNull, Actual: state}
+		return &TypeMismatchError{Expected: StateNull, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
@@ -908,7 +1695,7 @@ func (r *CborReader) ReadUndefined() error {
 		return err
 	}
 	if state != StateUndefinedValue {
-		return &TypeMismatchError{Expected: StateUndefinedValue, Actual: state}
+		return &TypeMismatchError{Expected: StateUndefinedValue, Actual: state, Offset: r.offset}
 	}
 
 	r.invalidateState()
@@ -919,6 +1706,22 @@ func (r *CborReader) ReadUndefined() error {
 
 // ReadSimpleValue reads a simple value.
 func (r *CborReader) ReadSimpleValue() (SimpleValue, error) {
+	return r.readSimpleValue(r.conformanceMode, "ReadSimpleValue")
+}
+
+// ReadSimpleValueStrict reads a simple value, applying the strict mode checks whatever
+// the reader's conformance mode: a two-byte encoding of a reserved value 24-31, which
+// RFC 8949 defines as malformed, returns ErrInvalidSimpleValue, and a two-byte encoding
+// of a value below 24 that fits in the initial byte returns ErrNonCanonical. Unlike
+// ReadSimpleValue on a canonical reader, it keeps the two errors apart.
+func (r *CborReader) ReadSimpleValueStrict() (SimpleValue, error) {
+	return r.readSimpleValue(ConformanceStrict, "ReadSimpleValueStrict")
+}
+
+// readSimpleValue reads a simple value, checking two-byte encodings below 32 as mode
+// requires. Strict mode reports reserved values 24-31 as ErrInvalidSimpleValue, while
+// canonical modes report every such encoding as ErrNonCanonical.
+func (r *CborReader) readSimpleValue(mode CborConformanceMode, op string) (SimpleValue, error) {
 	state, err := r.PeekState()
 	if err != nil {
 		return 0, err
@@ -929,29 +1732,32 @@ func (r *CborReader) ReadSimpleValue() (SimpleValue, error) {
 	case StateSimpleValue, StateBoolean, StateNull, StateUndefinedValue:
 		// ok
 	default:
-		return 0, &TypeMismatchError{Expected: StateSimpleValue, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateSimpleValue, Actual: state, Offset: r.offset}
 	}
 
-	r.invalidateState()
-	_, ai := decodeInitialByte(r.data[r.offset])
-	r.offset++
+	_, ai := r.initialByteInfo()
 
-	var value SimpleValue
+	value := SimpleValue(ai)
+	size := 1
 	if ai == 24 {
-		if r.offset >= len(r.data) {
-			return 0, ErrUnexpectedEndOfData
+		if r.offset+1 >= len(r.data) {
+			return 0, NewCborError(ErrUnexpectedEndOfData, r.offset+1, op)
 		}
-		value = SimpleValue(r.data[r.offset])
-		r.offset++
+		value = SimpleValue(r.data[r.offset+1])
+		size = 2
 
-		// Canonical check: value must be >= 32
-		if r.conformanceMode >= ConformanceStrict && value < 32 {
-			return 0, ErrNonCanonical
+		// A two-byte simple value must be >= 32: 24-31 are reserved and lower values
+		// belong in the initial byte.
+		if (mode >= ConformanceCanonical && value < 32) || (mode >= ConformanceStrict && value < 24) {
+			return 0, NewCborError(ErrNonCanonical, r.offset, op)
+		}
+		if mode >= ConformanceStrict && value < 32 {
+			return 0, NewCborError(ErrInvalidSimpleValue, r.offset, op)
 		}
-	} else {
-		value = SimpleValue(ai)
 	}
 
+	r.invalidateState()
+	r.offset += size
 	r.advanceContainer()
 	return value, nil
 }
@@ -963,21 +1769,24 @@ func (r *CborReader) ReadFloat16() (float32, error) {
 		return 0, err
 	}
 	if state != StateHalfPrecisionFloat {
-		return 0, &TypeMismatchError{Expected: StateHalfPrecisionFloat, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateHalfPrecisionFloat, Actual: state, Offset: r.offset}
 	}
 
-	r.invalidateState()
-	r.offset++ // Skip initial byte
+	if r.offset+1+2 > len(r.data) {
+		return 0, NewCborError(ErrUnexpectedEndOfData, r.offset+1, "ReadFloat16")
+	}
 
-	if r.offset+2 > len(r.data) {
-		return 0, ErrUnexpectedEndOfData
+	bits := binary.BigEndian.Uint16(r.data[r.offset+1:])
+	value := float16BitsToFloat32(bits)
+	if err := r.checkIntegralFloat(float64(value)); err != nil {
+		return 0, err
 	}
 
-	bits := binary.BigEndian.Uint16(r.data[r.offset:])
-	r.offset += 2
+	r.invalidateState()
+	r.offset += 1 + 2
 	r.advanceContainer()
 
-	return float16BitsToFloat32(bits), nil
+	return value, nil
 }
 
 // ReadFloat32 reads a single-precision floating-point number.
@@ -987,21 +1796,24 @@ func (r *CborReader) ReadFloat32() (float32, error) {
 		return 0, err
 	}
 	if state != StateSinglePrecisionFloat {
-		return 0, &TypeMismatchError{Expected: StateSinglePrecisionFloat, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateSinglePrecisionFloat, Actual: state, Offset: r.offset}
 	}
 
-	r.invalidateState()
-	r.offset++ // Skip initial byte
+	if r.offset+1+4 > len(r.data) {
+		return 0, NewCborError(ErrUnexpectedEndOfData, r.offset+1, "ReadFloat32")
+	}
 
-	if r.offset+4 > len(r.data) {
-		return 0, ErrUnexpectedEndOfData
+	bits := binary.BigEndian.Uint32(r.data[r.offset+1:])
+	value := math.Float32frombits(bits)
+	if err := r.checkIntegralFloat(float64(value)); err != nil {
+		return 0, err
 	}
 
-	bits := binary.BigEndian.Uint32(r.data[r.offset:])
-	r.offset += 4
+	r.invalidateState()
+	r.offset += 1 + 4
 	r.advanceContainer()
 
-	return math.Float32frombits(bits), nil
+	return value, nil
 }
 
 // ReadFloat64 reads a double-precision floating-point number.
@@ -1011,21 +1823,57 @@ func (r *CborReader) ReadFloat64() (float64, error) {
 		return 0, err
 	}
 	if state != StateDoublePrecisionFloat {
-		return 0, &TypeMismatchError{Expected: StateDoublePrecisionFloat, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateDoublePrecisionFloat, Actual: state, Offset: r.offset}
 	}
 
-	r.invalidateState()
-	r.offset++ // Skip initial byte
+	if r.offset+1+8 > len(r.data) {
+		return 0, NewCborError(ErrUnexpectedEndOfData, r.offset+1, "ReadFloat64")
+	}
 
-	if r.offset+8 > len(r.data) {
-		return 0, ErrUnexpectedEndOfData
+	bits := binary.BigEndian.Uint64(r.data[r.offset+1:])
+	value := math.Float64frombits(bits)
+	if err := r.checkIntegralFloat(value); err != nil {
+		return 0, err
 	}
 
-	bits := binary.BigEndian.Uint64(r.data[r.offset:])
-	r.offset += 8
+	r.invalidateState()
+	r.offset += 1 + 8
 	r.advanceContainer()
 
-	return math.Float64frombits(bits), nil
+	return value, nil
+}
+
+// FloatWidth reports the width in bits (16, 32 or 64) of the next float without consuming
+// it. Items that are not floats return a TypeMismatchError.
+func (r *CborReader) FloatWidth() (int, error) {
+	state, err := r.PeekState()
+	if err != nil {
+		return 0, err
+	}
+
+	switch state {
+	case StateHalfPrecisionFloat:
+		return 16, nil
+	case StateSinglePrecisionFloat:
+		return 32, nil
+	case StateDoublePrecisionFloat:
+		return 64, nil
+	default:
+		return 0, &TypeMismatchError{Expected: StateDoublePrecisionFloat, Actual: state, Offset: r.offset}
+	}
+}
+
+// checkIntegralFloat rejects, in CTAP2 canonical mode, a float at the current offset whose
+// value is an integer that a CBOR integer could encode, since CTAP2 forbids encoding
+// integers as floats. Negative zero has no integer encoding and is allowed.
+func (r *CborReader) checkIntegralFloat(value float64) error {
+	if r.conformanceMode != ConformanceCtap2Canonical {
+		return nil
+	}
+	if value == math.Trunc(value) && value >= -(1<<64) && value < 1<<64 && !(value == 0 && math.Signbit(value)) {
+		return NewCborError(ErrNonCanonical, r.offset, "integral float")
+	}
+	return nil
 }
 
 // ReadFloat reads any floating-point number and returns it as float64.
@@ -1045,13 +1893,14 @@ func (r *CborReader) ReadFloat() (float64, error) {
 	case StateDoublePrecisionFloat:
 		return r.ReadFloat64()
 	default:
-		return 0, &TypeMismatchError{Expected: StateDoublePrecisionFloat, Actual: state}
+		return 0, &TypeMismatchError{Expected: StateDoublePrecisionFloat, Actual: state, Offset: r.offset}
 	}
 }
 
-// ReadDateTimeString reads a date/time string (tag 0).
+// ReadDateTimeString reads a date/time string (tag 0), parsed as RFC 3339 unless other
+// layouts are set with WithReaderDateTimeFormats.
 func (r *CborReader) ReadDateTimeString() (time.Time, error) {
-	tag, err := r.ReadTag()
+	tag, err := r.readSemanticTag()
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -1064,19 +1913,32 @@ func (r *CborReader) ReadDateTimeString() (time.Time, error) {
 		return time.Time{}, err
 	}
 
-	return time.Parse(time.RFC3339Nano, str)
+	if len(r.dateTimeFormats) == 0 {
+		return time.Parse(time.RFC3339Nano, str)
+	}
+	for _, layout := range r.dateTimeFormats {
+		var t time.Time
+		if t, err = time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
 }
 
 // ReadUnixTime reads an epoch-based date/time (tag 1).
 func (r *CborReader) ReadUnixTime() (time.Time, error) {
-	tag, err := r.ReadTag()
+	tag, err := r.readSemanticTag()
 	if err != nil {
 		return time.Time{}, err
 	}
 	if tag != TagUnixTime {
 		return time.Time{}, NewCborError(ErrInvalidCbor, r.offset, "expected unix time tag")
 	}
+	return r.readEpochTime()
+}
 
+// readEpochTime reads integer or float seconds since the epoch, the content of tag 1.
+func (r *CborReader) readEpochTime() (time.Time, error) {
 	state, err := r.PeekState()
 	if err != nil {
 		return time.Time{}, err
@@ -1095,79 +1957,213 @@ func (r *CborReader) ReadUnixTime() (time.Time, error) {
 		if err != nil {
 			return time.Time{}, err
 		}
-		secs := int64(f)
-		nsecs := int64((f - float64(secs)) * 1e9)
-		return time.Unix(secs, nsecs), nil
+		return unixTimeFromFloat(f, r.offset)
 
 	default:
-		return time.Time{}, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state}
+		return time.Time{}, &TypeMismatchError{Expected: StateUnsignedInteger, Actual: state, Offset: r.offset}
 	}
 }
 
-// SkipValue skips the current value (including nested values for arrays/maps).
-func (r *CborReader) SkipValue() error {
-	state, err := r.PeekState()
+// unixTimeFromFloat converts fractional epoch seconds to a time, flooring to whole seconds
+// so that the nanoseconds stay in [0, 1e9) for negative times, and rounding the fraction to
+// the nearest nanosecond. Non-finite values and values outside the int64 range of seconds
+// return ErrOverflow.
+func unixTimeFromFloat(f float64, offset int) (time.Time, error) {
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return time.Time{}, NewCborError(ErrOverflow, offset, "epoch time out of range")
+	}
+
+	secs := math.Floor(f)
+	nsecs := int64(math.Round((f - secs) * 1e9))
+	if nsecs >= 1e9 {
+		secs++
+		nsecs -= 1e9
+	}
+	return time.Unix(int64(secs), nsecs), nil
+}
+
+// ReadFullDate reads a full-date string (tag 1004) as midnight UTC.
+func (r *CborReader) ReadFullDate() (time.Time, error) {
+	tag, err := r.readSemanticTag()
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
+	if tag != TagFullDateString {
+		return time.Time{}, NewCborError(ErrInvalidCbor, r.offset, "expected full-date tag")
+	}
+
+	start := r.offset
+	str, err := r.ReadTextString()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(fullDateLayout, str)
+	if err != nil {
+		return time.Time{}, NewCborError(ErrInvalidDate, start, err.Error())
+	}
+	return t, nil
+}
+
+// ReadEpochDate reads an epoch-based date (tag 100) as midnight UTC.
+func (r *CborReader) ReadEpochDate() (time.Time, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if tag != TagEpochDate {
+		return time.Time{}, NewCborError(ErrInvalidCbor, r.offset, "expected epoch date tag")
+	}
+
+	days, err := r.ReadInt64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if days > math.MaxInt64/secondsPerDay || days < math.MinInt64/secondsPerDay {
+		return time.Time{}, NewCborError(ErrOverflow, r.offset, "ReadEpochDate")
+	}
+	return time.Unix(days*secondsPerDay, 0).UTC(), nil
+}
+
+// SkipValue skips the current value (including nested values for arrays/maps). Nested
+// containers are walked with the reader's own nesting stack rather than by recursion, so
+// Go stack use stays constant and depth is bounded by the maximum nesting depth.
+func (r *CborReader) SkipValue() error {
+	depth := len(r.nestingStack)
+	for {
+		state, err := r.PeekState()
+		if err != nil {
+			return err
+		}
+
+		switch state {
+		case StateTag:
+			// The content of the tag follows.
+			if _, err := r.ReadTag(); err != nil {
+				return err
+			}
+			if r.offset == len(r.data) {
+				return NewCborError(ErrUnexpectedEndOfData, r.offset, "SkipValue")
+			}
+			continue
+		case StateStartArray:
+			if _, err := r.ReadStartArray(); err != nil {
+				return err
+			}
+			continue
+		case StateStartMap:
+			if _, err := r.ReadStartMap(); err != nil {
+				return err
+			}
+			continue
+		case StateEndArray, StateEndMap:
+			if len(r.nestingStack) == depth {
+				return NewCborError(ErrInvalidState, r.offset, "SkipValue")
+			}
+			if state == StateEndArray {
+				err = r.ReadEndArray()
+			} else {
+				err = r.ReadEndMap()
+			}
+		default:
+			err = r.skipScalar(state)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(r.nestingStack) == depth {
+			return nil
+		}
+	}
+}
 
+// skipScalar skips an item that is not a container or tag.
+func (r *CborReader) skipScalar(state CborReaderState) error {
+	var err error
 	switch state {
 	case StateUnsignedInteger:
 		_, err = r.ReadUint64()
-		return err
 	case StateNegativeInteger:
 		_, err = r.ReadInt64()
-		return err
 	case StateByteString, StateStartIndefiniteLengthByteString:
 		_, err = r.ReadByteString()
-		return err
 	case StateTextString, StateStartIndefiniteLengthTextString:
 		_, err = r.ReadTextString()
-		return err
-	case StateStartArray:
-		return r.skipArray()
-	case StateStartMap:
-		return r.skipMap()
-	case StateTag:
-		_, err = r.ReadTag()
-		if err != nil {
-			return err
-		}
-		return r.SkipValue()
 	case StateBoolean:
 		_, err = r.ReadBoolean()
-		return err
 	case StateNull:
-		return r.ReadNull()
+		err = r.ReadNull()
 	case StateUndefinedValue:
-		return r.ReadUndefined()
+		err = r.ReadUndefined()
 	case StateSimpleValue:
 		_, err = r.ReadSimpleValue()
-		return err
 	case StateHalfPrecisionFloat:
 		_, err = r.ReadFloat16()
-		return err
 	case StateSinglePrecisionFloat:
 		_, err = r.ReadFloat32()
-		return err
 	case StateDoublePrecisionFloat:
 		_, err = r.ReadFloat64()
-		return err
 	default:
-		return ErrInvalidState
+		err = NewCborError(ErrInvalidState, r.offset, "SkipValue")
+	}
+	return err
+}
+
+// SkipToEndOfContainer skips the remaining items of the current array or map and consumes
+// its end. It returns ErrInvalidState at the root level.
+func (r *CborReader) SkipToEndOfContainer() error {
+	if len(r.nestingStack) == 0 {
+		return NewCborError(ErrInvalidState, r.offset, "SkipToEndOfContainer")
+	}
+
+	for {
+		state, err := r.PeekState()
+		if err != nil {
+			return err
+		}
+		switch state {
+		case StateEndArray:
+			return r.ReadEndArray()
+		case StateEndMap:
+			return r.ReadEndMap()
+		}
+		if err := r.SkipValue(); err != nil {
+			return err
+		}
+	}
+}
+
+// AtBreak reports whether the current array or map has no more items, so the next read
+// must be its end, without consuming anything. For an indefinite-length container this is
+// its break byte, for a definite-length one the end of its declared items. It returns
+// ErrInvalidState at the root level, where a break is invalid.
+func (r *CborReader) AtBreak() (bool, error) {
+	if len(r.nestingStack) == 0 {
+		return false, NewCborError(ErrInvalidState, r.offset, "AtBreak")
+	}
+
+	state, err := r.PeekState()
+	if err != nil {
+		return false, err
+	}
+	switch state {
+	case StateEndArray, StateEndMap:
+		return true, nil
+	default:
+		return false, nil
 	}
 }
 
-// skipArray skips an array and all its contents.
-func (r *CborReader) skipArray() error {
+// ReadArrayPrefix reads an array, passing up to n elements to readElem and skipping the rest.
+// readElem must consume exactly one element. The end of the array is consumed as well.
+func (r *CborReader) ReadArrayPrefix(n int, readElem func(index int, r *CborReader) error) error {
 	length, err := r.ReadStartArray()
 	if err != nil {
 		return err
 	}
 
-	if length == -1 {
-		// Indefinite length
-		for {
+	for i := 0; length == -1 || i < length; i++ {
+		if length == -1 {
 			state, err := r.PeekState()
 			if err != nil {
 				return err
@@ -1175,75 +2171,93 @@ func (r *CborReader) skipArray() error {
 			if state == StateEndArray {
 				break
 			}
-			if err := r.SkipValue(); err != nil {
-				return err
-			}
 		}
-	} else {
-		for i := 0; i < length; i++ {
-			if err := r.SkipValue(); err != nil {
-				return err
-			}
+
+		if i < n {
+			err = readElem(i, r)
+		} else {
+			err = r.SkipValue()
+		}
+		if err != nil {
+			return err
 		}
 	}
 
 	return r.ReadEndArray()
 }
 
-// skipMap skips a map and all its contents.
-func (r *CborReader) skipMap() error {
-	length, err := r.ReadStartMap()
-	if err != nil {
-		return err
+// CountRemainingItems returns the number of items left in the current container without
+// consuming them. For maps it counts key/value pairs. Definite-length containers report the
+// declared length; indefinite-length containers are scanned ahead and the reader rewound.
+// It returns ErrInvalidState at the root level or between a map key and its value.
+func (r *CborReader) CountRemainingItems() (int, error) {
+	if len(r.nestingStack) == 0 {
+		return 0, NewCborError(ErrInvalidState, r.offset, "CountRemainingItems")
 	}
 
-	if length == -1 {
-		// Indefinite length
-		for {
-			state, err := r.PeekState()
-			if err != nil {
-				return err
-			}
-			if state == StateEndMap {
-				break
-			}
-			// Skip key
-			if err := r.SkipValue(); err != nil {
-				return err
-			}
-			// Skip value
-			if err := r.SkipValue(); err != nil {
-				return err
-			}
+	info := &r.nestingStack[len(r.nestingStack)-1]
+	if info.isMap && info.keyRead {
+		return 0, NewCborError(ErrInvalidState, r.offset, "CountRemainingItems")
+	}
+	if !info.isIndefinite {
+		return int(info.definiteLength - info.itemsRead), nil
+	}
+
+	mark := r.Bookmark()
+	defer r.Restore(mark)
+
+	end := StateEndArray
+	if info.isMap {
+		end = StateEndMap
+	}
+
+	count := 0
+	for {
+		state, err := r.PeekState()
+		if err != nil {
+			return 0, err
 		}
-	} else {
-		for i := 0; i < length; i++ {
-			// Skip key
-			if err := r.SkipValue(); err != nil {
-				return err
-			}
-			// Skip value
+		if state == end {
+			return count, nil
+		}
+		if err := r.SkipValue(); err != nil {
+			return 0, err
+		}
+		if info.isMap {
 			if err := r.SkipValue(); err != nil {
-				return err
+				return 0, err
 			}
 		}
+		count++
 	}
-
-	return r.ReadEndMap()
 }
 
-// TryReadNull returns true if the next value is null and consumes it.
+// TryReadNull returns true if the next value is null and consumes it. With
+// WithReaderUndefinedAsNull, undefined counts as null.
 func (r *CborReader) TryReadNull() (bool, error) {
 	state, err := r.PeekState()
 	if err != nil {
 		return false, err
 	}
-	if state == StateNull {
-		return true, r.ReadNull()
+	if r.isNull(state) {
+		return true, r.skipNull()
 	}
 	return false, nil
 }
 
+// isNull reports whether state is null, or undefined when undefined is read as null.
+func (r *CborReader) isNull(state CborReaderState) bool {
+	return state == StateNull || (state == StateUndefinedValue && r.undefinedAsNull)
+}
+
+// skipNull consumes the null or undefined value reported by isNull.
+func (r *CborReader) skipNull() error {
+	r.invalidateState()
+	r.offset++
+	r.advanceContainer()
+	return nil
+}
+
 // ReadEncodedValue reads a single complete CBOR value as raw bytes.
 func (r *CborReader) ReadEncodedValue() ([]byte, error) {
 	start := r.offset
@@ -1256,3 +2270,13 @@ func (r *CborReader) ReadEncodedValue() ([]byte, error) {
 	copy(result, r.data[start:r.offset])
 	return result, nil
 }
+
+// ReadEncodedValueAppend reads a single complete CBOR value and appends its raw bytes to
+// dst, returning the extended slice. On error dst is returned unchanged.
+func (r *CborReader) ReadEncodedValueAppend(dst []byte) ([]byte, error) {
+	start := r.offset
+	if err := r.SkipValue(); err != nil {
+		return dst, err
+	}
+	return append(dst, r.data[start:r.offset]...), nil
+}
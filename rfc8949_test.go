@@ -2,6 +2,7 @@ package cbor
 
 import (
 	"encoding/hex"
+	"errors"
 	"testing"
 )
 
@@ -932,3 +933,51 @@ func TestWriterProducesCorrectCBOR(t *testing.T) {
 		})
 	}
 }
+
+// Arguments that do not use the shortest form (RFC 8949 Section 4.2.1) at each width
+// boundary. Strict readers reject the first vector of each pair and accept the second.
+func TestRFC8949NonMinimalArguments(t *testing.T) {
+	tests := []struct {
+		name       string
+		nonMinimal string
+		minimal    string
+	}{
+		{"uint_1byte", "1817", "1818"},
+		{"uint_1byte_zero", "1800", "1818"},
+		{"uint_2byte", "1900ff", "190100"},
+		{"uint_2byte_zero", "190000", "19ffff"},
+		{"uint_4byte", "1a0000ffff", "1a00010000"},
+		{"uint_4byte_zero", "1a00000000", "1affffffff"},
+		{"uint_8byte", "1b00000000ffffffff", "1b0000000100000000"},
+		{"uint_8byte_zero", "1b0000000000000000", "1bffffffffffffffff"},
+		{"nint_1byte", "3817", "3818"},
+		{"nint_2byte", "3900ff", "390100"},
+		{"nint_4byte", "3a0000ffff", "3a00010000"},
+		{"nint_8byte", "3b00000000ffffffff", "3b0000000100000000"},
+		{"bytes_length", "5800", "40"},
+		{"text_length", "79000161", "6161"},
+		{"array_length", "9a0000000100", "8100"},
+		{"map_length", "b8010000", "a10000"},
+		{"tag", "d81700", "d81800"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonMinimal, _ := hex.DecodeString(tt.nonMinimal)
+			minimal, _ := hex.DecodeString(tt.minimal)
+
+			if err := NewCborReader(nonMinimal).SkipValue(); err != nil {
+				t.Errorf("lax reader rejected %s: %v", tt.nonMinimal, err)
+			}
+			for _, mode := range []CborConformanceMode{ConformanceStrict, ConformanceCanonical, ConformanceCtap2Canonical} {
+				err := NewCborReader(nonMinimal, WithReaderConformanceMode(mode)).SkipValue()
+				if !errors.Is(err, ErrNonCanonical) {
+					t.Errorf("mode %d: expected ErrNonCanonical for %s, got %v", mode, tt.nonMinimal, err)
+				}
+				if err := NewCborReader(minimal, WithReaderConformanceMode(mode)).SkipValue(); err != nil {
+					t.Errorf("mode %d: rejected minimal %s: %v", mode, tt.minimal, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package cbor
+
+// WriteStartSet writes the beginning of a set (tag 258) of length elements. The elements
+// follow as array items and the set is closed with WriteEndSet.
+func (w *CborWriter) WriteStartSet(length int) error {
+	if err := w.WriteTag(TagSet); err != nil {
+		return err
+	}
+	return w.WriteStartArray(length)
+}
+
+// WriteEndSet writes the end of a set.
+func (w *CborWriter) WriteEndSet() error {
+	return w.WriteEndArray()
+}
+
+// ReadStartSet reads the beginning of a set (tag 258) and returns its length, or -1 for an
+// indefinite-length set. The elements are read as array items followed by ReadEndSet.
+func (r *CborReader) ReadStartSet() (int, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return 0, err
+	}
+	if tag != TagSet {
+		return 0, NewCborError(ErrInvalidCbor, r.offset, "expected set tag")
+	}
+	return r.ReadStartArray()
+}
+
+// ReadEndSet reads the end of a set.
+func (r *CborReader) ReadEndSet() error {
+	return r.ReadEndArray()
+}
+
+// ReadSet reads a whole set (tag 258) and returns the encoded form of each element. If
+// rejectDuplicates is true, two elements with identical encodings return
+// ErrDuplicateElement.
+func (r *CborReader) ReadSet(rejectDuplicates bool) ([][]byte, error) {
+	length, err := r.ReadStartSet()
+	if err != nil {
+		return nil, err
+	}
+
+	var elements [][]byte
+	var seen map[string]struct{}
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndArray)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+
+		start := r.offset
+		element, err := r.ReadEncodedValue()
+		if err != nil {
+			return nil, err
+		}
+		if rejectDuplicates {
+			if seen == nil {
+				seen = make(map[string]struct{})
+			}
+			if _, dup := seen[string(element)]; dup {
+				return nil, NewCborError(ErrDuplicateElement, start, "ReadSet")
+			}
+			seen[string(element)] = struct{}{}
+		}
+		elements = append(elements, element)
+	}
+
+	if err := r.ReadEndSet(); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
@@ -0,0 +1,87 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteStartSet(3); err != nil {
+		t.Fatalf("WriteStartSet failed: %v", err)
+	}
+	w.WriteInt32(1)
+	w.WriteTextString("a")
+	w.WriteInt32(2)
+	if err := w.WriteEndSet(); err != nil {
+		t.Fatalf("WriteEndSet failed: %v", err)
+	}
+
+	expected := "d9010283016161" + "02"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	r := NewCborReader(w.Bytes())
+	length, err := r.ReadStartSet()
+	if err != nil {
+		t.Fatalf("ReadStartSet failed: %v", err)
+	}
+	if length != 3 {
+		t.Errorf("expected length 3, got %d", length)
+	}
+	for i := 0; i < length; i++ {
+		if err := r.SkipValue(); err != nil {
+			t.Fatalf("SkipValue failed: %v", err)
+		}
+	}
+	if err := r.ReadEndSet(); err != nil {
+		t.Fatalf("ReadEndSet failed: %v", err)
+	}
+
+	elements, err := NewCborReader(w.Bytes()).ReadSet(true)
+	if err != nil {
+		t.Fatalf("ReadSet failed: %v", err)
+	}
+	want := []string{"01", "6161", "02"}
+	if len(elements) != len(want) {
+		t.Fatalf("expected %d elements, got %d", len(want), len(elements))
+	}
+	for i, e := range elements {
+		if got := hex.EncodeToString(e); got != want[i] {
+			t.Errorf("element %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestReadSetDuplicates(t *testing.T) {
+	for _, input := range []string{"d90102830102" + "01", "d901029f010201ff"} {
+		data, _ := hex.DecodeString(input)
+
+		elements, err := NewCborReader(data).ReadSet(false)
+		if err != nil {
+			t.Fatalf("%s: ReadSet failed: %v", input, err)
+		}
+		if len(elements) != 3 {
+			t.Errorf("%s: expected 3 elements, got %d", input, len(elements))
+		}
+
+		_, err = NewCborReader(data).ReadSet(true)
+		var cborErr *CborError
+		if !errors.Is(err, ErrDuplicateElement) || !errors.As(err, &cborErr) || cborErr.Offset != 6 {
+			t.Errorf("%s: expected ErrDuplicateElement at offset 6, got %v", input, err)
+		}
+	}
+
+	data, _ := hex.DecodeString("820102")
+	var mismatch *TypeMismatchError
+	if _, err := NewCborReader(data).ReadSet(false); !errors.As(err, &mismatch) {
+		t.Errorf("expected TypeMismatchError for untagged array, got %v", err)
+	}
+
+	data, _ = hex.DecodeString("d9010382")
+	if _, err := NewCborReader(data).ReadSet(false); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor for wrong tag, got %v", err)
+	}
+}
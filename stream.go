@@ -0,0 +1,108 @@
+package cbor
+
+import (
+	"errors"
+	"io"
+	"slices"
+)
+
+// maxEmptyReads is the number of consecutive empty reads after which a Decoder gives up
+// with io.ErrNoProgress.
+const maxEmptyReads = 100
+
+// An Encoder writes CBOR items to an output stream, one item per call to Encode.
+type Encoder struct {
+	w  io.Writer
+	cw *CborWriter
+}
+
+// NewEncoder returns an encoder that writes to w using a writer configured with opts.
+func NewEncoder(w io.Writer, opts ...WriterOption) *Encoder {
+	return &Encoder{w: w, cw: NewCborWriter(opts...)}
+}
+
+// Encode writes the encoding of v to the stream as a single item. See Marshal for the
+// mapping of Go values. Successive calls produce a CBOR sequence (RFC 8742).
+func (e *Encoder) Encode(v any) error {
+	e.cw.Reset()
+	if err := e.cw.WriteValue(v); err != nil {
+		return err
+	}
+	_, err := e.cw.WriteTo(e.w)
+	return err
+}
+
+// A Decoder reads successive CBOR items from an input stream, such as a CBOR sequence
+// (RFC 8742). It buffers input and may read past the item being decoded.
+type Decoder struct {
+	r   io.Reader
+	cr  *CborReader
+	buf []byte
+	off int   // start of the unread data in buf
+	err error // error from the last read of r
+}
+
+// NewDecoder returns a decoder that reads from r using a reader configured with opts.
+func NewDecoder(r io.Reader, opts ...ReaderOption) *Decoder {
+	return &Decoder{r: r, cr: NewCborReader(nil, opts...)}
+}
+
+// Decode reads the next item from the stream and stores it in the value pointed to by v.
+// See Unmarshal for the mapping of Go values. At the end of the stream it returns io.EOF;
+// a stream that ends inside an item returns ErrUnexpectedEndOfData. Other read errors are
+// returned as is.
+func (d *Decoder) Decode(v any) error {
+	n, err := d.nextItem()
+	if err != nil {
+		return err
+	}
+
+	d.cr.ResetWithData(d.buf[d.off : d.off+n])
+	d.off += n
+	return d.cr.ReadValue(v)
+}
+
+// nextItem buffers the next complete item and returns its length.
+func (d *Decoder) nextItem() (int, error) {
+	for {
+		if d.off < len(d.buf) {
+			d.cr.ResetWithData(d.buf[d.off:])
+			err := d.cr.SkipValue()
+			if err == nil {
+				return d.cr.offset, nil
+			}
+			if !errors.Is(err, ErrUnexpectedEndOfData) || d.err == io.EOF {
+				return 0, err
+			}
+		}
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.fill()
+	}
+}
+
+// fill discards consumed input and reads more from the stream, recording any read error.
+func (d *Decoder) fill() {
+	if d.off > 0 {
+		n := copy(d.buf, d.buf[d.off:])
+		d.buf = d.buf[:n]
+		d.off = 0
+	}
+	if len(d.buf) == cap(d.buf) {
+		d.buf = slices.Grow(d.buf, max(512, cap(d.buf)))
+	}
+
+	for i := 0; i < maxEmptyReads; i++ {
+		n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+n]
+		if err != nil {
+			d.err = err
+			return
+		}
+		if n > 0 {
+			return
+		}
+	}
+	d.err = io.ErrNoProgress
+}
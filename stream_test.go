@@ -0,0 +1,102 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+type streamRecord struct {
+	Name string `cbor:"name"`
+	Tags []int  `cbor:"tags"`
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, v := range []any{1, "a", []int{1, 2}} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	expected := "01616182" + "0102"
+	if got := hex.EncodeToString(buf.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	if err := NewEncoder(&buf).Encode(make(chan int)); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestDecoderSequence(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	records := []streamRecord{
+		{"first", []int{1, 2, 3}},
+		{"second", nil},
+		{"third", []int{1000000}},
+	}
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	readers := map[string]io.Reader{
+		"whole":    bytes.NewReader(buf.Bytes()),
+		"one_byte": iotest.OneByteReader(bytes.NewReader(buf.Bytes())),
+		"data_err": iotest.DataErrReader(bytes.NewReader(buf.Bytes())),
+	}
+	for name, src := range readers {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecoder(src)
+			for i, want := range records {
+				var got streamRecord
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("Decode %d failed: %v", i, err)
+				}
+				if got.Name != want.Name || len(got.Tags) != len(want.Tags) {
+					t.Errorf("record %d: expected %+v, got %+v", i, want, got)
+				}
+			}
+			var extra streamRecord
+			if err := dec.Decode(&extra); err != io.EOF {
+				t.Errorf("expected io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	data, _ := hex.DecodeString("01" + "8201")
+	dec := NewDecoder(bytes.NewReader(data))
+	var v int
+	if err := dec.Decode(&v); err != nil || v != 1 {
+		t.Fatalf("Decode: expected 1, got %d, %v", v, err)
+	}
+	var s []int
+	if err := dec.Decode(&s); !errors.Is(err, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+	}
+
+	readErr := errors.New("read failed")
+	dec = NewDecoder(io.MultiReader(bytes.NewReader([]byte{0x82, 0x01}), iotest.ErrReader(readErr)))
+	if err := dec.Decode(&s); !errors.Is(err, readErr) {
+		t.Errorf("expected read error, got %v", err)
+	}
+
+	data, _ = hex.DecodeString("1c")
+	if err := NewDecoder(bytes.NewReader(data)).Decode(&v); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor, got %v", err)
+	}
+
+	dec = NewDecoder(bytes.NewReader(nil), WithReaderConformanceMode(ConformanceCanonical))
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Errorf("expected io.EOF for empty stream, got %v", err)
+	}
+}
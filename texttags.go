@@ -0,0 +1,132 @@
+package cbor
+
+import (
+	"encoding/base64"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// WriteBase64URLText writes s as a text string inside tag 33, marking it as already
+// base64url encoded. The string is not validated.
+func (w *CborWriter) WriteBase64URLText(s string) error {
+	if err := w.WriteTag(TagBase64URL); err != nil {
+		return err
+	}
+	return w.WriteTextString(s)
+}
+
+// WriteBase64Text writes s as a text string inside tag 34, marking it as already base64
+// encoded. The string is not validated.
+func (w *CborWriter) WriteBase64Text(s string) error {
+	if err := w.WriteTag(TagBase64); err != nil {
+		return err
+	}
+	return w.WriteTextString(s)
+}
+
+// ReadBase64URLText reads a base64url text string (tag 33). If validate is true the string
+// must be unpadded base64url as required by RFC 8949.
+func (r *CborReader) ReadBase64URLText(validate bool) (string, error) {
+	return r.readBase64Text(TagBase64URL, base64.RawURLEncoding, validate)
+}
+
+// ReadBase64Text reads a base64 text string (tag 34). If validate is true the string must
+// be padded base64 as required by RFC 8949.
+func (r *CborReader) ReadBase64Text(validate bool) (string, error) {
+	return r.readBase64Text(TagBase64, base64.StdEncoding, validate)
+}
+
+// readBase64Text reads a text string inside tag, optionally checking that it decodes
+// with enc.
+func (r *CborReader) readBase64Text(tag CborTag, enc *base64.Encoding, validate bool) (string, error) {
+	got, err := r.readSemanticTag()
+	if err != nil {
+		return "", err
+	}
+	if got != tag {
+		return "", NewCborError(ErrInvalidCbor, r.offset, "expected base64 text tag")
+	}
+
+	start := r.offset
+	s, err := r.ReadTextString()
+	if err != nil {
+		return "", err
+	}
+	if validate {
+		if _, err := enc.Strict().DecodeString(s); err != nil {
+			return "", NewCborError(ErrInvalidCbor, start, "invalid base64 text")
+		}
+	}
+	return s, nil
+}
+
+// WriteRegexp writes a regular expression pattern as a text string inside tag 35.
+func (w *CborWriter) WriteRegexp(pattern string) error {
+	if err := w.WriteTag(TagRegularExpression); err != nil {
+		return err
+	}
+	return w.WriteTextString(pattern)
+}
+
+// ReadRegexp reads a regular expression pattern (tag 35) without compiling it.
+func (r *CborReader) ReadRegexp() (string, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return "", err
+	}
+	if tag != TagRegularExpression {
+		return "", NewCborError(ErrInvalidCbor, r.offset, "expected regular expression tag")
+	}
+	return r.ReadTextString()
+}
+
+// ReadCompiledRegexp reads a regular expression (tag 35) and compiles it with
+// regexp.Compile. Patterns Go cannot compile return ErrInvalidCbor.
+func (r *CborReader) ReadCompiledRegexp() (*regexp.Regexp, error) {
+	start := r.offset
+	pattern, err := r.ReadRegexp()
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, NewCborError(ErrInvalidCbor, start, err.Error())
+	}
+	return re, nil
+}
+
+// WriteMIMEMessage writes a MIME message (RFC 2045) as a text string inside tag 36.
+func (w *CborWriter) WriteMIMEMessage(msg string) error {
+	if err := w.WriteTag(TagMIMEMessage); err != nil {
+		return err
+	}
+	return w.WriteTextString(msg)
+}
+
+// ReadMIMEMessage reads a MIME message (tag 36) and returns its raw text.
+func (r *CborReader) ReadMIMEMessage() (string, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return "", err
+	}
+	if tag != TagMIMEMessage {
+		return "", NewCborError(ErrInvalidCbor, r.offset, "expected MIME message tag")
+	}
+	return r.ReadTextString()
+}
+
+// ReadParsedMIMEMessage reads a MIME message (tag 36) and parses its header with
+// mail.ReadMessage. Messages that cannot be parsed return ErrInvalidCbor.
+func (r *CborReader) ReadParsedMIMEMessage() (*mail.Message, error) {
+	start := r.offset
+	msg, err := r.ReadMIMEMessage()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(msg))
+	if err != nil {
+		return nil, NewCborError(ErrInvalidCbor, start, err.Error())
+	}
+	return parsed, nil
+}
@@ -0,0 +1,176 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBase64Text(t *testing.T) {
+	w := NewCborWriter()
+	w.WriteStartArray(2)
+	if err := w.WriteBase64URLText("AQL_"); err != nil {
+		t.Fatalf("WriteBase64URLText failed: %v", err)
+	}
+	if err := w.WriteBase64Text("AQL/"); err != nil {
+		t.Fatalf("WriteBase64Text failed: %v", err)
+	}
+	w.WriteEndArray()
+
+	expected := "82d8216441514c5fd8226441514c2f"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	r := NewCborReader(w.Bytes())
+	r.ReadStartArray()
+	s, err := r.ReadBase64URLText(true)
+	if err != nil {
+		t.Fatalf("ReadBase64URLText failed: %v", err)
+	}
+	if s != "AQL_" {
+		t.Errorf("expected AQL_, got %q", s)
+	}
+	s, err = r.ReadBase64Text(true)
+	if err != nil {
+		t.Fatalf("ReadBase64Text failed: %v", err)
+	}
+	if s != "AQL/" {
+		t.Errorf("expected AQL/, got %q", s)
+	}
+}
+
+func TestBase64TextValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   bool
+		text  string
+		valid bool
+	}{
+		{"url_unpadded", true, "AQI", true},
+		{"url_padded", true, "AQI=", false},
+		{"url_std_alphabet", true, "AQL/", false},
+		{"std_padded", false, "AQI=", true},
+		{"std_unpadded", false, "AQI", false},
+		{"std_url_alphabet", false, "AQL_", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter()
+			read := (*CborReader).ReadBase64Text
+			if tt.url {
+				w.WriteBase64URLText(tt.text)
+				read = (*CborReader).ReadBase64URLText
+			} else {
+				w.WriteBase64Text(tt.text)
+			}
+
+			if _, err := read(NewCborReader(w.Bytes()), false); err != nil {
+				t.Fatalf("unvalidated read failed: %v", err)
+			}
+			_, err := read(NewCborReader(w.Bytes()), true)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidCbor) {
+				t.Errorf("expected ErrInvalidCbor, got %v", err)
+			}
+		})
+	}
+
+	data, _ := hex.DecodeString("d8226441514c2f")
+	if _, err := NewCborReader(data).ReadBase64URLText(false); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor for wrong tag, got %v", err)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteRegexp("^a+$"); err != nil {
+		t.Fatalf("WriteRegexp failed: %v", err)
+	}
+	expected := "d823645e612b24"
+	if got := hex.EncodeToString(w.Bytes()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	pattern, err := NewCborReader(w.Bytes()).ReadRegexp()
+	if err != nil {
+		t.Fatalf("ReadRegexp failed: %v", err)
+	}
+	if pattern != "^a+$" {
+		t.Errorf("expected ^a+$, got %q", pattern)
+	}
+
+	re, err := NewCborReader(w.Bytes()).ReadCompiledRegexp()
+	if err != nil {
+		t.Fatalf("ReadCompiledRegexp failed: %v", err)
+	}
+	if !re.MatchString("aaa") || re.MatchString("ab") {
+		t.Errorf("compiled regexp %s matched unexpectedly", re)
+	}
+}
+
+func TestRegexpErrors(t *testing.T) {
+	w := NewCborWriter()
+	w.WriteRegexp("(")
+	if _, err := NewCborReader(w.Bytes()).ReadRegexp(); err != nil {
+		t.Errorf("ReadRegexp should not compile the pattern: %v", err)
+	}
+	if _, err := NewCborReader(w.Bytes()).ReadCompiledRegexp(); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor, got %v", err)
+	}
+
+	data, _ := hex.DecodeString("d820645e612b24")
+	if _, err := NewCborReader(data).ReadRegexp(); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor for wrong tag, got %v", err)
+	}
+}
+
+func TestMIMEMessage(t *testing.T) {
+	msg := "Subject: hi\r\n\r\nbody"
+	w := NewCborWriter()
+	if err := w.WriteMIMEMessage(msg); err != nil {
+		t.Fatalf("WriteMIMEMessage failed: %v", err)
+	}
+	if got := hex.EncodeToString(w.Bytes()[:3]); got != "d82473" {
+		t.Errorf("expected prefix d82473, got %s", got)
+	}
+
+	raw, err := NewCborReader(w.Bytes()).ReadMIMEMessage()
+	if err != nil {
+		t.Fatalf("ReadMIMEMessage failed: %v", err)
+	}
+	if raw != msg {
+		t.Errorf("expected %q, got %q", msg, raw)
+	}
+
+	parsed, err := NewCborReader(w.Bytes()).ReadParsedMIMEMessage()
+	if err != nil {
+		t.Fatalf("ReadParsedMIMEMessage failed: %v", err)
+	}
+	if subject := parsed.Header.Get("Subject"); subject != "hi" {
+		t.Errorf("expected subject hi, got %q", subject)
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	if string(body) != "body" {
+		t.Errorf("expected body, got %q", body)
+	}
+}
+
+func TestMIMEMessageErrors(t *testing.T) {
+	data, _ := hex.DecodeString("d8236161")
+	_, err := NewCborReader(data).ReadMIMEMessage()
+	var cborErr *CborError
+	if !errors.As(err, &cborErr) || !errors.Is(err, ErrInvalidCbor) || cborErr.Offset != 2 {
+		t.Errorf("expected ErrInvalidCbor at offset 2, got %v", err)
+	}
+
+	w := NewCborWriter()
+	w.WriteMIMEMessage("no header separator")
+	if _, err := NewCborReader(w.Bytes()).ReadParsedMIMEMessage(); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package cbor
+
+import (
+	"math"
+	"time"
+)
+
+// TimePrecision selects the resolution WriteTime encodes.
+type TimePrecision int
+
+const (
+	// TimePrecisionNanosecond keeps the full resolution of the time.
+	TimePrecisionNanosecond TimePrecision = iota
+	// TimePrecisionMillisecond truncates (or rounds) to whole milliseconds.
+	TimePrecisionMillisecond
+	// TimePrecisionSecond truncates (or rounds) to whole seconds, so tag 1 always
+	// carries an integer.
+	TimePrecisionSecond
+)
+
+// timeOptions holds the configuration of a WriteTime call.
+type timeOptions struct {
+	tag       CborTag
+	precision TimePrecision
+	round     bool
+}
+
+// TimeOption configures WriteTime.
+type TimeOption func(*timeOptions)
+
+// WithTimeTag selects TagDateTimeString (the default) or TagUnixTime.
+func WithTimeTag(tag CborTag) TimeOption {
+	return func(o *timeOptions) {
+		o.tag = tag
+	}
+}
+
+// WithTimePrecision selects the resolution of the encoded time.
+func WithTimePrecision(precision TimePrecision) TimeOption {
+	return func(o *timeOptions) {
+		o.precision = precision
+	}
+}
+
+// WithTimeRounding rounds to the selected precision instead of truncating.
+func WithTimeRounding() TimeOption {
+	return func(o *timeOptions) {
+		o.round = true
+	}
+}
+
+// WriteTime writes t as a tag 0 date/time string or a tag 1 epoch time. Epoch times use an
+// integer when t has no fractional second at the selected precision and a float otherwise.
+// Other tags return ErrInvalidCbor.
+func (w *CborWriter) WriteTime(t time.Time, opts ...TimeOption) error {
+	o := timeOptions{tag: TagDateTimeString}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var unit time.Duration
+	switch o.precision {
+	case TimePrecisionMillisecond:
+		unit = time.Millisecond
+	case TimePrecisionSecond:
+		unit = time.Second
+	}
+	if unit != 0 {
+		if o.round {
+			t = t.Round(unit)
+		} else {
+			t = t.Truncate(unit)
+		}
+	}
+
+	switch o.tag {
+	case TagDateTimeString:
+		return w.WriteDateTimeString(t)
+	case TagUnixTime:
+		return w.WriteUnixTime(t)
+	default:
+		return NewCborError(ErrInvalidCbor, len(w.buffer), "time tag must be 0 or 1")
+	}
+}
+
+// durationOptions holds the configuration of a WriteDuration or ReadDuration call.
+type durationOptions struct {
+	unit   time.Duration
+	tag    CborTag
+	hasTag bool
+}
+
+// DurationOption configures WriteDuration and ReadDuration.
+type DurationOption func(*durationOptions)
+
+// WithDurationUnit encodes durations as an integer count of unit, such as time.Second.
+// The default is time.Nanosecond. Writing truncates toward zero to a whole unit.
+func WithDurationUnit(unit time.Duration) DurationOption {
+	return func(o *durationOptions) {
+		o.unit = unit
+	}
+}
+
+// WithDurationTag wraps durations in tag. There is no standard tag for durations, so
+// this is for application-defined tags; reading then requires the tag.
+func WithDurationTag(tag CborTag) DurationOption {
+	return func(o *durationOptions) {
+		o.tag = tag
+		o.hasTag = true
+	}
+}
+
+// applyDurationOptions returns the configuration selected by opts, or ErrInvalidCbor if
+// the unit is not positive.
+func applyDurationOptions(opts []DurationOption, offset int) (durationOptions, error) {
+	o := durationOptions{unit: time.Nanosecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.unit <= 0 {
+		return o, NewCborError(ErrInvalidCbor, offset, "duration unit must be positive")
+	}
+	return o, nil
+}
+
+// WriteDuration writes d as an integer count of the configured unit (nanoseconds by
+// default), optionally inside an application tag.
+func (w *CborWriter) WriteDuration(d time.Duration, opts ...DurationOption) error {
+	o, err := applyDurationOptions(opts, len(w.buffer))
+	if err != nil {
+		return err
+	}
+
+	if o.hasTag {
+		if err := w.WriteTag(o.tag); err != nil {
+			return err
+		}
+	}
+	return w.WriteInt64(int64(d / o.unit))
+}
+
+// ReadDuration reads a duration written by WriteDuration with the same options. Counts
+// that do not fit in a time.Duration return ErrOverflow.
+func (r *CborReader) ReadDuration(opts ...DurationOption) (time.Duration, error) {
+	o, err := applyDurationOptions(opts, r.offset)
+	if err != nil {
+		return 0, err
+	}
+
+	if o.hasTag {
+		tag, err := r.readSemanticTag()
+		if err != nil {
+			return 0, err
+		}
+		if tag != o.tag {
+			return 0, NewCborError(ErrInvalidCbor, r.offset, "unexpected duration tag")
+		}
+	}
+
+	start := r.offset
+	count, err := r.ReadInt64()
+	if err != nil {
+		return 0, err
+	}
+	if count > math.MaxInt64/int64(o.unit) || count < math.MinInt64/int64(o.unit) {
+		return 0, NewCborError(ErrOverflow, start, "ReadDuration")
+	}
+	return time.Duration(count) * o.unit, nil
+}
+
+// Keys of a tag 1001 extended time map (RFC 9581). Negative keys are critical: a reader
+// that does not understand one must reject the time.
+const (
+	extendedTimeBase         = 1  // seconds since the epoch, integer or float
+	extendedTimeMilliseconds = -3 // fraction of a second, in milliseconds
+	extendedTimeMicroseconds = -6
+	extendedTimeNanoseconds  = -9
+	extendedTimeAttoseconds  = -18 // finest fraction defined by RFC 9581
+)
+
+// WriteExtendedTime writes t as a tag 1001 extended time: a map holding the epoch seconds
+// under key 1 and, when t has a fraction of a second, that fraction under key -3, -6 or -9
+// as milliseconds, microseconds or nanoseconds, whichever is the coarsest exact unit. The
+// location of t is not encoded.
+func (w *CborWriter) WriteExtendedTime(t time.Time) error {
+	nsec := int64(t.Nanosecond())
+	var fracKey, frac int64
+	switch {
+	case nsec == 0:
+	case nsec%1e6 == 0:
+		fracKey, frac = extendedTimeMilliseconds, nsec/1e6
+	case nsec%1e3 == 0:
+		fracKey, frac = extendedTimeMicroseconds, nsec/1e3
+	default:
+		fracKey, frac = extendedTimeNanoseconds, nsec
+	}
+
+	entries := 1
+	if fracKey != 0 {
+		entries = 2
+	}
+	if err := w.WriteTag(TagExtendedTime); err != nil {
+		return err
+	}
+	if err := w.WriteStartMap(entries); err != nil {
+		return err
+	}
+	if err := w.WriteInt64(extendedTimeBase); err != nil {
+		return err
+	}
+	if err := w.WriteInt64(t.Unix()); err != nil {
+		return err
+	}
+	if fracKey != 0 {
+		if err := w.WriteInt64(fracKey); err != nil {
+			return err
+		}
+		if err := w.WriteInt64(frac); err != nil {
+			return err
+		}
+	}
+	return w.WriteEndMap()
+}
+
+// ReadExtendedTime reads a tag 1001 extended time. The base time under key 1 is required
+// and may be an integer or a float. A fraction of a second may be given under key -3, -6,
+// ... -18; fractions finer than a nanosecond are truncated. Unknown positive keys are
+// skipped, while unknown negative (critical) keys return ErrInvalidCbor.
+func (r *CborReader) ReadExtendedTime() (time.Time, error) {
+	tag, err := r.readSemanticTag()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if tag != TagExtendedTime {
+		return time.Time{}, NewCborError(ErrInvalidCbor, r.offset, "expected extended time tag")
+	}
+
+	start := r.offset
+	length, err := r.ReadStartMap()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var base time.Time
+	var haveBase, haveFrac bool
+	var nsec int64
+	for i := 0; ; i++ {
+		more, err := r.moreItems(length, i, StateEndMap)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !more {
+			break
+		}
+
+		keyOffset := r.offset
+		key, err := r.ReadInt64()
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		switch {
+		case key == extendedTimeBase:
+			if haveBase {
+				return time.Time{}, NewCborError(ErrDuplicateKey, keyOffset, "ReadExtendedTime")
+			}
+			if base, err = r.readEpochTime(); err != nil {
+				return time.Time{}, err
+			}
+			haveBase = true
+
+		case key < 0 && key >= extendedTimeAttoseconds && key%3 == 0:
+			if haveFrac {
+				return time.Time{}, NewCborError(ErrInvalidCbor, keyOffset, "more than one extended time fraction")
+			}
+			valueOffset := r.offset
+			frac, err := r.ReadUint64()
+			if err != nil {
+				return time.Time{}, err
+			}
+			digits := int(-key)
+			if frac >= uint64(math.Pow10(digits)) {
+				return time.Time{}, NewCborError(ErrInvalidCbor, valueOffset, "extended time fraction out of range")
+			}
+			if digits <= 9 {
+				nsec = int64(frac) * int64(math.Pow10(9-digits))
+			} else {
+				nsec = int64(frac / uint64(math.Pow10(digits-9)))
+			}
+			haveFrac = true
+
+		case key < 0:
+			return time.Time{}, NewCborError(ErrInvalidCbor, keyOffset, "unsupported critical extended time key")
+
+		default:
+			if err := r.SkipValue(); err != nil {
+				return time.Time{}, err
+			}
+		}
+	}
+
+	if err := r.ReadEndMap(); err != nil {
+		return time.Time{}, err
+	}
+	if !haveBase {
+		return time.Time{}, NewCborError(ErrInvalidCbor, start, "extended time without base time")
+	}
+	return base.Add(time.Duration(nsec)), nil
+}
@@ -0,0 +1,274 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteTime(t *testing.T) {
+	// 2013-03-21T20:04:00.7505Z
+	at := time.Unix(1363896240, 750500000).UTC()
+
+	tests := []struct {
+		name     string
+		opts     []TimeOption
+		expected string
+	}{
+		{"default", nil, "c07819323031332d30332d32315432303a30343a30302e373530355a"},
+		{"string seconds", []TimeOption{WithTimePrecision(TimePrecisionSecond)}, "c074323031332d30332d32315432303a30343a30305a"},
+		{"epoch float", []TimeOption{WithTimeTag(TagUnixTime)}, "c1fb41d452d9ec300831"},
+		{"epoch seconds", []TimeOption{WithTimeTag(TagUnixTime), WithTimePrecision(TimePrecisionSecond)}, "c11a514b67b0"},
+		{"epoch seconds rounded", []TimeOption{WithTimeTag(TagUnixTime), WithTimePrecision(TimePrecisionSecond), WithTimeRounding()}, "c11a514b67b1"},
+		{"epoch milliseconds", []TimeOption{WithTimeTag(TagUnixTime), WithTimePrecision(TimePrecisionMillisecond)}, "c1fb41d452d9ec300000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := w.WriteTime(at, tt.opts...); err != nil {
+				t.Fatalf("WriteTime failed: %v", err)
+			}
+			expected, _ := hex.DecodeString(tt.expected)
+			if !bytes.Equal(w.Bytes(), expected) {
+				t.Errorf("expected %s, got %x", tt.expected, w.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriteTimeInvalidTag(t *testing.T) {
+	w := NewCborWriter()
+	if err := w.WriteTime(time.Now(), WithTimeTag(TagURI)); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor, got %v", err)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	d := 90*time.Second + 500*time.Millisecond
+
+	tests := []struct {
+		name     string
+		opts     []DurationOption
+		expected string
+		decoded  time.Duration
+	}{
+		{"nanoseconds", nil, "1b0000001512386900", d},
+		{"milliseconds", []DurationOption{WithDurationUnit(time.Millisecond)}, "1a00016184", d},
+		{"seconds truncated", []DurationOption{WithDurationUnit(time.Second)}, "185a", 90 * time.Second},
+		{"tagged", []DurationOption{WithDurationUnit(time.Second), WithDurationTag(1002)}, "d903ea185a", 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := w.WriteDuration(d, tt.opts...); err != nil {
+				t.Fatalf("WriteDuration failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+
+			got, err := NewCborReader(w.Bytes()).ReadDuration(tt.opts...)
+			if err != nil {
+				t.Fatalf("ReadDuration failed: %v", err)
+			}
+			if got != tt.decoded {
+				t.Errorf("expected %v, got %v", tt.decoded, got)
+			}
+		})
+	}
+
+	w := NewCborWriter()
+	w.WriteDuration(-1500*time.Millisecond, WithDurationUnit(time.Second))
+	if got := hex.EncodeToString(w.Bytes()); got != "20" {
+		t.Errorf("expected negative duration to truncate toward zero, got %s", got)
+	}
+}
+
+func TestDurationErrors(t *testing.T) {
+	if err := NewCborWriter().WriteDuration(time.Second, WithDurationUnit(0)); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor for zero unit, got %v", err)
+	}
+
+	data, _ := hex.DecodeString("1b0000000100000000") // 2^32 hours
+	if _, err := NewCborReader(data).ReadDuration(WithDurationUnit(time.Hour)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+
+	data, _ = hex.DecodeString("d903eb185a")
+	if _, err := NewCborReader(data).ReadDuration(WithDurationTag(1002)); !errors.Is(err, ErrInvalidCbor) {
+		t.Errorf("expected ErrInvalidCbor for wrong tag, got %v", err)
+	}
+}
+
+func TestReaderDateTimeFormats(t *testing.T) {
+	w := NewCborWriter()
+	w.WriteTag(TagDateTimeString)
+	w.WriteTextString("2013-03-21 20:04:00")
+	data := w.BytesCopy()
+
+	if _, err := NewCborReader(data).ReadDateTimeString(); err == nil {
+		t.Errorf("expected the default RFC 3339 layout to reject a space separator")
+	}
+
+	r := NewCborReader(data, WithReaderDateTimeFormats(time.RFC3339Nano, "2006-01-02 15:04:05"))
+	got, err := r.ReadDateTimeString()
+	if err != nil {
+		t.Fatalf("ReadDateTimeString failed: %v", err)
+	}
+	if want := time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	w = NewCborWriter()
+	w.WriteDateTimeString(time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC))
+	if _, err := NewCborReader(w.Bytes(), WithReaderDateTimeFormats(time.RFC3339Nano, "2006-01-02 15:04:05")).ReadDateTimeString(); err != nil {
+		t.Errorf("expected the first layout to parse RFC 3339, got %v", err)
+	}
+	if _, err := NewCborReader(w.Bytes(), WithReaderDateTimeFormats("2006-01-02 15:04:05")).ReadDateTimeString(); err == nil {
+		t.Errorf("expected an error when no layout matches")
+	}
+}
+
+func TestWriteDateTimeStringForms(t *testing.T) {
+	zone := time.FixedZone("", 2*60*60)
+	rfcVector := "c074323031332d30332d32315432303a30343a30305a" // 0("2013-03-21T20:04:00Z")
+
+	tests := []struct {
+		name string
+		opts []WriterOption
+		t    time.Time
+		want string
+	}{
+		{"rfc_vector", nil, time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), rfcVector},
+		{"offset_kept", nil, time.Date(2013, 3, 21, 22, 4, 0, 0, zone), "2013-03-21T22:04:00+02:00"},
+		{"utc_option", []WriterOption{WithWriterDateTimeUTC(true)}, time.Date(2013, 3, 21, 22, 4, 0, 0, zone), rfcVector},
+		{"canonical_utc", []WriterOption{WithConformanceMode(ConformanceCanonical)}, time.Date(2013, 3, 21, 22, 4, 0, 0, zone), rfcVector},
+		{"trailing_zeros_dropped", nil, time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC), "2013-03-21T20:04:00.5Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter(tt.opts...)
+			if err := w.WriteDateTimeString(tt.t); err != nil {
+				t.Fatalf("WriteDateTimeString failed: %v", err)
+			}
+			if tt.want == rfcVector {
+				if got := hex.EncodeToString(w.Bytes()); got != rfcVector {
+					t.Errorf("expected %s, got %s", rfcVector, got)
+				}
+				return
+			}
+			r := NewCborReader(w.Bytes())
+			r.ReadTag()
+			if s, _ := r.ReadTextString(); s != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, s)
+			}
+		})
+	}
+
+	far := time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, mode := range []CborConformanceMode{ConformanceStrict, ConformanceCanonical, ConformanceCtap2Canonical} {
+		w := NewCborWriter(WithConformanceMode(mode))
+		if err := w.WriteDateTimeString(far); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("mode %d: expected ErrInvalidDate for year 10000, got %v", mode, err)
+		}
+		if w.Len() != 0 {
+			t.Errorf("mode %d: expected nothing written, got %x", mode, w.Bytes())
+		}
+	}
+
+	// lax writers keep writing whatever time.Format produces
+	w := NewCborWriter()
+	if err := w.WriteDateTimeString(far); err != nil {
+		t.Errorf("lax: unexpected error for year 10000: %v", err)
+	}
+}
+
+func TestExtendedTime(t *testing.T) {
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		// RFC 9581 Section 3: 1001({1: 1363896240, -3: 500})
+		{"milliseconds", time.Unix(1363896240, 500000000), "d903e9a2011a514b67b0221901f4"},
+		{"whole_seconds", time.Unix(1363896240, 0), "d903e9a1011a514b67b0"},
+		{"microseconds", time.Unix(1363896240, 1000), "d903e9a2011a514b67b02501"},
+		{"nanoseconds", time.Unix(1363896240, 1), "d903e9a2011a514b67b02801"},
+		{"before_epoch", time.Unix(-2, 250000000), "d903e9a201212218fa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCborWriter()
+			if err := w.WriteExtendedTime(tt.at); err != nil {
+				t.Fatalf("WriteExtendedTime failed: %v", err)
+			}
+			if got := hex.EncodeToString(w.Bytes()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			got, err := NewCborReader(w.Bytes()).ReadExtendedTime()
+			if err != nil {
+				t.Fatalf("ReadExtendedTime failed: %v", err)
+			}
+			if !got.Equal(tt.at) {
+				t.Errorf("expected %v, got %v", tt.at, got)
+			}
+		})
+	}
+}
+
+func TestReadExtendedTimeForms(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want time.Time
+	}{
+		{"float_base", "d903e9a101fb41d452d9ec200000", time.Unix(1363896240, 500000000)},
+		{"picoseconds_truncated", "d903e9a2011a514b67b02b1a000f4246", time.Unix(1363896240, 1000)},
+		{"unknown_positive_key_skipped", "d903e9a2011a514b67b00a6141", time.Unix(1363896240, 0)},
+		{"indefinite_map", "d903e9bf011a514b67b0ff", time.Unix(1363896240, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			got, err := NewCborReader(data).ReadExtendedTime()
+			if err != nil {
+				t.Fatalf("ReadExtendedTime failed: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadExtendedTimeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"wrong_tag", "c11a514b67b0", ErrInvalidCbor},
+		{"missing_base", "d903e9a1221901f4", ErrInvalidCbor},
+		{"critical_key", "d903e9a2011a514b67b02000", ErrInvalidCbor},
+		{"fraction_out_of_range", "d903e9a2011a514b67b0221903e8", ErrInvalidCbor},
+		{"two_fractions", "d903e9a3011a514b67b022012501", ErrInvalidCbor},
+		{"duplicate_base", "d903e9a201000101", ErrDuplicateKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			if _, err := NewCborReader(data).ReadExtendedTime(); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package cbor
+
+// ValidateRoot checks that data holds exactly one well-formed CBOR item whose major type
+// is expected. Decoding follows the reader options, so conformance and nesting limits apply
+// and a self-described CBOR tag stripped by WithReaderStripSelfDescribe is not the root.
+func ValidateRoot(data []byte, expected MajorType, opts ...ReaderOption) error {
+	r := NewCborReader(data, opts...)
+	if r.BytesRemaining() == 0 {
+		return NewCborError(ErrUnexpectedEndOfData, r.offset, "empty document")
+	}
+
+	if mt, _ := decodeInitialByte(r.data[r.offset]); mt != expected {
+		return NewCborError(ErrInvalidMajorType, r.offset, "expected root "+expected.String()+" but got "+mt.String())
+	}
+
+	if err := r.SkipValue(); err != nil {
+		return err
+	}
+	if r.BytesRemaining() > 0 {
+		return NewCborError(ErrNotAtEnd, r.offset, "")
+	}
+	return nil
+}
+
+// checkMapKeys walks the next item and verifies the keys of every map it contains.
+// Canonical modes require keys in the writer's sort order (bytewise, or length-first for
+// CTAP2), which also rules out duplicates; other modes only reject duplicate keys.
+func (r *CborReader) checkMapKeys() error {
+	state, err := r.PeekState()
+	if err != nil {
+		return err
+	}
+	for state == StateTag {
+		if _, err := r.ReadTag(); err != nil {
+			return err
+		}
+		if state, err = r.PeekState(); err != nil {
+			return err
+		}
+	}
+
+	switch state {
+	case StateStartArray:
+		length, err := r.ReadStartArray()
+		if err != nil {
+			return err
+		}
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, StateEndArray)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			if err := r.checkMapKeys(); err != nil {
+				return err
+			}
+		}
+		return r.ReadEndArray()
+
+	case StateStartMap:
+		length, err := r.ReadStartMap()
+		if err != nil {
+			return err
+		}
+		canonical := r.conformanceMode >= ConformanceCanonical
+		var seen map[string]struct{}
+		var prev []byte
+		for i := 0; ; i++ {
+			more, err := r.moreItems(length, i, StateEndMap)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+
+			start := r.offset
+			if err := r.checkMapKeys(); err != nil {
+				return err
+			}
+			key := r.data[start:r.offset]
+			if canonical {
+				if i > 0 {
+					switch c := compareMapKeys(prev, key, r.conformanceMode); {
+					case c == 0:
+						return NewCborError(ErrDuplicateKey, start, "")
+					case c > 0:
+						return NewCborError(ErrUnsortedKeys, start, "")
+					}
+				}
+				prev = key
+			} else {
+				if seen == nil {
+					seen = make(map[string]struct{})
+				}
+				if _, dup := seen[string(key)]; dup {
+					return NewCborError(ErrDuplicateKey, start, "")
+				}
+				seen[string(key)] = struct{}{}
+			}
+
+			if err := r.checkMapKeys(); err != nil {
+				return err
+			}
+		}
+		return r.ReadEndMap()
+
+	default:
+		return r.SkipValue()
+	}
+}
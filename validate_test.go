@@ -0,0 +1,68 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestValidateRoot(t *testing.T) {
+	tests := []struct {
+		name     string
+		hex      string
+		expected MajorType
+		err      error
+	}{
+		{"map", "a16161820102", MajorTypeMap, nil},
+		{"tagged", "d82063666f6f", MajorTypeTag, nil},
+		{"wrong_type", "820102", MajorTypeMap, ErrInvalidMajorType},
+		{"trailing_data", "a000", MajorTypeMap, ErrNotAtEnd},
+		{"truncated", "a16161", MajorTypeMap, ErrUnexpectedEndOfData},
+		{"empty", "", MajorTypeMap, ErrUnexpectedEndOfData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			err := ValidateRoot(data, tt.expected)
+			if tt.err == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateRootAppliesReaderOptions(t *testing.T) {
+	data, _ := hex.DecodeString("bf6161f6ff")
+	if err := ValidateRoot(data, MajorTypeMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := ValidateRoot(data, MajorTypeMap, WithReaderConformanceMode(ConformanceCanonical))
+	if !errors.Is(err, ErrIndefiniteLengthNotAllowed) {
+		t.Errorf("expected ErrIndefiniteLengthNotAllowed, got %v", err)
+	}
+}
+
+func TestValidateRootStripSelfDescribe(t *testing.T) {
+	data, _ := hex.DecodeString("d9d9f7a0")
+	if err := ValidateRoot(data, MajorTypeMap, WithReaderStripSelfDescribe(true)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateRoot(data, MajorTypeMap); !errors.Is(err, ErrInvalidMajorType) {
+		t.Errorf("expected ErrInvalidMajorType without stripping, got %v", err)
+	}
+	if err := ValidateRoot(data, MajorTypeTag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	data, _ = hex.DecodeString("d9d9f7")
+	if err := ValidateRoot(data, MajorTypeMap, WithReaderStripSelfDescribe(true)); !errors.Is(err, ErrUnexpectedEndOfData) {
+		t.Errorf("expected ErrUnexpectedEndOfData, got %v", err)
+	}
+}
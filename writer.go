@@ -1,9 +1,13 @@
 package cbor
 
 import (
-	"encoding/binary"
+	"bytes"
+	"io"
 	"math"
 	"math/big"
+	"reflect"
+	"slices"
+	"sort"
 	"time"
 )
 
@@ -16,6 +20,19 @@ type CborWriter struct {
 	currentOffset           int
 	allowMultipleRootValues bool
 	rootValueWritten        bool
+	extraRootValue          bool // more than one root value was written
+	deterministicMaps       bool
+	floatMode               FloatMode
+	dateTimeUTC             bool
+	enumNames               map[reflect.Type]map[int]string
+	generation              uint64       // incremented by Reset to invalidate checkpoints
+	editSeq                 uint64       // sequence number of the next entry in edits
+	edits                   []bufferEdit // changes to written bytes that checkpoints may span
+	tagPending              bool         // a tag was written and its content has not started
+	templateKeys            []any
+	keyTemplate             *keyTemplate
+	ptrLevel                int              // pointers being encoded by encodeValue
+	ptrSeen                 map[any]struct{} // pointers on the encodeValue path past maxPtrLevel
 }
 
 // nestingInfo tracks the state of nested containers.
@@ -26,6 +43,26 @@ type nestingInfo struct {
 	isMap          bool
 	keyWritten     bool // for maps, tracks if we're expecting a value
 	isIndefinite   bool
+	start          int        // buffer offset of the container header
+	contentStart   int        // buffer offset of the first item
+	nextKeyStart   int        // for sorted maps, buffer offset where the next key begins
+	entries        []mapEntry // for sorted maps, the encoded key/value pairs written so far
+}
+
+// mapEntry records the position of an encoded map key/value pair within the buffer.
+type mapEntry struct {
+	start  int
+	keyEnd int
+	end    int
+}
+
+// bufferEdit records that written bytes were rewritten in place from start to end, as when
+// a map is sorted, or cut at start when end equals start. Edits are kept in order with
+// nondecreasing ends; an edit that covers earlier ones replaces them.
+type bufferEdit struct {
+	seq   uint64
+	start int
+	end   int
 }
 
 // WriterOption is a function that configures a CborWriter.
@@ -45,6 +82,15 @@ func WithInitialCapacity(capacity int) WriterOption {
 	}
 }
 
+// WithSizeHint ensures the buffer has room for n bytes of output, like calling Grow before
+// the first write. Unlike WithInitialCapacity it keeps a larger existing buffer, such as
+// that of a pooled writer.
+func WithSizeHint(n int) WriterOption {
+	return func(w *CborWriter) {
+		w.Grow(n)
+	}
+}
+
 // WithMaxNestingDepth sets the maximum nesting depth.
 func WithMaxNestingDepth(depth int) WriterOption {
 	return func(w *CborWriter) {
@@ -59,6 +105,45 @@ func WithAllowMultipleRootValues(allow bool) WriterOption {
 	}
 }
 
+// WithWriterDeterministicMaps sorts map keys by their encoded bytes (bytewise lexicographic)
+// without enabling the other canonical checks, so repeated identical inputs always produce
+// identical output. Canonical modes always sort keys.
+func WithWriterDeterministicMaps() WriterOption {
+	return func(w *CborWriter) {
+		w.deterministicMaps = true
+	}
+}
+
+// FloatMode selects how WriteFloat and reflection-based encoding choose a float width.
+type FloatMode int
+
+const (
+	// FloatShortest writes the smallest width that represents the value exactly (the default).
+	FloatShortest FloatMode = iota
+	// FloatAlwaysDouble writes every float as a double-precision float.
+	FloatAlwaysDouble
+	// FloatPreserveInput writes the width of the Go value: float32 values as single and
+	// float64 values as double precision.
+	FloatPreserveInput
+)
+
+// WithWriterFloatMode sets how floats are sized by WriteFloat and reflection-based
+// encoding. WriteFloat16, WriteFloat32 and WriteFloat64 always write their own width, and
+// canonical modes always use FloatShortest.
+func WithWriterFloatMode(mode FloatMode) WriterOption {
+	return func(w *CborWriter) {
+		w.floatMode = mode
+	}
+}
+
+// WithWriterDateTimeUTC makes WriteDateTimeString convert times to UTC, so they end in
+// "Z" instead of a numeric offset. Canonical modes always do this.
+func WithWriterDateTimeUTC(enable bool) WriterOption {
+	return func(w *CborWriter) {
+		w.dateTimeUTC = enable
+	}
+}
+
 // NewCborWriter creates a new CborWriter with the specified options.
 func NewCborWriter(opts ...WriterOption) *CborWriter {
 	w := &CborWriter{
@@ -67,12 +152,20 @@ func NewCborWriter(opts ...WriterOption) *CborWriter {
 		nestingStack:    make([]nestingInfo, 0, 16),
 		maxNestingDepth: 64,
 	}
+	w.applyOptions(opts)
+	return w
+}
 
+// applyOptions applies opts and derives the state that depends on them.
+func (w *CborWriter) applyOptions(opts []WriterOption) {
 	for _, opt := range opts {
 		opt(w)
 	}
 
-	return w
+	if w.templateKeys != nil {
+		w.keyTemplate = w.buildKeyTemplate(w.templateKeys)
+		w.templateKeys = nil
+	}
 }
 
 // Reset clears the writer for reuse.
@@ -81,6 +174,258 @@ func (w *CborWriter) Reset() {
 	w.nestingStack = w.nestingStack[:0]
 	w.currentOffset = 0
 	w.rootValueWritten = false
+	w.extraRootValue = false
+	w.tagPending = false
+	w.generation++
+	w.edits = w.edits[:0]
+}
+
+// recordEdit notes that the bytes from start to end were rewritten in place, or that the
+// buffer was cut at start when end equals start.
+func (w *CborWriter) recordEdit(start, end int) {
+	for len(w.edits) > 0 && w.edits[len(w.edits)-1].end > start {
+		w.edits = w.edits[:len(w.edits)-1]
+	}
+	w.edits = append(w.edits, bufferEdit{seq: w.editSeq, start: start, end: end})
+	w.editSeq++
+}
+
+// editedSince reports whether an edit numbered seq or later changed bytes before length.
+func (w *CborWriter) editedSince(seq uint64, length int) bool {
+	for i := len(w.edits) - 1; i >= 0 && w.edits[i].seq >= seq; i-- {
+		if w.edits[i].start < length {
+			return true
+		}
+	}
+	return false
+}
+
+// WriterCheckpoint captures the complete writer state so a speculative write can be undone.
+type WriterCheckpoint struct {
+	length           int
+	nestingStack     []nestingInfo
+	rootValueWritten bool
+	extraRootValue   bool
+	tagPending       bool
+	generation       uint64
+	editSeq          uint64
+}
+
+// Checkpoint captures the current writer state for use with Rollback.
+func (w *CborWriter) Checkpoint() WriterCheckpoint {
+	stack := make([]nestingInfo, len(w.nestingStack))
+	for i, info := range w.nestingStack {
+		stack[i] = info
+		stack[i].entries = append([]mapEntry(nil), info.entries...)
+	}
+	return WriterCheckpoint{
+		length:           len(w.buffer),
+		nestingStack:     stack,
+		rootValueWritten: w.rootValueWritten,
+		extraRootValue:   w.extraRootValue,
+		tagPending:       w.tagPending,
+		generation:       w.generation,
+		editSeq:          w.editSeq,
+	}
+}
+
+// Rollback restores the writer to a state captured by Checkpoint, discarding everything
+// written since. It returns ErrInvalidState if, in the meantime, the writer was reset,
+// truncated or rolled back below the checkpoint, or a map open at the checkpoint was
+// closed and its keys were reordered.
+func (w *CborWriter) Rollback(c WriterCheckpoint) error {
+	if c.generation != w.generation || c.length > len(w.buffer) || w.editedSince(c.editSeq, c.length) {
+		return ErrInvalidState
+	}
+	if c.length < len(w.buffer) {
+		w.recordEdit(c.length, c.length)
+	}
+
+	w.buffer = w.buffer[:c.length]
+	w.currentOffset = c.length
+	w.nestingStack = w.nestingStack[:0]
+	for _, info := range c.nestingStack {
+		info.entries = append([]mapEntry(nil), info.entries...)
+		w.nestingStack = append(w.nestingStack, info)
+	}
+	w.rootValueWritten = c.rootValueWritten
+	w.extraRootValue = c.extraRootValue
+	w.tagPending = c.tagPending
+	return nil
+}
+
+// Bookmark returns the current buffer length for use with TruncateTo.
+func (w *CborWriter) Bookmark() int {
+	return len(w.buffer)
+}
+
+// TruncateTo discards everything written after mark, a value previously returned by Bookmark,
+// and pops any containers opened after it. The container state at mark is rebuilt from
+// the encoded bytes, so a definite-length container whose items were all written before
+// mark is treated as closed. It returns ErrInvalidState if mark is out of range, does not
+// fall on an item boundary, or lies inside a map whose keys were reordered when it was closed.
+func (w *CborWriter) TruncateTo(mark int) error {
+	if mark < 0 || mark > len(w.buffer) {
+		return ErrInvalidState
+	}
+	for i := len(w.edits) - 1; i >= 0 && w.edits[i].end > mark; i-- {
+		if w.edits[i].start < mark {
+			return ErrInvalidState
+		}
+	}
+
+	// Containers opened before mark and still open now were open at mark as well,
+	// and their parents were not modified since. Only the innermost one needs rebuilding.
+	depth := 0
+	for depth < len(w.nestingStack) && w.nestingStack[depth].start < mark {
+		depth++
+	}
+
+	rebuilt := *w
+	rebuilt.tagPending = false
+	rebuilt.nestingStack = make([]nestingInfo, depth, max(depth, 16))
+	copy(rebuilt.nestingStack, w.nestingStack[:depth])
+
+	scanStart := 0
+	if depth > 0 {
+		info := &rebuilt.nestingStack[depth-1]
+		info.itemsWritten = 0
+		info.keyWritten = false
+		info.entries = nil
+		info.nextKeyStart = info.contentStart
+		scanStart = info.contentStart
+	} else {
+		rebuilt.rootValueWritten = false
+		rebuilt.extraRootValue = false
+	}
+
+	if err := rebuilt.replay(w.buffer[:mark], scanStart, depth); err != nil {
+		return err
+	}
+
+	cut := mark < len(w.buffer)
+	*w = rebuilt
+	if cut {
+		w.recordEdit(mark, mark)
+	}
+	w.buffer = w.buffer[:mark]
+	w.currentOffset = mark
+	return nil
+}
+
+// replay rebuilds the writer's container state from the encoded items in data[pos:].
+// Containers below floor are known to be open and are never closed.
+func (w *CborWriter) replay(data []byte, pos, floor int) error {
+	for pos < len(data) {
+		w.buffer = data[:pos]
+
+		if data[pos] == breakByte {
+			if len(w.nestingStack) <= floor || !w.nestingStack[len(w.nestingStack)-1].isIndefinite {
+				return ErrInvalidState
+			}
+			pos++
+			w.buffer = data[:pos]
+			w.nestingStack = w.nestingStack[:len(w.nestingStack)-1]
+			w.advanceContainer()
+			w.closeFullContainers(floor)
+			continue
+		}
+
+		mt, ai, arg, n, err := decodeHeader(data[pos:])
+		if err != nil {
+			return ErrInvalidState
+		}
+		start := pos
+		pos += n
+		indefinite := ai == byte(AdditionalInfoIndefiniteLength)
+		w.tagPending = mt == MajorTypeTag
+
+		switch mt {
+		case MajorTypeByteString, MajorTypeTextString:
+			if indefinite {
+				w.nestingStack = append(w.nestingStack, nestingInfo{
+					majorType:      mt,
+					definiteLength: -1,
+					isIndefinite:   true,
+					start:          start,
+					contentStart:   pos,
+				})
+				continue
+			}
+			if uint64(len(data)-pos) < arg {
+				return ErrInvalidState
+			}
+			pos += int(arg)
+			if len(w.nestingStack) > 0 && w.nestingStack[len(w.nestingStack)-1].isIndefinite &&
+				w.nestingStack[len(w.nestingStack)-1].majorType == mt {
+				// A chunk of an indefinite-length string
+				continue
+			}
+		case MajorTypeArray, MajorTypeMap:
+			info := nestingInfo{
+				majorType:      mt,
+				definiteLength: int64(arg),
+				isMap:          mt == MajorTypeMap,
+				isIndefinite:   indefinite,
+				start:          start,
+				contentStart:   pos,
+				nextKeyStart:   pos,
+			}
+			if indefinite {
+				info.definiteLength = -1
+			}
+			w.nestingStack = append(w.nestingStack, info)
+			w.buffer = data[:pos]
+			w.closeFullContainers(floor)
+			continue
+		case MajorTypeTag:
+			// Tags are prefixes of the value that follows
+			continue
+		case MajorTypeSimpleOrFloat:
+			if ai > 27 {
+				return ErrInvalidState
+			}
+		}
+
+		w.buffer = data[:pos]
+		w.advanceContainer()
+		w.closeFullContainers(floor)
+	}
+
+	w.buffer = data
+	return nil
+}
+
+// closeFullContainers pops definite-length containers above floor whose items have all been written.
+func (w *CborWriter) closeFullContainers(floor int) {
+	for len(w.nestingStack) > floor {
+		info := &w.nestingStack[len(w.nestingStack)-1]
+		if info.isIndefinite || info.itemsWritten < info.definiteLength {
+			return
+		}
+		w.nestingStack = w.nestingStack[:len(w.nestingStack)-1]
+		w.advanceContainer()
+	}
+}
+
+// Finish checks that the writer holds complete output before Bytes is used. It returns
+// ErrIncompleteContainer if a container or indefinite-length string is still open,
+// ErrInvalidState if nothing was written or a tag has no content, and ErrNotAtEnd if more
+// than one root value was written without WithAllowMultipleRootValues.
+func (w *CborWriter) Finish() error {
+	if err := w.checkNoPendingTag(); err != nil {
+		return err
+	}
+	if len(w.nestingStack) > 0 {
+		return ErrIncompleteContainer
+	}
+	if !w.rootValueWritten {
+		return ErrInvalidState
+	}
+	if w.extraRootValue && !w.allowMultipleRootValues {
+		return ErrNotAtEnd
+	}
+	return nil
 }
 
 // Bytes returns the encoded CBOR data.
@@ -95,6 +440,26 @@ func (w *CborWriter) BytesCopy() []byte {
 	return result
 }
 
+// WriteTo writes the encoded data to dst, implementing io.WriterTo. The buffer is left
+// intact; call Reset to reuse the writer. A short write without an error from dst returns
+// io.ErrShortWrite.
+func (w *CborWriter) WriteTo(dst io.Writer) (int64, error) {
+	n, err := dst.Write(w.buffer)
+	if err == nil && n < len(w.buffer) {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
+
+// Grow grows the buffer's capacity, if necessary, to guarantee space for another n bytes,
+// so that n bytes can be written without another allocation. It panics if n is negative.
+func (w *CborWriter) Grow(n int) {
+	if n < 0 {
+		panic("cbor.CborWriter.Grow: negative count")
+	}
+	w.buffer = slices.Grow(w.buffer, n)
+}
+
 // Len returns the current length of the encoded data.
 func (w *CborWriter) Len() int {
 	return len(w.buffer)
@@ -113,9 +478,28 @@ func (w *CborWriter) checkNestingDepth() error {
 	return nil
 }
 
+// pushContainer opens a container or indefinite-length string, which also starts the
+// content of any pending tag.
+func (w *CborWriter) pushContainer(info nestingInfo) {
+	w.tagPending = false
+	w.nestingStack = append(w.nestingStack, info)
+}
+
+// checkNoPendingTag returns ErrInvalidState if a tag is waiting for its content.
+func (w *CborWriter) checkNoPendingTag() error {
+	if w.tagPending {
+		return ErrInvalidState
+	}
+	return nil
+}
+
 // advanceContainer updates container state after writing an item.
 func (w *CborWriter) advanceContainer() {
+	w.tagPending = false
 	if len(w.nestingStack) == 0 {
+		if w.rootValueWritten {
+			w.extraRootValue = true
+		}
 		w.rootValueWritten = true
 		return
 	}
@@ -126,9 +510,16 @@ func (w *CborWriter) advanceContainer() {
 			// We just wrote a value
 			info.keyWritten = false
 			info.itemsWritten++
+			if w.sortsMapKeys() {
+				info.entries[len(info.entries)-1].end = len(w.buffer)
+				info.nextKeyStart = len(w.buffer)
+			}
 		} else {
 			// We just wrote a key
 			info.keyWritten = true
+			if w.sortsMapKeys() {
+				info.entries = append(info.entries, mapEntry{start: info.nextKeyStart, keyEnd: len(w.buffer)})
+			}
 		}
 	} else {
 		info.itemsWritten++
@@ -137,31 +528,14 @@ func (w *CborWriter) advanceContainer() {
 
 // writeMinimalInitialByte writes the initial byte using minimal encoding (for canonical mode).
 func (w *CborWriter) writeMinimalInitialByte(mt MajorType, value uint64) {
-	if value < 24 {
-		w.buffer = append(w.buffer, encodeInitialByte(mt, byte(value)))
-	} else if value <= math.MaxUint8 {
-		w.buffer = append(w.buffer, encodeInitialByte(mt, byte(AdditionalInfo8Bit)), byte(value))
-	} else if value <= math.MaxUint16 {
-		w.buffer = append(w.buffer, encodeInitialByte(mt, byte(AdditionalInfo16Bit)))
-		w.buffer = binary.BigEndian.AppendUint16(w.buffer, uint16(value))
-	} else if value <= math.MaxUint32 {
-		w.buffer = append(w.buffer, encodeInitialByte(mt, byte(AdditionalInfo32Bit)))
-		w.buffer = binary.BigEndian.AppendUint32(w.buffer, uint32(value))
-	} else {
-		w.buffer = append(w.buffer, encodeInitialByte(mt, byte(AdditionalInfo64Bit)))
-		w.buffer = binary.BigEndian.AppendUint64(w.buffer, value)
-	}
+	w.buffer = AppendHeader(w.buffer, mt, value)
 	w.currentOffset = len(w.buffer)
 }
 
 // WriteInt64 writes a signed 64-bit integer.
 func (w *CborWriter) WriteInt64(value int64) error {
-	if value >= 0 {
-		w.writeMinimalInitialByte(MajorTypeUnsignedInteger, uint64(value))
-	} else {
-		// CBOR encodes negative integers as -1 - n, so the encoded value is -(value+1)
-		w.writeMinimalInitialByte(MajorTypeNegativeInteger, uint64(-1-value))
-	}
+	w.buffer = AppendInt64(w.buffer, value)
+	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
 }
@@ -221,6 +595,16 @@ func (w *CborWriter) WriteBigInt(value *big.Int) error {
 	if value.IsUint64() {
 		return w.WriteUint64(value.Uint64())
 	}
+	if value.Sign() < 0 {
+		// Values down to -2^64 still fit a major type 1 argument of -1 - n
+		n := new(big.Int).Not(value)
+		if n.IsUint64() {
+			w.writeMinimalInitialByte(MajorTypeNegativeInteger, n.Uint64())
+			w.currentOffset = len(w.buffer)
+			w.advanceContainer()
+			return nil
+		}
+	}
 
 	// Need to use bignum encoding
 	var tag CborTag
@@ -246,10 +630,56 @@ func (w *CborWriter) WriteBigInt(value *big.Int) error {
 	return w.WriteByteString(bytes)
 }
 
+// WriteRat writes a rational number using semantic tag 30 as a [numerator, denominator] array.
+func (w *CborWriter) WriteRat(value *big.Rat) error {
+	if value == nil {
+		return w.WriteNull()
+	}
+
+	if err := w.WriteTag(TagRationalNumber); err != nil {
+		return err
+	}
+	if err := w.WriteStartArray(2); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(value.Num()); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(value.Denom()); err != nil {
+		return err
+	}
+	return w.WriteEndArray()
+}
+
 // WriteByteString writes a byte string.
 func (w *CborWriter) WriteByteString(value []byte) error {
-	w.writeMinimalInitialByte(MajorTypeByteString, uint64(len(value)))
-	w.buffer = append(w.buffer, value...)
+	w.buffer = AppendByteString(w.buffer, value)
+	w.currentOffset = len(w.buffer)
+	w.advanceContainer()
+	return nil
+}
+
+// WriteByteStringFromReader writes a definite-length byte string whose length bytes of
+// content are read from src, avoiding an intermediate copy. If src yields fewer bytes it
+// returns ErrUnexpectedEndOfData and other read errors as-is, leaving the writer unchanged.
+func (w *CborWriter) WriteByteStringFromReader(src io.Reader, length int) error {
+	if length < 0 {
+		return NewCborError(ErrInvalidCbor, len(w.buffer), "negative byte string length")
+	}
+
+	mark := len(w.buffer)
+	w.writeMinimalInitialByte(MajorTypeByteString, uint64(length))
+	start := len(w.buffer)
+	w.buffer = slices.Grow(w.buffer, length)[:start+length]
+	if _, err := io.ReadFull(src, w.buffer[start:]); err != nil {
+		w.buffer = w.buffer[:mark]
+		w.currentOffset = mark
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return NewCborError(ErrUnexpectedEndOfData, mark, "byte string source is short")
+		}
+		return err
+	}
+
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -257,8 +687,7 @@ func (w *CborWriter) WriteByteString(value []byte) error {
 
 // WriteTextString writes a UTF-8 text string.
 func (w *CborWriter) WriteTextString(value string) error {
-	w.writeMinimalInitialByte(MajorTypeTextString, uint64(len(value)))
-	w.buffer = append(w.buffer, value...)
+	w.buffer = AppendTextString(w.buffer, value)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -270,12 +699,15 @@ func (w *CborWriter) WriteStartArray(length int) error {
 		return err
 	}
 
+	start := len(w.buffer)
 	w.writeMinimalInitialByte(MajorTypeArray, uint64(length))
-	w.nestingStack = append(w.nestingStack, nestingInfo{
+	w.pushContainer(nestingInfo{
 		majorType:      MajorTypeArray,
 		definiteLength: int64(length),
 		isMap:          false,
 		isIndefinite:   false,
+		start:          start,
+		contentStart:   len(w.buffer),
 	})
 	return nil
 }
@@ -292,17 +724,22 @@ func (w *CborWriter) WriteStartIndefiniteLengthArray() error {
 
 	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeArray, byte(AdditionalInfoIndefiniteLength)))
 	w.currentOffset = len(w.buffer)
-	w.nestingStack = append(w.nestingStack, nestingInfo{
+	w.pushContainer(nestingInfo{
 		majorType:      MajorTypeArray,
 		definiteLength: -1,
 		isMap:          false,
 		isIndefinite:   true,
+		start:          len(w.buffer) - 1,
+		contentStart:   len(w.buffer),
 	})
 	return nil
 }
 
 // WriteEndArray writes the end of an array.
 func (w *CborWriter) WriteEndArray() error {
+	if err := w.checkNoPendingTag(); err != nil {
+		return err
+	}
 	if len(w.nestingStack) == 0 {
 		return ErrInvalidState
 	}
@@ -333,12 +770,16 @@ func (w *CborWriter) WriteStartMap(length int) error {
 		return err
 	}
 
+	start := len(w.buffer)
 	w.writeMinimalInitialByte(MajorTypeMap, uint64(length))
-	w.nestingStack = append(w.nestingStack, nestingInfo{
+	w.pushContainer(nestingInfo{
 		majorType:      MajorTypeMap,
 		definiteLength: int64(length),
 		isMap:          true,
 		isIndefinite:   false,
+		start:          start,
+		contentStart:   len(w.buffer),
+		nextKeyStart:   len(w.buffer),
 	})
 	return nil
 }
@@ -355,17 +796,23 @@ func (w *CborWriter) WriteStartIndefiniteLengthMap() error {
 
 	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeMap, byte(AdditionalInfoIndefiniteLength)))
 	w.currentOffset = len(w.buffer)
-	w.nestingStack = append(w.nestingStack, nestingInfo{
+	w.pushContainer(nestingInfo{
 		majorType:      MajorTypeMap,
 		definiteLength: -1,
 		isMap:          true,
 		isIndefinite:   true,
+		start:          len(w.buffer) - 1,
+		contentStart:   len(w.buffer),
+		nextKeyStart:   len(w.buffer),
 	})
 	return nil
 }
 
 // WriteEndMap writes the end of a map.
 func (w *CborWriter) WriteEndMap() error {
+	if err := w.checkNoPendingTag(); err != nil {
+		return err
+	}
 	if len(w.nestingStack) == 0 {
 		return ErrInvalidState
 	}
@@ -380,35 +827,127 @@ func (w *CborWriter) WriteEndMap() error {
 		return ErrIncompleteContainer
 	}
 
-	if info.isIndefinite {
-		w.buffer = append(w.buffer, breakByte)
-		w.currentOffset = len(w.buffer)
-	} else if info.itemsWritten != info.definiteLength {
+	if !info.isIndefinite && info.itemsWritten != info.definiteLength {
 		if info.itemsWritten < info.definiteLength {
 			return ErrIncompleteContainer
 		}
 		return ErrExtraItems
 	}
 
+	if w.sortsMapKeys() {
+		if err := w.sortMapEntries(info.entries); err != nil {
+			return err
+		}
+	}
+
+	if info.isIndefinite {
+		w.buffer = append(w.buffer, breakByte)
+		w.currentOffset = len(w.buffer)
+	}
+
 	w.nestingStack = w.nestingStack[:len(w.nestingStack)-1]
 	w.advanceContainer()
 	return nil
 }
 
+// sortsMapKeys reports whether map keys are sorted when a map is closed.
+func (w *CborWriter) sortsMapKeys() bool {
+	return w.deterministicMaps || w.conformanceMode == ConformanceCanonical || w.conformanceMode == ConformanceCtap2Canonical
+}
+
+// sortMapEntries reorders the encoded key/value pairs of the map being closed.
+// RFC 8949 canonical mode and deterministic maps use bytewise lexicographic key order;
+// CTAP2 canonical mode sorts shorter keys first. Canonical modes reject duplicate keys.
+func (w *CborWriter) sortMapEntries(entries []mapEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if w.keyTemplate != nil {
+		if handled, err := w.sortMapEntriesByTemplate(entries); handled {
+			return err
+		}
+	}
+
+	key := func(e mapEntry) []byte {
+		return w.buffer[e.start:e.keyEnd]
+	}
+
+	sorted := make([]mapEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return w.compareKeys(key(sorted[i]), key(sorted[j])) < 0
+	})
+
+	if w.conformanceMode == ConformanceCanonical || w.conformanceMode == ConformanceCtap2Canonical {
+		for i := 1; i < len(sorted); i++ {
+			if bytes.Equal(key(sorted[i-1]), key(sorted[i])) {
+				return ErrDuplicateKey
+			}
+		}
+	}
+
+	w.reorderMapEntries(entries, sorted)
+	return nil
+}
+
+// compareKeys orders two encoded map keys for the writer's conformance mode.
+func (w *CborWriter) compareKeys(a, b []byte) int {
+	return compareMapKeys(a, b, w.conformanceMode)
+}
+
+// compareMapKeys orders two encoded map keys: bytewise lexicographic, except that CTAP2
+// canonical mode sorts shorter keys first.
+func compareMapKeys(a, b []byte, mode CborConformanceMode) int {
+	if mode == ConformanceCtap2Canonical && len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(a, b)
+}
+
+// reorderMapEntries rewrites the encoded pairs covered by entries in the order of sorted.
+// Checkpoints taken inside the map no longer match the bytes once they have moved.
+func (w *CborWriter) reorderMapEntries(entries, sorted []mapEntry) {
+	if slices.Equal(entries, sorted) {
+		return
+	}
+	start := entries[0].start
+	end := entries[len(entries)-1].end
+	w.recordEdit(start, end)
+	content := make([]byte, end-start)
+	copy(content, w.buffer[start:end])
+
+	pos := start
+	for _, e := range sorted {
+		pos += copy(w.buffer[pos:], content[e.start-start:e.end-start])
+	}
+}
+
 // WriteTag writes a semantic tag.
 func (w *CborWriter) WriteTag(tag CborTag) error {
 	w.writeMinimalInitialByte(MajorTypeTag, uint64(tag))
+	w.tagPending = true
 	// Don't advance container - the tagged value will do that
 	return nil
 }
 
+// WriteTags writes tags in order, outermost first, before a single content value. Like
+// WriteTag it leaves the tags pending, so closing a container or calling Finish before the
+// content is written returns ErrInvalidState.
+func (w *CborWriter) WriteTags(tags ...CborTag) error {
+	for _, tag := range tags {
+		if err := w.WriteTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WriteBoolean writes a boolean value.
 func (w *CborWriter) WriteBoolean(value bool) error {
-	if value {
-		w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(SimpleValueTrue)))
-	} else {
-		w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(SimpleValueFalse)))
-	}
+	w.buffer = AppendBool(w.buffer, value)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -416,7 +955,7 @@ func (w *CborWriter) WriteBoolean(value bool) error {
 
 // WriteNull writes a null value.
 func (w *CborWriter) WriteNull() error {
-	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(SimpleValueNull)))
+	w.buffer = AppendNull(w.buffer)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -424,19 +963,19 @@ func (w *CborWriter) WriteNull() error {
 
 // WriteUndefined writes an undefined value.
 func (w *CborWriter) WriteUndefined() error {
-	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(SimpleValueUndefined)))
+	w.buffer = AppendUndefined(w.buffer)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
 }
 
-// WriteSimpleValue writes a simple value.
+// WriteSimpleValue writes a simple value. The reserved values 24-31 have no well-formed
+// encoding and return ErrInvalidSimpleValue.
 func (w *CborWriter) WriteSimpleValue(value SimpleValue) error {
-	if value < 32 {
-		w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(value)))
-	} else {
-		w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(AdditionalInfo8Bit)), byte(value))
+	if value >= 24 && value < 32 {
+		return NewCborError(ErrInvalidSimpleValue, len(w.buffer), "WriteSimpleValue")
 	}
+	w.buffer = AppendSimpleValue(w.buffer, value)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -444,9 +983,7 @@ func (w *CborWriter) WriteSimpleValue(value SimpleValue) error {
 
 // WriteFloat16 writes a half-precision (16-bit) floating-point number.
 func (w *CborWriter) WriteFloat16(value float32) error {
-	bits := float32ToFloat16Bits(value)
-	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, 25)) // 25 = half precision
-	w.buffer = binary.BigEndian.AppendUint16(w.buffer, bits)
+	w.buffer = AppendFloat16(w.buffer, value)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -454,9 +991,7 @@ func (w *CborWriter) WriteFloat16(value float32) error {
 
 // WriteFloat32 writes a single-precision (32-bit) floating-point number.
 func (w *CborWriter) WriteFloat32(value float32) error {
-	bits := math.Float32bits(value)
-	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, 26)) // 26 = single precision
-	w.buffer = binary.BigEndian.AppendUint32(w.buffer, bits)
+	w.buffer = AppendFloat32(w.buffer, value)
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
@@ -464,28 +999,64 @@ func (w *CborWriter) WriteFloat32(value float32) error {
 
 // WriteFloat64 writes a double-precision (64-bit) floating-point number.
 func (w *CborWriter) WriteFloat64(value float64) error {
-	bits := math.Float64bits(value)
-	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, 27)) // 27 = double precision
-	w.buffer = binary.BigEndian.AppendUint64(w.buffer, bits)
+	w.buffer = AppendFloat64(w.buffer, value)
+	w.currentOffset = len(w.buffer)
+	w.advanceContainer()
+	return nil
+}
+
+// WriteNaN writes the canonical quiet NaN as the half-precision float f97e00, whatever the
+// float mode.
+func (w *CborWriter) WriteNaN() error {
+	return w.writeFloat16Bits(0x7e00)
+}
+
+// WritePositiveInfinity writes positive infinity as the half-precision float f97c00,
+// whatever the float mode.
+func (w *CborWriter) WritePositiveInfinity() error {
+	return w.writeFloat16Bits(0x7c00)
+}
+
+// WriteNegativeInfinity writes negative infinity as the half-precision float f9fc00,
+// whatever the float mode.
+func (w *CborWriter) WriteNegativeInfinity() error {
+	return w.writeFloat16Bits(0xfc00)
+}
+
+// writeFloat16Bits writes a half-precision float from its IEEE 754 bits.
+func (w *CborWriter) writeFloat16Bits(bits uint16) error {
+	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeSimpleOrFloat, byte(AdditionalInfo16Bit)), byte(bits>>8), byte(bits))
 	w.currentOffset = len(w.buffer)
 	w.advanceContainer()
 	return nil
 }
 
-// WriteFloat writes a floating-point number using the smallest representation that doesn't lose precision.
+// WriteFloat writes a floating-point number using the smallest representation that doesn't
+// lose precision, or as configured by WithWriterFloatMode. Negative zero keeps its sign
+// at every width.
 func (w *CborWriter) WriteFloat(value float64) error {
-	// Check if it can be represented as float32 without loss
-	f32 := float32(value)
-	if float64(f32) == value {
-		// Check if it can be represented as float16 without loss
-		f16bits := float32ToFloat16Bits(f32)
-		f16back := float16BitsToFloat32(f16bits)
-		if f16back == f32 && !math.IsNaN(value) {
-			return w.WriteFloat16(f32)
-		}
-		return w.WriteFloat32(f32)
+	return w.writeFloatOfWidth(value, 64)
+}
+
+// writeFloatOfWidth writes a float that came from a Go value of bitSize bits according to
+// the float mode.
+func (w *CborWriter) writeFloatOfWidth(value float64, bitSize int) error {
+	mode := w.floatMode
+	if w.conformanceMode == ConformanceCanonical || w.conformanceMode == ConformanceCtap2Canonical {
+		mode = FloatShortest
+	}
+
+	switch {
+	case mode == FloatShortest:
+		w.buffer = AppendFloat(w.buffer, value)
+	case mode == FloatPreserveInput && bitSize == 32:
+		w.buffer = AppendFloat32(w.buffer, float32(value))
+	default:
+		w.buffer = AppendFloat64(w.buffer, value)
 	}
-	return w.WriteFloat64(value)
+	w.currentOffset = len(w.buffer)
+	w.advanceContainer()
+	return nil
 }
 
 // WriteStartIndefiniteLengthByteString writes the start of an indefinite-length byte string.
@@ -500,10 +1071,12 @@ func (w *CborWriter) WriteStartIndefiniteLengthByteString() error {
 
 	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeByteString, byte(AdditionalInfoIndefiniteLength)))
 	w.currentOffset = len(w.buffer)
-	w.nestingStack = append(w.nestingStack, nestingInfo{
+	w.pushContainer(nestingInfo{
 		majorType:      MajorTypeByteString,
 		definiteLength: -1,
 		isIndefinite:   true,
+		start:          len(w.buffer) - 1,
+		contentStart:   len(w.buffer),
 	})
 	return nil
 }
@@ -526,6 +1099,9 @@ func (w *CborWriter) WriteByteStringChunk(value []byte) error {
 
 // WriteEndIndefiniteLengthByteString writes the end of an indefinite-length byte string.
 func (w *CborWriter) WriteEndIndefiniteLengthByteString() error {
+	if err := w.checkNoPendingTag(); err != nil {
+		return err
+	}
 	if len(w.nestingStack) == 0 {
 		return ErrInvalidState
 	}
@@ -553,10 +1129,12 @@ func (w *CborWriter) WriteStartIndefiniteLengthTextString() error {
 
 	w.buffer = append(w.buffer, encodeInitialByte(MajorTypeTextString, byte(AdditionalInfoIndefiniteLength)))
 	w.currentOffset = len(w.buffer)
-	w.nestingStack = append(w.nestingStack, nestingInfo{
+	w.pushContainer(nestingInfo{
 		majorType:      MajorTypeTextString,
 		definiteLength: -1,
 		isIndefinite:   true,
+		start:          len(w.buffer) - 1,
+		contentStart:   len(w.buffer),
 	})
 	return nil
 }
@@ -579,6 +1157,9 @@ func (w *CborWriter) WriteTextStringChunk(value string) error {
 
 // WriteEndIndefiniteLengthTextString writes the end of an indefinite-length text string.
 func (w *CborWriter) WriteEndIndefiniteLengthTextString() error {
+	if err := w.checkNoPendingTag(); err != nil {
+		return err
+	}
 	if len(w.nestingStack) == 0 {
 		return ErrInvalidState
 	}
@@ -594,8 +1175,18 @@ func (w *CborWriter) WriteEndIndefiniteLengthTextString() error {
 	return nil
 }
 
-// WriteDateTimeString writes a date/time string with the appropriate tag.
+// WriteDateTimeString writes a date/time string (tag 0) in RFC 3339 form, omitting the
+// fraction or its trailing zeros, and in UTC if WithWriterDateTimeUTC or a canonical mode
+// is set. In strict and canonical modes, years outside 0000-9999, which RFC 3339 cannot
+// express, return ErrInvalidDate.
 func (w *CborWriter) WriteDateTimeString(t time.Time) error {
+	if w.dateTimeUTC || w.conformanceMode == ConformanceCanonical || w.conformanceMode == ConformanceCtap2Canonical {
+		t = t.UTC()
+	}
+	if year := t.Year(); w.conformanceMode >= ConformanceStrict && (year < 0 || year > 9999) {
+		return NewCborError(ErrInvalidDate, len(w.buffer), "year outside RFC 3339 range")
+	}
+
 	if err := w.WriteTag(TagDateTimeString); err != nil {
 		return err
 	}
@@ -615,6 +1206,44 @@ func (w *CborWriter) WriteUnixTime(t time.Time) error {
 	return w.WriteInt64(t.Unix())
 }
 
+// dateOnly returns the calendar date of t as midnight UTC. In strict and canonical modes
+// it rejects times with a non-zero time of day.
+func (w *CborWriter) dateOnly(t time.Time) (time.Time, error) {
+	year, month, day := t.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if w.conformanceMode >= ConformanceStrict {
+		hour, minute, sec := t.Clock()
+		if hour != 0 || minute != 0 || sec != 0 || t.Nanosecond() != 0 {
+			return time.Time{}, NewCborError(ErrInvalidDate, len(w.buffer), "date has a non-zero time of day")
+		}
+	}
+	return date, nil
+}
+
+// WriteFullDate writes the calendar date of t as a full-date string (tag 1004).
+func (w *CborWriter) WriteFullDate(t time.Time) error {
+	date, err := w.dateOnly(t)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteTag(TagFullDateString); err != nil {
+		return err
+	}
+	return w.WriteTextString(date.Format(fullDateLayout))
+}
+
+// WriteEpochDate writes the calendar date of t as signed days since 1970-01-01 (tag 100).
+func (w *CborWriter) WriteEpochDate(t time.Time) error {
+	date, err := w.dateOnly(t)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteTag(TagEpochDate); err != nil {
+		return err
+	}
+	return w.WriteInt64(date.Unix() / secondsPerDay)
+}
+
 // WriteUri writes a URI with the appropriate tag.
 func (w *CborWriter) WriteUri(uri string) error {
 	if err := w.WriteTag(TagURI); err != nil {
@@ -637,13 +1266,52 @@ func (w *CborWriter) WriteSelfDescribedCbor() error {
 }
 
 // WriteRaw writes raw bytes directly to the buffer.
-// Use with caution - this bypasses all encoding.
+// Use with caution - this bypasses all encoding and container accounting; use WriteRawItem
+// to splice a complete item into a container.
 func (w *CborWriter) WriteRaw(data []byte) error {
 	w.buffer = append(w.buffer, data...)
 	w.currentOffset = len(w.buffer)
 	return nil
 }
 
+// WriteRawItem writes data, which must hold exactly one complete pre-encoded item, and
+// counts it as one item of the current container. Unlike WriteRaw it keeps container
+// accounting correct, but it does not check the bytes; see WriteRawValidated.
+func (w *CborWriter) WriteRawItem(data []byte) error {
+	w.appendRawItem(data)
+	return nil
+}
+
+// WriteRawValidated writes data, which must hold exactly one complete pre-encoded item,
+// and counts it as one item of the current container. The item is checked with the
+// writer's conformance mode and remaining nesting depth before anything is written, and
+// its maps are checked for duplicate or, in canonical modes, unsorted keys.
+func (w *CborWriter) WriteRawValidated(data []byte) error {
+	if len(data) == 0 {
+		return NewCborError(ErrUnexpectedEndOfData, len(w.buffer), "empty raw value")
+	}
+
+	r := NewCborReader(data,
+		WithReaderConformanceMode(w.conformanceMode),
+		WithReaderMaxNestingDepth(w.maxNestingDepth-len(w.nestingStack)))
+	if err := r.checkMapKeys(); err != nil {
+		return err
+	}
+	if r.BytesRemaining() > 0 {
+		return NewCborError(ErrInvalidCbor, len(w.buffer), "raw value must hold exactly one item")
+	}
+
+	w.appendRawItem(data)
+	return nil
+}
+
+// appendRawItem appends one complete encoded item and advances the current container.
+func (w *CborWriter) appendRawItem(data []byte) {
+	w.buffer = append(w.buffer, data...)
+	w.currentOffset = len(w.buffer)
+	w.advanceContainer()
+}
+
 // float32ToFloat16Bits converts a float32 to IEEE 754 half-precision bits.
 func float32ToFloat16Bits(f float32) uint16 {
 	bits := math.Float32bits(f)